@@ -2,7 +2,6 @@
 package actions // import "go.followtheprocess.codes/actions"
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"os"
@@ -63,8 +62,10 @@ func GetEnv(key string) (value string, ok bool) {
 //
 // Attempting to set $GITHUB_*, $RUNNER_*, $CI or $NODE_OPTIONS is not allowed and will
 // return an error.
+//
+// It is safe to call concurrently, see [FileCommands].
 func SetEnv(key, value string) error {
-	return setVarFile(envFile, key, value)
+	return defaultFileCommands.SetEnv(key, value)
 }
 
 // SetOutput sets an output variable by writing it to $GITHUB_OUTPUT.
@@ -74,8 +75,11 @@ func SetEnv(key, value string) error {
 // minimising the chance of collision with the contents.
 //
 // See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#multiline-strings.
+//
+// It is safe to call concurrently, see [FileCommands]. To set several outputs together,
+// use [FileCommands.Batch].
 func SetOutput(key, value string) error {
-	return setVarFile(outFile, key, value)
+	return defaultFileCommands.SetOutput(key, value)
 }
 
 // GetState gets a state variable by name.
@@ -99,7 +103,7 @@ func GetState(key string) (value string, ok bool) {
 //
 // See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#sending-values-to-the-pre-and-post-actions
 func SetState(key, value string) error {
-	return setVarFile(stateFile, key, value)
+	return defaultFileCommands.SetState(key, value)
 }
 
 // AddPath prepends path to $GITHUB_PATH and does the same with the actual $PATH variable.
@@ -142,7 +146,8 @@ func AddPath(path string) error {
 // GitHub flavoured markdown content is supported. Subsequent calls to Summary overwrite the contents.
 //
 // For writing complex markdown or html summaries, consider using [html/template] to format your content
-// as desired, then passing the rendered template to Summary.
+// as desired, then passing the rendered template to Summary. To build up a summary incrementally
+// or append to an existing one, use [SummaryBuilder].
 //
 // See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#adding-a-job-summary
 //
@@ -162,57 +167,3 @@ func Summary(contents string) error {
 
 	return nil
 }
-
-// setVarFile sets either a GITHUB_ENV or GITHUB_OUTPUT file variable. The process
-// is largely the same for each.
-func setVarFile(name, key, value string) error {
-	key = strings.TrimSpace(key)
-	value = strings.TrimSpace(value)
-
-	if key == "" {
-		return errors.New("key cannot be empty")
-	}
-
-	if value == "" {
-		return errors.New("value cannot be empty")
-	}
-
-	if name == envFile {
-		// If it's GITHUB_ENV, we aren't allowed to play with these env vars
-		if key == "CI" || key == "NODE_OPTIONS" || strings.HasPrefix(key, "GITHUB_") ||
-			strings.HasPrefix(key, "RUNNER_") {
-			return fmt.Errorf("setting $%s is disallowed", key)
-		}
-	}
-
-	path := os.Getenv(name)
-	if path == "" {
-		return fmt.Errorf("$%s is not set or is empty", name)
-	}
-
-	// Append to the file
-	//nolint:gosec // G703: path is set by the trusted Actions runner, not user input
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, filePermissions)
-	if err != nil {
-		return fmt.Errorf("could not open $%s file: %w", outFile, err)
-	}
-	defer file.Close()
-
-	// If the value is multi-line, do the whole EOF delimiter thing, but with
-	// a random string to make pretty sure it never collides with file content
-	if strings.Contains(value, "\n") {
-		delimiter := "ghadelimiter_" + rand.Text()
-		fmt.Fprintf(file, "%s<<%s\n%s\n%s", key, delimiter, value, delimiter)
-	} else {
-		fmt.Fprintf(file, "%s=%s\n", key, value)
-	}
-
-	// If it's an env var, let's export the actual env var too
-	if name == envFile {
-		if err := os.Setenv(key, value); err != nil {
-			return fmt.Errorf("failed to set $%s: %w", key, err)
-		}
-	}
-
-	return nil
-}
@@ -250,6 +250,25 @@ func TestSetOutput(t *testing.T) {
 		test.True(t, bytes.Contains(contents, []byte("ghadelimiter_")))
 		test.True(t, bytes.Contains(contents, []byte(value)))
 	})
+	t.Run("multiline then another", func(t *testing.T) {
+		tmp, err := os.CreateTemp(t.TempDir(), "TestSetOutput*")
+		test.Ok(t, err)
+		tmp.Close()
+
+		t.Setenv(outFile, tmp.Name()) // Set $TEST_GITHUB_OUTPUT to the path to our file
+
+		test.Ok(t, SetOutput("MULTILINE", "some\nlines"))
+		test.Ok(t, SetOutput("AFTER", "value"))
+
+		contents, err := os.ReadFile(tmp.Name())
+		test.Ok(t, err)
+
+		// The closing delimiter must be on its own line, so the next value starts on a new one
+		lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+		test.Equal(t, len(lines), 5)
+		test.Equal(t, lines[3], strings.TrimPrefix(lines[0], "MULTILINE<<"))
+		test.Equal(t, lines[4], "AFTER=value")
+	})
 	t.Run("unset", func(t *testing.T) {
 		// Not setting $TEST_GITHUB_OUTPUT
 		err := SetOutput("KEY", "value")
@@ -0,0 +1,315 @@
+// Package actionstest provides a fake GitHub Actions runner environment for unit testing actions.
+//
+// [New] builds a sandboxed environment from a [testing.TB]: the files the runner provides
+// ($GITHUB_OUTPUT, $GITHUB_ENV etc.) are created in a temporary directory, the default
+// environment variables are set and inputs, an event payload and any other variables are
+// set from the options. Once the code under test has run, the results can be inspected:
+//
+//	func TestGreet(t *testing.T) {
+//		runner := actionstest.New(t, actionstest.Input("name", "Gopher"))
+//
+//		if err := greet(runner.Logger()); err != nil {
+//			t.Fatalf("greet returned an error: %v", err)
+//		}
+//
+//		if got := runner.Outputs()["greeting"]; got != "Hello Gopher" {
+//			t.Errorf("got greeting %q, wanted %q", got, "Hello Gopher")
+//		}
+//	}
+//
+// As the environment is set with [testing.T.Setenv], tests using this package cannot be run
+// in parallel. Everything is restored when the test finishes, including variables set by
+// the code under test with [go.followtheprocess.codes/actions.SetEnv].
+package actionstest // import "go.followtheprocess.codes/actions/actionstest"
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.followtheprocess.codes/actions"
+	"go.followtheprocess.codes/actions/internal/sandbox"
+	"go.followtheprocess.codes/actions/log"
+)
+
+// Runner is a fake runner environment, see [New].
+type Runner struct {
+	t         testing.TB
+	log       *buffer           // Everything written to the Logger
+	sandbox   sandbox.Sandbox   // The fake runner environment
+	inputs    map[string]string // Inputs keyed by name
+	env       map[string]string // Additional env vars
+	state     map[string]string // State from a previous phase
+	eventName string            // Name of the event in $GITHUB_EVENT_NAME
+	event     []byte            // JSON payload of the event in $GITHUB_EVENT_PATH
+}
+
+// Option is a configuration option for a [Runner].
+type Option interface {
+	// Apply the option to the runner.
+	apply(runner *Runner)
+}
+
+// option is a function that implements the Option interface.
+type option func(runner *Runner)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(runner *Runner) {
+	o(runner)
+}
+
+// Input sets the action input called name, as read by the [go.followtheprocess.codes/actions/input]
+// package. It may be passed more than once.
+func Input(name, value string) Option {
+	f := func(runner *Runner) {
+		runner.inputs[name] = value
+	}
+
+	return option(f)
+}
+
+// Env sets an environment variable, overriding the default if it's one the runner sets
+// e.g. "GITHUB_REPOSITORY". It may be passed more than once.
+func Env(key, value string) Option {
+	f := func(runner *Runner) {
+		runner.env[key] = value
+	}
+
+	return option(f)
+}
+
+// State sets a state variable as if it had been saved by a previous phase of the action,
+// as read by [go.followtheprocess.codes/actions.GetState]. It may be passed more than once.
+func State(key, value string) Option {
+	f := func(runner *Runner) {
+		runner.state[key] = value
+	}
+
+	return option(f)
+}
+
+// Event sets the event that triggered the workflow, by default "push" with an empty payload.
+//
+// The payload is marshalled to JSON, unless it is a []byte or [json.RawMessage] in which
+// case it is used as is, allowing a fixture file to be passed straight through.
+func Event(name string, payload any) Option {
+	f := func(runner *Runner) {
+		runner.eventName = name
+
+		switch p := payload.(type) {
+		case []byte:
+			runner.event = p
+		case json.RawMessage:
+			runner.event = p
+		default:
+			data, err := json.Marshal(payload)
+			if err != nil {
+				runner.t.Fatalf("actionstest: could not marshal %s event payload: %v", name, err)
+			}
+
+			runner.event = data
+		}
+	}
+
+	return option(f)
+}
+
+// New returns a new [Runner], setting up the fake environment for the duration of the test.
+//
+// Any existing inputs, state and $RUNNER_DEBUG are cleared, and every variable read by
+// [go.followtheprocess.codes/actions.Context] is set to a sensible default.
+func New(t testing.TB, options ...Option) *Runner {
+	t.Helper()
+
+	runner := &Runner{
+		t:         t,
+		log:       &buffer{},
+		eventName: "push",
+		event:     []byte("{}"),
+		inputs:    make(map[string]string),
+		env:       make(map[string]string),
+		state:     make(map[string]string),
+	}
+
+	for _, option := range options {
+		option.apply(runner)
+	}
+
+	fake, err := sandbox.New(t.TempDir(), "", runner.event)
+	if err != nil {
+		t.Fatalf("actionstest: %v", err)
+	}
+
+	runner.sandbox = fake
+
+	// SetOutput etc. hold the files open, which would stop them being removed on Windows
+	t.Cleanup(func() {
+		_ = actions.DefaultFileCommands().Close()
+	})
+
+	// Anything leaking in from the real environment, especially when tests are run in
+	// GitHub Actions, would make the tests unpredictable
+	for _, variable := range os.Environ() {
+		key, _, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(key, "INPUT_") || strings.HasPrefix(key, "STATE_") || key == "RUNNER_DEBUG" {
+			t.Setenv(key, "")
+			_ = os.Unsetenv(key)
+		}
+	}
+
+	env := fake.Env(runner.eventName)
+	env["PATH"] = os.Getenv("PATH") // So it's restored after AddPath
+
+	for key, value := range runner.env {
+		env[key] = value
+	}
+
+	for name, value := range runner.inputs {
+		env[sandbox.InputVar(name)] = value
+	}
+
+	for key, value := range runner.state {
+		env["STATE_"+key] = value
+	}
+
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	// SetEnv sets the real env var too, which t.Setenv knows nothing about
+	before := os.Environ()
+
+	t.Cleanup(func() {
+		set, err := runner.sandbox.Parse("GITHUB_ENV")
+		if err != nil {
+			return
+		}
+
+		for key := range set {
+			_ = os.Unsetenv(key)
+		}
+
+		for _, variable := range before {
+			key, value, _ := strings.Cut(variable, "=")
+			if _, ok := set[key]; ok {
+				_ = os.Setenv(key, value)
+			}
+		}
+	})
+
+	return runner
+}
+
+// Workspace returns the path to $GITHUB_WORKSPACE, an empty temporary directory.
+func (r *Runner) Workspace() string {
+	return r.sandbox.Workspace
+}
+
+// Outputs returns the outputs written to $GITHUB_OUTPUT, keyed by name.
+func (r *Runner) Outputs() map[string]string {
+	r.t.Helper()
+	return r.mustParse("GITHUB_OUTPUT")
+}
+
+// Env returns the environment variables written to $GITHUB_ENV, keyed by name.
+func (r *Runner) Env() map[string]string {
+	r.t.Helper()
+	return r.mustParse("GITHUB_ENV")
+}
+
+// State returns the state written to $GITHUB_STATE, keyed by name.
+func (r *Runner) State() map[string]string {
+	r.t.Helper()
+	return r.mustParse("GITHUB_STATE")
+}
+
+// Path returns the directories written to $GITHUB_PATH, in the order they were written.
+func (r *Runner) Path() []string {
+	r.t.Helper()
+
+	paths, err := r.sandbox.Path()
+	if err != nil {
+		r.t.Fatalf("actionstest: %v", err)
+	}
+
+	return paths
+}
+
+// Summary returns the contents of $GITHUB_STEP_SUMMARY.
+func (r *Runner) Summary() string {
+	r.t.Helper()
+
+	summary, err := r.sandbox.Summary()
+	if err != nil {
+		r.t.Fatalf("actionstest: %v", err)
+	}
+
+	return summary
+}
+
+// Logger returns a [log.Logger] whose output is captured by the runner, see [Runner.Log]
+// and [Runner.Commands]. It's safe to use concurrently.
+func (r *Runner) Logger() log.Logger {
+	return log.New(r.log)
+}
+
+// Log returns everything written to the [Runner.Logger], verbatim.
+func (r *Runner) Log() string {
+	return r.log.String()
+}
+
+// Commands returns the workflow commands written to the [Runner.Logger] in order, decoded
+// the same way as the runner does, see [log.Commands].
+func (r *Runner) Commands() []log.Command {
+	r.t.Helper()
+
+	var commands []log.Command
+
+	for command, err := range log.Commands(strings.NewReader(r.log.String())) {
+		if err != nil {
+			r.t.Fatalf("actionstest: could not read log: %v", err)
+		}
+
+		commands = append(commands, command)
+	}
+
+	return commands
+}
+
+// mustParse returns the values written to the file command file in the env var key,
+// failing the test on error.
+func (r *Runner) mustParse(key string) map[string]string {
+	r.t.Helper()
+
+	values, err := r.sandbox.Parse(key)
+	if err != nil {
+		r.t.Fatalf("actionstest: %v", err)
+	}
+
+	return values
+}
+
+// buffer is a [bytes.Buffer] that's safe for concurrent use.
+type buffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+// Write implements [io.Writer] for buffer.
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// String returns the contents of the buffer.
+func (b *buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
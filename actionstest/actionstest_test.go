@@ -0,0 +1,136 @@
+package actionstest_test
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.followtheprocess.codes/actions"
+	"go.followtheprocess.codes/actions/actionstest"
+	"go.followtheprocess.codes/actions/event"
+	"go.followtheprocess.codes/actions/input"
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+func TestEnvironment(t *testing.T) {
+	t.Setenv("INPUT_LEAKED", "from the real environment")
+
+	runner := actionstest.New(
+		t,
+		actionstest.Input("who to greet", "Gopher"),
+		actionstest.Env("GITHUB_REPOSITORY", "someone/something"),
+		actionstest.State("pid", "123"),
+		actionstest.Event("workflow_dispatch", map[string]any{"ref": "refs/heads/dev"}),
+	)
+
+	name, ok := input.Get("who to greet")
+	test.True(t, ok)
+	test.Equal(t, name, "Gopher")
+
+	_, ok = input.Get("leaked")
+	test.False(t, ok)
+
+	pid, ok := actions.GetState("pid")
+	test.True(t, ok)
+	test.Equal(t, pid, "123")
+
+	ctx, err := actions.Context()
+	test.Ok(t, err)
+	test.Equal(t, ctx.Repository.String(), "someone/something")
+	test.Equal(t, ctx.Workspace, runner.Workspace())
+	test.Equal(t, ctx.EventName, "workflow_dispatch")
+
+	payload, err := event.Read()
+	test.Ok(t, err)
+
+	dispatch, ok := payload.(*event.WorkflowDispatchEvent)
+	test.True(t, ok)
+	test.Equal(t, dispatch.Ref, "refs/heads/dev")
+}
+
+func TestResults(t *testing.T) {
+	runner := actionstest.New(t)
+
+	test.Ok(t, actions.SetOutput("greeting", "hello"))
+	test.Ok(t, actions.SetOutput("poem", "roses are red\nviolets are blue"))
+	test.Ok(t, actions.SetEnv("ACTIONSTEST_VAR", "yes"))
+	test.Ok(t, actions.SetState("cleanup", "true"))
+	test.Ok(t, actions.AddPath("/opt/tool/bin"))
+	test.Ok(t, actions.AddPath("/opt/other/bin"))
+	test.Ok(t, actions.Summary("# Done"))
+
+	test.EqualFunc(
+		t,
+		runner.Outputs(),
+		map[string]string{"greeting": "hello", "poem": "roses are red\nviolets are blue"},
+		maps.Equal,
+	)
+	test.EqualFunc(t, runner.Env(), map[string]string{"ACTIONSTEST_VAR": "yes"}, maps.Equal)
+	test.EqualFunc(t, runner.State(), map[string]string{"cleanup": "true"}, maps.Equal)
+	test.EqualFunc(t, runner.Path(), []string{"/opt/tool/bin", "/opt/other/bin"}, slices.Equal)
+	test.Equal(t, runner.Summary(), "# Done")
+}
+
+func TestRestoresEnv(t *testing.T) {
+	path := os.Getenv("PATH")
+
+	t.Run("sets", func(t *testing.T) {
+		actionstest.New(t)
+
+		test.Ok(t, actions.SetEnv("ACTIONSTEST_LEAK", "value"))
+		test.Ok(t, actions.AddPath("/somewhere"))
+		test.Equal(t, os.Getenv("ACTIONSTEST_LEAK"), "value")
+	})
+
+	_, ok := os.LookupEnv("ACTIONSTEST_LEAK")
+	test.False(t, ok)
+	test.Equal(t, os.Getenv("PATH"), path)
+}
+
+func TestCommands(t *testing.T) {
+	runner := actionstest.New(t)
+	logger := runner.Logger()
+
+	logger.Warning("careful: 100%\nreally", log.Title("Heads up, friend"), log.File("main.go"), log.Lines(3, 4))
+	logger.WithGroup("Build", func() {
+		fmt.Fprintln(logger, "plain output")
+	})
+	logger.StopCommands(func() {
+		logger.Error("not a command")
+	})
+	logger.Mask("secret")
+
+	want := []log.Command{
+		{
+			Name:    "warning",
+			Message: "careful: 100%\nreally",
+			Properties: map[string]string{
+				"title":   "Heads up, friend",
+				"file":    "main.go",
+				"line":    "3",
+				"endLine": "4",
+			},
+		},
+		{Name: "group", Message: "Build"},
+		{Name: "endgroup"},
+	}
+
+	got := runner.Commands()
+	test.Equal(t, len(got), 5)
+
+	for i, command := range want {
+		test.Equal(t, got[i].Name, command.Name)
+		test.Equal(t, got[i].Message, command.Message)
+		test.EqualFunc(t, got[i].Properties, command.Properties, maps.Equal)
+	}
+
+	test.Equal(t, got[3].Name, "stop-commands")
+	test.Equal(t, got[4].Name, "add-mask")
+	test.Equal(t, got[4].Message, "secret")
+
+	test.True(t, filepath.IsAbs(runner.Workspace()))
+}
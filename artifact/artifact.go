@@ -0,0 +1,521 @@
+// Package artifact uploads, lists and downloads workflow artifacts, mirroring the
+// @actions/artifact package of the actions toolkit.
+//
+// Only artifacts v4 (the version used by actions/upload-artifact@v4 and later) is supported:
+//
+//	files := []string{"report/index.html", "report/style.css"}
+//
+//	uploaded, err := artifact.Upload(ctx, "report", files, "report", artifact.RetentionDays(7))
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	// Later, possibly in another job of the same run
+//	dir, err := artifact.DownloadByName(ctx, "report", "")
+//
+// Files are uploaded as a single zip, streamed straight to storage without being written to
+// disk first, and downloaded artifacts are extracted into a directory.
+//
+// See https://github.com/actions/toolkit/tree/main/packages/artifact
+package artifact // import "go.followtheprocess.codes/actions/artifact"
+
+import (
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.followtheprocess.codes/actions/internal/blob"
+	"go.followtheprocess.codes/actions/internal/twirp"
+)
+
+const (
+	// resultsURLVar is the env var containing the URL of the results service.
+	resultsURLVar = "ACTIONS_RESULTS_URL"
+
+	// tokenVar is the env var containing the token used to authenticate with the runtime services.
+	tokenVar = "ACTIONS_RUNTIME_TOKEN"
+
+	// retentionVar is the env var containing the maximum retention period, in days, allowed
+	// by the repository or organisation.
+	retentionVar = "GITHUB_RETENTION_DAYS"
+
+	// workspaceVar is the env var containing the path to the workspace, the default download
+	// destination.
+	workspaceVar = "GITHUB_WORKSPACE"
+
+	// tempVar is the env var containing the path to the runner's temporary directory.
+	tempVar = "RUNNER_TEMP"
+
+	// service is the name of the artifact twirp service.
+	service = "github.actions.results.api.v1.ArtifactService"
+
+	// version is the version of the artifact protocol.
+	version = 4
+
+	// blockSize is the size of each block of an upload, 8MB.
+	blockSize = 8 << 20
+
+	// defaultLevel is the default zip compression level, the same as the actions toolkit.
+	defaultLevel = 6
+
+	// dirPermissions is the permissions used when creating directories.
+	dirPermissions = 0o755
+
+	// invalidNameChars are the characters not allowed in artifact names.
+	invalidNameChars = "\":<>|*?\r\n\\/"
+
+	// invalidPathChars are the characters not allowed in the paths of files within an artifact.
+	invalidPathChars = "\":<>|*?\r\n"
+)
+
+// ErrNotFound is returned from [Get], [Download] and [DownloadByName] when no artifact in the
+// current workflow run matches.
+var ErrNotFound = errors.New("artifact not found")
+
+// Artifact is a workflow artifact.
+type Artifact struct {
+	CreatedAt time.Time // When the artifact was created, zero if not known
+	Name      string    // The name of the artifact
+	Digest    string    // SHA-256 digest of the artifact zip e.g. "sha256:abc...", only set by Upload
+	ID        int64     // The unique ID of the artifact
+	Size      int64     // Size of the artifact zip in bytes
+}
+
+// config holds the configuration for a single call to one of the functions in this package.
+type config struct {
+	client        *http.Client // The HTTP client to use
+	retentionDays int          // Days before the artifact expires, 0 means the repository default
+	level         int          // Zip compression level
+}
+
+// Option is a configuration option for the functions in this package.
+type Option interface {
+	// Apply the option to the config.
+	apply(cfg *config)
+}
+
+// option is a function that implements the Option interface.
+type option func(cfg *config)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(cfg *config) {
+	o(cfg)
+}
+
+// Client sets the [*http.Client] used, by default [http.DefaultClient] is used.
+func Client(client *http.Client) Option {
+	f := func(cfg *config) {
+		cfg.client = client
+	}
+
+	return option(f)
+}
+
+// RetentionDays sets the number of days before an uploaded artifact expires.
+//
+// It may not exceed the limit set by the repository ($GITHUB_RETENTION_DAYS), and is reduced
+// to that limit if it does. By default the repository's limit is used.
+func RetentionDays(days int) Option {
+	f := func(cfg *config) {
+		cfg.retentionDays = days
+	}
+
+	return option(f)
+}
+
+// CompressionLevel sets the zip compression level of an upload from 0 (no compression, good
+// for files that are already compressed) to 9 (best compression), by default 6.
+func CompressionLevel(level int) Option {
+	f := func(cfg *config) {
+		cfg.level = level
+	}
+
+	return option(f)
+}
+
+// Upload uploads files as an artifact called name, returning the uploaded [Artifact].
+//
+// Every file must be within root, which is stripped from the paths of the files in the
+// artifact e.g. uploading "out/report/index.html" with root "out" results in an artifact
+// containing "report/index.html". An artifact with the same name must not already
+// exist in the current workflow run.
+func Upload(ctx context.Context, name string, files []string, root string, options ...Option) (Artifact, error) {
+	cfg := newConfig(options)
+
+	if err := validateName(name); err != nil {
+		return Artifact{}, err
+	}
+
+	if cfg.level < flate.NoCompression || cfg.level > flate.BestCompression {
+		return Artifact{}, fmt.Errorf("invalid compression level %d, must be between 0 and 9", cfg.level)
+	}
+
+	entries, err := zipEntries(files, root)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	client, ids, err := connect(cfg)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	create := struct {
+		ExpiresAt string `json:"expires_at,omitempty"`
+		backendIDs
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	}{
+		backendIDs: ids,
+		Name:       name,
+		Version:    version,
+	}
+
+	days, err := retention(cfg.retentionDays)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	if days > 0 {
+		create.ExpiresAt = time.Now().UTC().AddDate(0, 0, days).Format(time.RFC3339Nano)
+	}
+
+	var created struct {
+		SignedUploadURL string `json:"signed_upload_url"`
+		OK              bool   `json:"ok"`
+	}
+
+	if err = client.Call(ctx, service, "CreateArtifact", create, &created); err != nil {
+		return Artifact{}, err
+	}
+
+	if !created.OK {
+		return Artifact{}, fmt.Errorf("could not create artifact %q", name)
+	}
+
+	// Stream the zip straight into the upload, hashing it on the way
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeZip(pw, entries, cfg.level))
+	}()
+
+	hash := sha256.New()
+
+	size, err := blob.Upload(ctx, cfg.client, created.SignedUploadURL, io.TeeReader(pr, hash), blockSize)
+	pr.CloseWithError(err) // Unblocks the writer if the upload failed part way
+
+	if err != nil {
+		return Artifact{}, fmt.Errorf("could not upload artifact %q: %w", name, err)
+	}
+
+	digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+
+	finalize := struct {
+		backendIDs
+		Name string `json:"name"`
+		Hash string `json:"hash"`
+		Size int64  `json:"size,string"`
+	}{
+		backendIDs: ids,
+		Name:       name,
+		Hash:       digest,
+		Size:       size,
+	}
+
+	var finalized struct {
+		ArtifactID int64 `json:"artifact_id,string"`
+		OK         bool  `json:"ok"`
+	}
+
+	if err := client.Call(ctx, service, "FinalizeArtifact", finalize, &finalized); err != nil {
+		return Artifact{}, err
+	}
+
+	if !finalized.OK {
+		return Artifact{}, fmt.Errorf("could not finalize artifact %q", name)
+	}
+
+	return Artifact{Name: name, Digest: digest, ID: finalized.ArtifactID, Size: size}, nil
+}
+
+// List returns all the artifacts in the current workflow run.
+func List(ctx context.Context, options ...Option) ([]Artifact, error) {
+	return list(ctx, newConfig(options), "", 0)
+}
+
+// Get returns the artifact called name in the current workflow run, or an error wrapping
+// [ErrNotFound] if there isn't one.
+func Get(ctx context.Context, name string, options ...Option) (Artifact, error) {
+	artifacts, err := list(ctx, newConfig(options), name, 0)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	if len(artifacts) == 0 {
+		return Artifact{}, fmt.Errorf("%w: no artifact named %q", ErrNotFound, name)
+	}
+
+	return artifacts[len(artifacts)-1], nil
+}
+
+// Download downloads the artifact with the given ID from the current workflow run and extracts
+// it into dest, returning the path to dest.
+//
+// If dest is empty, $GITHUB_WORKSPACE is used. Any file in the artifact which would be extracted
+// outside of dest is rejected with an error.
+func Download(ctx context.Context, id int64, dest string, options ...Option) (string, error) {
+	cfg := newConfig(options)
+
+	artifacts, err := list(ctx, cfg, "", id)
+	if err != nil {
+		return "", err
+	}
+
+	if len(artifacts) == 0 {
+		return "", fmt.Errorf("%w: no artifact with ID %d", ErrNotFound, id)
+	}
+
+	return download(ctx, cfg, artifacts[0].Name, dest)
+}
+
+// DownloadByName downloads the artifact called name from the current workflow run and extracts
+// it into dest, returning the path to dest.
+//
+// If dest is empty, $GITHUB_WORKSPACE is used. Any file in the artifact which would be extracted
+// outside of dest is rejected with an error.
+func DownloadByName(ctx context.Context, name, dest string, options ...Option) (string, error) {
+	cfg := newConfig(options)
+
+	artifacts, err := list(ctx, cfg, name, 0)
+	if err != nil {
+		return "", err
+	}
+
+	if len(artifacts) == 0 {
+		return "", fmt.Errorf("%w: no artifact named %q", ErrNotFound, name)
+	}
+
+	return download(ctx, cfg, name, dest)
+}
+
+// backendIDs are the backend IDs identifying the current workflow run and job, sent with every request.
+type backendIDs struct {
+	RunID string `json:"workflow_run_backend_id"`
+	JobID string `json:"workflow_job_run_backend_id"`
+}
+
+// list lists artifacts in the current run, optionally filtered by name or ID.
+func list(ctx context.Context, cfg config, name string, id int64) ([]Artifact, error) {
+	client, ids, err := connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	request := struct {
+		backendIDs
+		NameFilter string `json:"name_filter,omitempty"`
+		IDFilter   int64  `json:"id_filter,omitempty,string"`
+	}{
+		backendIDs: ids,
+		NameFilter: name,
+		IDFilter:   id,
+	}
+
+	var response struct {
+		Artifacts []struct {
+			CreatedAt  time.Time `json:"created_at"`
+			Name       string    `json:"name"`
+			DatabaseID int64     `json:"database_id,string"`
+			Size       int64     `json:"size,string"`
+		} `json:"artifacts"`
+	}
+
+	if err := client.Call(ctx, service, "ListArtifacts", request, &response); err != nil {
+		return nil, err
+	}
+
+	artifacts := make([]Artifact, 0, len(response.Artifacts))
+	for _, artifact := range response.Artifacts {
+		artifacts = append(artifacts, Artifact{
+			CreatedAt: artifact.CreatedAt,
+			Name:      artifact.Name,
+			ID:        artifact.DatabaseID,
+			Size:      artifact.Size,
+		})
+	}
+
+	return artifacts, nil
+}
+
+// download downloads the artifact called name and extracts it into dest.
+func download(ctx context.Context, cfg config, name, dest string) (string, error) {
+	if dest == "" {
+		dest = os.Getenv(workspaceVar)
+		if dest == "" {
+			return "", fmt.Errorf("$%s is not set or is empty", workspaceVar)
+		}
+	}
+
+	client, ids, err := connect(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	request := struct {
+		backendIDs
+		Name string `json:"name"`
+	}{
+		backendIDs: ids,
+		Name:       name,
+	}
+
+	var response struct {
+		SignedURL string `json:"signed_url"`
+	}
+
+	if err = client.Call(ctx, service, "GetSignedArtifactURL", request, &response); err != nil {
+		return "", err
+	}
+
+	if response.SignedURL == "" {
+		return "", fmt.Errorf("%w: no download URL for artifact %q", ErrNotFound, name)
+	}
+
+	temp := os.Getenv(tempVar)
+	if temp == "" {
+		return "", fmt.Errorf("$%s is not set or is empty", tempVar)
+	}
+
+	if err = os.MkdirAll(temp, dirPermissions); err != nil {
+		return "", fmt.Errorf("could not create temporary directory: %w", err)
+	}
+
+	archive, err := os.Create(filepath.Join(temp, rand.Text()+".zip"))
+	if err != nil {
+		return "", fmt.Errorf("could not create artifact archive: %w", err)
+	}
+
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	size, err := blob.Download(ctx, cfg.client, response.SignedURL, archive)
+	if err != nil {
+		return "", fmt.Errorf("could not download artifact %q: %w", name, err)
+	}
+
+	if err := extract(archive, size, dest); err != nil {
+		return "", fmt.Errorf("could not extract artifact %q: %w", name, err)
+	}
+
+	return dest, nil
+}
+
+// connect returns a client for the artifact service, along with the backend IDs of the
+// current run and job.
+//
+// The backend IDs are only available from the scopes in the runtime token, which look like
+// "Actions.Results:<run backend ID>:<job backend ID>".
+func connect(cfg config) (*twirp.Client, backendIDs, error) {
+	base := os.Getenv(resultsURLVar)
+	if base == "" {
+		return nil, backendIDs{}, fmt.Errorf("$%s is not set or is empty", resultsURLVar)
+	}
+
+	token := os.Getenv(tokenVar)
+	if token == "" {
+		return nil, backendIDs{}, fmt.Errorf("$%s is not set or is empty", tokenVar)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, backendIDs{}, fmt.Errorf("$%s is not a valid token", tokenVar)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, backendIDs{}, fmt.Errorf("$%s is not a valid token: %w", tokenVar, err)
+	}
+
+	var claims struct {
+		Scope string `json:"scp"`
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, backendIDs{}, fmt.Errorf("$%s is not a valid token: %w", tokenVar, err)
+	}
+
+	for scope := range strings.FieldsSeq(claims.Scope) {
+		parts := strings.Split(scope, ":")
+		if len(parts) == 3 && parts[0] == "Actions.Results" {
+			client := &twirp.Client{HTTP: cfg.client, BaseURL: base, Token: token}
+			return client, backendIDs{RunID: parts[1], JobID: parts[2]}, nil
+		}
+	}
+
+	return nil, backendIDs{}, fmt.Errorf("$%s does not contain the workflow run and job backend IDs", tokenVar)
+}
+
+// retention returns the number of days an artifact should be retained for, limited to
+// $GITHUB_RETENTION_DAYS if it's set.
+func retention(days int) (int, error) {
+	if days < 0 {
+		return 0, fmt.Errorf("invalid retention days %d", days)
+	}
+
+	limit := os.Getenv(retentionVar)
+	if limit == "" {
+		return days, nil
+	}
+
+	maxDays, err := strconv.Atoi(limit)
+	if err != nil {
+		return 0, fmt.Errorf("$%s is not an integer: %q", retentionVar, limit)
+	}
+
+	if days == 0 || days > maxDays {
+		return maxDays, nil
+	}
+
+	return days, nil
+}
+
+// newConfig builds the config from the default and options.
+func newConfig(options []Option) config {
+	cfg := config{
+		client: http.DefaultClient,
+		level:  defaultLevel,
+	}
+
+	for _, option := range options {
+		option.apply(&cfg)
+	}
+
+	return cfg
+}
+
+// validateName checks name is an acceptable artifact name.
+func validateName(name string) error {
+	if name == "" {
+		return errors.New("artifact name must not be empty")
+	}
+
+	if i := strings.IndexAny(name, invalidNameChars); i != -1 {
+		return fmt.Errorf("artifact name %q contains invalid character %q", name, name[i])
+	}
+
+	return nil
+}
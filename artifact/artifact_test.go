@@ -0,0 +1,502 @@
+package artifact_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/artifact"
+	"go.followtheprocess.codes/test"
+)
+
+// fakeArtifact is an artifact stored by the fake artifact service.
+type fakeArtifact struct {
+	expiresAt string
+	name      string
+	hash      string
+	data      []byte
+	id        int64
+	size      int64
+	finalized bool
+}
+
+// fakeArtifacts is an in-memory implementation of the artifact service, and the blob storage
+// it hands out signed URLs for.
+type fakeArtifacts struct {
+	server    *httptest.Server
+	blocks    map[string][]byte // Staged blocks, by artifact ID and block ID
+	artifacts []*fakeArtifact   // All artifacts, in order of creation
+	mu        sync.Mutex
+}
+
+// newFakeArtifacts starts a fake artifact service and sets up the environment to use it, along
+// with a workspace and runner temp directory.
+func newFakeArtifacts(t *testing.T) *fakeArtifacts {
+	t.Helper()
+
+	fake := &fakeArtifacts{blocks: make(map[string][]byte)}
+
+	fake.server = httptest.NewServer(fake)
+	t.Cleanup(fake.server.Close)
+
+	claims := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"scp": "Actions.ExampleScope Actions.Results:run-backend-id:job-backend-id"}`),
+	)
+
+	t.Setenv("ACTIONS_RESULTS_URL", fake.server.URL+"/")
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "header."+claims+".signature")
+	t.Setenv("GITHUB_RETENTION_DAYS", "")
+	t.Setenv("RUNNER_TEMP", t.TempDir())
+	t.Setenv("GITHUB_WORKSPACE", t.TempDir())
+
+	return fake
+}
+
+// add adds a finalized artifact directly, returning its ID.
+func (f *fakeArtifacts) add(name string, data []byte) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := int64(len(f.artifacts) + 1)
+	f.artifacts = append(f.artifacts, &fakeArtifact{
+		name:      name,
+		data:      data,
+		id:        id,
+		size:      int64(len(data)),
+		finalized: true,
+	})
+
+	return id
+}
+
+// get returns the artifact called name.
+func (f *fakeArtifacts) get(name string) *fakeArtifact {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, artifact := range f.artifacts {
+		if artifact.name == name {
+			return artifact
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeArtifacts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/twirp/github.actions.results.api.v1.ArtifactService/"):
+		f.twirp(w, r, strings.TrimPrefix(r.URL.Path, "/twirp/github.actions.results.api.v1.ArtifactService/"))
+	case strings.HasPrefix(r.URL.Path, "/blob/"):
+		f.blob(w, r, strings.TrimPrefix(r.URL.Path, "/blob/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeArtifacts) twirp(w http.ResponseWriter, r *http.Request, method string) {
+	var request struct {
+		RunID      string `json:"workflow_run_backend_id"`
+		JobID      string `json:"workflow_job_run_backend_id"`
+		Name       string `json:"name"`
+		NameFilter string `json:"name_filter"`
+		IDFilter   string `json:"id_filter"`
+		ExpiresAt  string `json:"expires_at"`
+		Hash       string `json:"hash"`
+		Size       string `json:"size"`
+		Version    int    `json:"version"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+os.Getenv("ACTIONS_RUNTIME_TOKEN") ||
+		request.RunID != "run-backend-id" || request.JobID != "job-backend-id" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"code": "unauthenticated", "msg": "bad token"})
+
+		return
+	}
+
+	switch method {
+	case "CreateArtifact":
+		for _, existing := range f.artifacts {
+			if existing.name == request.Name {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]string{"code": "already_exists", "msg": "artifact exists"})
+
+				return
+			}
+		}
+
+		if request.Version != 4 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		id := int64(len(f.artifacts) + 1)
+		f.artifacts = append(f.artifacts, &fakeArtifact{name: request.Name, expiresAt: request.ExpiresAt, id: id})
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":                true,
+			"signed_upload_url": f.server.URL + "/blob/" + strconv.FormatInt(id, 10) + "?sig=abc",
+		})
+	case "FinalizeArtifact":
+		for _, existing := range f.artifacts {
+			if existing.name != request.Name || existing.finalized {
+				continue
+			}
+
+			existing.finalized = true
+			existing.hash = request.Hash
+			existing.size, _ = strconv.ParseInt(request.Size, 10, 64)
+
+			json.NewEncoder(w).Encode(map[string]any{"ok": true, "artifact_id": strconv.FormatInt(existing.id, 10)})
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"code": "not_found", "msg": "no such artifact"})
+	case "ListArtifacts":
+		artifacts := []map[string]any{}
+
+		for _, existing := range f.artifacts {
+			id := strconv.FormatInt(existing.id, 10)
+			if !existing.finalized || (request.NameFilter != "" && existing.name != request.NameFilter) ||
+				(request.IDFilter != "" && id != request.IDFilter) {
+				continue
+			}
+
+			artifacts = append(artifacts, map[string]any{
+				"workflow_run_backend_id":     request.RunID,
+				"workflow_job_run_backend_id": request.JobID,
+				"database_id":                 id,
+				"name":                        existing.name,
+				"size":                        strconv.FormatInt(existing.size, 10),
+				"created_at":                  "2025-01-02T03:04:05Z",
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"artifacts": artifacts})
+	case "GetSignedArtifactURL":
+		for _, existing := range f.artifacts {
+			if existing.name == request.Name && existing.finalized {
+				url := f.server.URL + "/blob/" + strconv.FormatInt(existing.id, 10) + "?sig=abc"
+				json.NewEncoder(w).Encode(map[string]string{"signed_url": url})
+
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"code": "not_found", "msg": "no such artifact"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeArtifacts) blob(w http.ResponseWriter, r *http.Request, id string) {
+	if r.URL.Query().Get("sig") != "abc" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var target *fakeArtifact
+
+	for _, existing := range f.artifacts {
+		if strconv.FormatInt(existing.id, 10) == id {
+			target = existing
+		}
+	}
+
+	if target == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		w.Write(target.data)
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "block":
+		f.blocks[id+"/"+r.URL.Query().Get("blockid")], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+
+		if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		target.data = nil
+		for _, block := range list.Latest {
+			target.data = append(target.data, f.blocks[id+"/"+block]...)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// writeFile writes contents to path, creating any parent directories.
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	test.Ok(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	test.Ok(t, os.WriteFile(path, []byte(contents), 0o644))
+}
+
+// readFile returns the contents of path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	contents, err := os.ReadFile(path)
+	test.Ok(t, err)
+
+	return string(contents)
+}
+
+func TestUploadDownload(t *testing.T) {
+	fake := newFakeArtifacts(t)
+	t.Setenv("GITHUB_RETENTION_DAYS", "10")
+
+	root := filepath.Join(t.TempDir(), "report")
+	writeFile(t, filepath.Join(root, "index.html"), "<h1>Report</h1>")
+	writeFile(t, filepath.Join(root, "assets", "style.css"), strings.Repeat("body {}\n", 100))
+	test.Ok(t, os.MkdirAll(filepath.Join(root, "empty"), 0o755))
+
+	files := []string{
+		filepath.Join(root, "index.html"),
+		filepath.Join(root, "assets", "style.css"),
+		filepath.Join(root, "empty"),
+		filepath.Join(root, "index.html"), // Duplicates are ignored
+	}
+
+	uploaded, err := artifact.Upload(t.Context(), "report", files, root, artifact.RetentionDays(30))
+	test.Ok(t, err)
+	test.Equal(t, uploaded.Name, "report")
+	test.Equal(t, uploaded.ID, 1)
+
+	stored := fake.get("report")
+	sum := sha256.Sum256(stored.data)
+
+	test.Equal(t, uploaded.Size, int64(len(stored.data)))
+	test.Equal(t, uploaded.Digest, "sha256:"+hex.EncodeToString(sum[:]))
+	test.Equal(t, stored.hash, uploaded.Digest)
+
+	// Retention is limited to $GITHUB_RETENTION_DAYS
+	expires, err := time.Parse(time.RFC3339Nano, stored.expiresAt)
+	test.Ok(t, err)
+	test.True(t, time.Until(expires) > 9*24*time.Hour && time.Until(expires) <= 10*24*time.Hour)
+
+	artifacts, err := artifact.List(t.Context())
+	test.Ok(t, err)
+	test.Equal(t, len(artifacts), 1)
+	test.Equal(t, artifacts[0].Name, "report")
+	test.Equal(t, artifacts[0].Size, uploaded.Size)
+	test.Equal(t, artifacts[0].CreatedAt, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	got, err := artifact.Get(t.Context(), "report")
+	test.Ok(t, err)
+	test.Equal(t, got.ID, uploaded.ID)
+
+	dest := t.TempDir()
+
+	dir, err := artifact.Download(t.Context(), uploaded.ID, dest)
+	test.Ok(t, err)
+	test.Equal(t, dir, dest)
+
+	test.Equal(t, readFile(t, filepath.Join(dest, "index.html")), "<h1>Report</h1>")
+	test.Equal(t, readFile(t, filepath.Join(dest, "assets", "style.css")), strings.Repeat("body {}\n", 100))
+
+	info, err := os.Stat(filepath.Join(dest, "empty"))
+	test.Ok(t, err)
+	test.True(t, info.IsDir())
+
+	// Defaults to the workspace
+	dir, err = artifact.DownloadByName(t.Context(), "report", "")
+	test.Ok(t, err)
+	test.Equal(t, dir, os.Getenv("GITHUB_WORKSPACE"))
+	test.Equal(t, readFile(t, filepath.Join(dir, "index.html")), "<h1>Report</h1>")
+
+	// Names are unique within a run
+	_, err = artifact.Upload(t.Context(), "report", files, root)
+	test.Err(t, err)
+}
+
+func TestCompressionLevel(t *testing.T) {
+	fake := newFakeArtifacts(t)
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "data.txt"), strings.Repeat("a", 1000))
+
+	files := []string{filepath.Join(root, "data.txt")}
+
+	for _, level := range []int{0, 9} {
+		name := "level-" + strconv.Itoa(level)
+
+		_, err := artifact.Upload(t.Context(), name, files, root, artifact.CompressionLevel(level))
+		test.Ok(t, err)
+
+		data := fake.get(name).data
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		test.Ok(t, err)
+		test.Equal(t, len(zr.File), 1)
+		test.Equal(t, zr.File[0].Name, "data.txt")
+
+		if level == 0 {
+			test.Equal(t, zr.File[0].Method, zip.Store)
+			test.Equal(t, zr.File[0].CompressedSize64, 1000)
+		} else {
+			test.Equal(t, zr.File[0].Method, zip.Deflate)
+			test.True(t, zr.File[0].CompressedSize64 < 100)
+		}
+	}
+}
+
+func TestUploadInvalid(t *testing.T) {
+	newFakeArtifacts(t)
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "ok.txt"), "ok")
+
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	writeFile(t, outside, "outside")
+
+	ok := []string{filepath.Join(root, "ok.txt")}
+
+	tests := []struct {
+		name    string
+		upload  string
+		wantErr string
+		files   []string
+		options []artifact.Option
+	}{
+		{
+			name:    "empty name",
+			upload:  "",
+			files:   ok,
+			wantErr: "artifact name must not be empty",
+		},
+		{
+			name:    "invalid name",
+			upload:  "my/artifact",
+			files:   ok,
+			wantErr: `artifact name "my/artifact" contains invalid character '/'`,
+		},
+		{
+			name:    "no files",
+			upload:  "artifact",
+			wantErr: "at least one file to upload is required",
+		},
+		{
+			name:    "outside root",
+			upload:  "artifact",
+			files:   []string{outside},
+			wantErr: outside + " is not within the root directory " + root,
+		},
+		{
+			name:    "compression level",
+			upload:  "artifact",
+			files:   ok,
+			options: []artifact.Option{artifact.CompressionLevel(10)},
+			wantErr: "invalid compression level 10, must be between 0 and 9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := artifact.Upload(t.Context(), tt.upload, tt.files, root, tt.options...)
+			test.Err(t, err)
+			test.Equal(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDownloadTraversal(t *testing.T) {
+	fake := newFakeArtifacts(t)
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for _, name := range []string{"ok.txt", "../evil.txt"} {
+		w, err := zw.Create(name)
+		test.Ok(t, err)
+
+		_, err = w.Write([]byte("data"))
+		test.Ok(t, err)
+	}
+
+	test.Ok(t, zw.Close())
+
+	id := fake.add("evil", buf.Bytes())
+
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+
+	_, err := artifact.Download(t.Context(), id, dest)
+	test.Err(t, err)
+	test.Equal(
+		t,
+		err.Error(),
+		`could not extract artifact "evil": artifact contains file "../evil.txt" outside of the destination directory`,
+	)
+
+	_, err = os.Stat(filepath.Join(parent, "evil.txt"))
+	test.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestNotFound(t *testing.T) {
+	newFakeArtifacts(t)
+
+	_, err := artifact.Get(t.Context(), "missing")
+	test.True(t, errors.Is(err, artifact.ErrNotFound))
+
+	_, err = artifact.Download(t.Context(), 42, t.TempDir())
+	test.True(t, errors.Is(err, artifact.ErrNotFound))
+
+	_, err = artifact.DownloadByName(t.Context(), "missing", t.TempDir())
+	test.True(t, errors.Is(err, artifact.ErrNotFound))
+}
+
+func TestRuntimeToken(t *testing.T) {
+	newFakeArtifacts(t)
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"scp": "Actions.ExampleScope"}`))
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "header."+claims+".signature")
+
+	_, err := artifact.List(t.Context())
+	test.Err(t, err)
+	test.Equal(t, err.Error(), "$ACTIONS_RUNTIME_TOKEN does not contain the workflow run and job backend IDs")
+
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "")
+
+	_, err = artifact.List(t.Context())
+	test.Err(t, err)
+	test.Equal(t, err.Error(), "$ACTIONS_RUNTIME_TOKEN is not set or is empty")
+}
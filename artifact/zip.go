@@ -0,0 +1,209 @@
+package artifact
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// filePermissions is the permissions used when extracting files that don't record any.
+const filePermissions = 0o644
+
+// zipEntry is a single file or directory to be added to an artifact zip.
+type zipEntry struct {
+	source string // Absolute path to the file or directory on disk
+	name   string // Slash separated path within the zip
+}
+
+// zipEntries works out where each of files goes in the zip, relative to root.
+func zipEntries(files []string, root string) ([]zipEntry, error) {
+	if len(files) == 0 {
+		return nil, errors.New("at least one file to upload is required")
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve root directory: %w", err)
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root directory %s is not a directory", root)
+	}
+
+	entries := make([]zipEntry, 0, len(files))
+	seen := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		source, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %w", file, err)
+		}
+
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, fmt.Errorf("could not upload %s: %w", file, err)
+		}
+
+		rel, err := filepath.Rel(root, source)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is not within the root directory %s", file, root)
+		}
+
+		name := filepath.ToSlash(rel)
+		if i := strings.IndexAny(name, invalidPathChars); i != -1 {
+			return nil, fmt.Errorf("path %q contains invalid character %q", name, name[i])
+		}
+
+		if info.IsDir() {
+			name += "/"
+		} else if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("could not upload %s: not a regular file or directory", file)
+		}
+
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		entries = append(entries, zipEntry{source: source, name: name})
+	}
+
+	return entries, nil
+}
+
+// writeZip writes a zip containing entries to w, compressed at level.
+func writeZip(w io.Writer, entries []zipEntry, level int) error {
+	zw := zip.NewWriter(w)
+
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+
+	for _, entry := range entries {
+		if err := addFile(zw, entry, level); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// addFile adds a single entry to zw.
+func addFile(zw *zip.Writer, entry zipEntry, level int) error {
+	info, err := os.Stat(entry.source)
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = entry.name
+
+	header.Method = zip.Deflate
+	if level == flate.NoCompression || info.IsDir() {
+		header.Method = zip.Store
+	}
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return nil
+	}
+
+	file, err := os.Open(entry.source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+
+	return err
+}
+
+// extract extracts the zip read from r, which is size bytes long, into dest.
+func extract(r io.ReaderAt, size int64, dest string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dest, dirPermissions); err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
+		target, err := within(dest, file.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := extractFile(file, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractFile extracts a single file from the zip to target.
+func extractFile(file *zip.File, target string) error {
+	if file.FileInfo().IsDir() {
+		return os.MkdirAll(target, dirPermissions)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), dirPermissions); err != nil {
+		return err
+	}
+
+	mode := file.Mode().Perm()
+	if mode == 0 {
+		mode = filePermissions
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+
+	return dst.Close()
+}
+
+// within returns the path name would be extracted to within dest, or an error if it
+// would escape it.
+func within(dest, name string) (string, error) {
+	local := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("artifact contains file %q outside of the destination directory", name)
+	}
+
+	return filepath.Join(dest, local), nil
+}
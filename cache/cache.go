@@ -0,0 +1,953 @@
+// Package cache saves and restores directories to and from the GitHub Actions cache service,
+// mirroring the @actions/cache package of the actions toolkit.
+//
+// Caches are identified by a key, and restoring falls back through an ordered list of restore
+// keys which match by prefix, most recently created first:
+//
+//	paths := []string{"~/.cache/go-build", "~/go/pkg/mod"}
+//	key := "go-" + runtime.GOOS + "-" + hash
+//
+//	result, err := cache.Restore(ctx, paths, key, []string{"go-" + runtime.GOOS + "-"})
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	// Do the expensive work
+//
+//	if !result.Exact {
+//		if err := cache.Save(ctx, paths, key); err != nil && !errors.Is(err, cache.ErrExists) {
+//			// Handle error
+//		}
+//	}
+//
+// Paths are archived with tar and gzip, both the original cache service at $ACTIONS_CACHE_URL
+// and the newer twirp based service at $ACTIONS_RESULTS_URL (used when the runner sets
+// $ACTIONS_CACHE_SERVICE_V2) are supported.
+//
+// See https://github.com/actions/toolkit/tree/main/packages/cache
+package cache // import "go.followtheprocess.codes/actions/cache"
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.followtheprocess.codes/actions/internal/blob"
+	"go.followtheprocess.codes/actions/internal/twirp"
+)
+
+const (
+	// cacheURLVar is the env var containing the URL of the (v1) cache service.
+	cacheURLVar = "ACTIONS_CACHE_URL"
+
+	// resultsURLVar is the env var containing the URL of the results service, which hosts
+	// the v2 cache service.
+	resultsURLVar = "ACTIONS_RESULTS_URL"
+
+	// serviceV2Var is the env var the runner sets when the v2 cache service should be used.
+	serviceV2Var = "ACTIONS_CACHE_SERVICE_V2"
+
+	// tokenVar is the env var containing the token used to authenticate with the runtime services.
+	tokenVar = "ACTIONS_RUNTIME_TOKEN"
+
+	// workspaceVar is the env var containing the path to the workspace, relative paths are
+	// resolved against it.
+	workspaceVar = "GITHUB_WORKSPACE"
+
+	// tempVar is the env var containing the path to the runner's temporary directory.
+	tempVar = "RUNNER_TEMP"
+
+	// service is the name of the v2 cache twirp service.
+	service = "github.actions.results.api.v1.CacheService"
+
+	// compression is the compression method, part of the cache version so that caches
+	// written by tools using a different method are never restored.
+	compression = "gzip"
+
+	// versionSalt is mixed into the cache version, bumped by the toolkit when the archive
+	// format changes incompatibly.
+	versionSalt = "1.0"
+
+	// maxKeyLength is the maximum length of a cache key.
+	maxKeyLength = 512
+
+	// maxKeys is the maximum number of keys, the primary key plus restore keys.
+	maxKeys = 10
+
+	// maxSize is the largest archive the cache service will accept, 10GB.
+	maxSize = 10 << 30
+
+	// defaultChunkSize is the size of each chunk of an upload, 32MB.
+	defaultChunkSize = 32 << 20
+
+	// dirPermissions is the permissions used when creating directories.
+	dirPermissions = 0o755
+)
+
+// ErrExists is returned from [Save] when a cache entry with the same key and version already
+// exists, or is being saved by another job. Cache entries are immutable so this is usually
+// safe to ignore.
+var ErrExists = errors.New("cache entry already exists")
+
+// Result is the outcome of a call to [Restore].
+type Result struct {
+	Key   string // The key of the cache entry that was restored, empty on a miss
+	Size  int64  // Size of the cache archive in bytes, 0 if not known
+	Exact bool   // Whether Key matched the primary key, rather than one of the restore keys
+}
+
+// Hit reports whether a cache entry was found, either by the primary key or a restore key.
+func (r Result) Hit() bool {
+	return r.Key != ""
+}
+
+// config holds the configuration for a single call to [Save] or [Restore].
+type config struct {
+	client     *http.Client // The HTTP client to use
+	chunkSize  int          // Size of each chunk of an upload
+	lookupOnly bool         // Only check whether an entry exists, don't download it
+}
+
+// Option is a configuration option for [Save] and [Restore].
+type Option interface {
+	// Apply the option to the config.
+	apply(cfg *config)
+}
+
+// option is a function that implements the Option interface.
+type option func(cfg *config)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(cfg *config) {
+	o(cfg)
+}
+
+// Client sets the [*http.Client] used to talk to the cache service, by default
+// [http.DefaultClient] is used.
+func Client(client *http.Client) Option {
+	f := func(cfg *config) {
+		cfg.client = client
+	}
+
+	return option(f)
+}
+
+// ChunkSize sets the size in bytes of each chunk the archive is uploaded in by [Save],
+// by default 32MB.
+func ChunkSize(size int) Option {
+	f := func(cfg *config) {
+		cfg.chunkSize = size
+	}
+
+	return option(f)
+}
+
+// LookupOnly makes [Restore] only check whether a matching cache entry exists, reporting
+// it in the [Result] without downloading it.
+func LookupOnly() Option {
+	f := func(cfg *config) {
+		cfg.lookupOnly = true
+	}
+
+	return option(f)
+}
+
+// Available reports whether the cache service is available in the current environment,
+// which it is not e.g. when running outside of GitHub Actions.
+func Available() bool {
+	if v2() {
+		return os.Getenv(resultsURLVar) != ""
+	}
+
+	return os.Getenv(cacheURLVar) != ""
+}
+
+// Version returns the version of a cache of paths, the cache service only matches entries
+// with the same version as well as key.
+//
+// The version is derived from the paths exactly as passed (so it changes if they are written
+// differently), the compression method and, on Windows, a marker so that caches are not
+// restored across operating systems.
+func Version(paths []string) string {
+	components := append([]string{}, paths...)
+	components = append(components, compression)
+
+	if runtime.GOOS == "windows" {
+		components = append(components, "windows-only")
+	}
+
+	components = append(components, versionSalt)
+
+	sum := sha256.Sum256([]byte(strings.Join(components, "|")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Restore restores the cache entry matching key, or failing that the first of restoreKeys
+// (in order) to match, to paths.
+//
+// The paths must be exactly the same as those passed to [Save], as must be the operating system.
+// Relative paths are resolved against $GITHUB_WORKSPACE and "~" is expanded to the home directory.
+//
+// A cache miss is not an error, use [Result.Hit] to check whether anything was restored.
+func Restore(ctx context.Context, paths []string, key string, restoreKeys []string, options ...Option) (Result, error) {
+	cfg := newConfig(options)
+
+	if len(paths) == 0 {
+		return Result{}, errors.New("at least one path to restore is required")
+	}
+
+	keys := append([]string{key}, restoreKeys...)
+	if len(keys) > maxKeys {
+		return Result{}, fmt.Errorf("too many keys, the limit is %d including the primary key", maxKeys)
+	}
+
+	for _, k := range keys {
+		if err := validateKey(k); err != nil {
+			return Result{}, err
+		}
+	}
+
+	version := Version(paths)
+
+	var (
+		entry entry
+		err   error
+	)
+
+	if v2() {
+		entry, err = lookupV2(ctx, cfg, key, restoreKeys, version)
+	} else {
+		entry, err = lookupV1(ctx, cfg, keys, version)
+	}
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	if entry.key == "" {
+		return Result{}, nil
+	}
+
+	result := Result{Key: entry.key, Exact: strings.EqualFold(entry.key, key)}
+
+	if cfg.lookupOnly {
+		return result, nil
+	}
+
+	workspace, err := workspaceDir()
+	if err != nil {
+		return Result{}, err
+	}
+
+	resolved, err := resolve(workspace, paths)
+	if err != nil {
+		return Result{}, err
+	}
+
+	archive, err := tempFile()
+	if err != nil {
+		return Result{}, err
+	}
+
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	result.Size, err = blob.Download(ctx, cfg.client, entry.url, archive)
+	if err != nil {
+		return Result{}, fmt.Errorf("could not download cache: %w", err)
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return Result{}, fmt.Errorf("could not read cache archive: %w", err)
+	}
+
+	if err := extract(archive, workspace, resolved); err != nil {
+		return Result{}, fmt.Errorf("could not extract cache: %w", err)
+	}
+
+	return result, nil
+}
+
+// Save archives paths and saves them to the cache under key.
+//
+// Relative paths are resolved against $GITHUB_WORKSPACE and "~" is expanded to the home directory,
+// paths that don't exist are skipped but at least one must exist. If an entry already exists for
+// key and these paths, an error wrapping [ErrExists] is returned.
+func Save(ctx context.Context, paths []string, key string, options ...Option) error {
+	cfg := newConfig(options)
+
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	if cfg.chunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size %d", cfg.chunkSize)
+	}
+
+	workspace, err := workspaceDir()
+	if err != nil {
+		return err
+	}
+
+	resolved, err := resolve(workspace, paths)
+	if err != nil {
+		return err
+	}
+
+	archive, err := tempFile()
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	if err = create(archive, workspace, resolved); err != nil {
+		return fmt.Errorf("could not create cache archive: %w", err)
+	}
+
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("could not read cache archive: %w", err)
+	}
+
+	if size > maxSize {
+		return fmt.Errorf("cache size of %d bytes is over the %d byte limit", size, int64(maxSize))
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not read cache archive: %w", err)
+	}
+
+	version := Version(paths)
+
+	if v2() {
+		return saveV2(ctx, cfg, key, version, archive, size)
+	}
+
+	return saveV1(ctx, cfg, key, version, archive, size)
+}
+
+// entry is a cache entry found by a lookup.
+type entry struct {
+	key string // The key of the entry, empty if none matched
+	url string // URL to download the entry archive from
+}
+
+// lookupV1 finds the first entry matching keys using the v1 cache service.
+func lookupV1(ctx context.Context, cfg config, keys []string, version string) (entry, error) {
+	query := url.Values{"keys": {strings.Join(keys, ",")}, "version": {version}}
+
+	var response struct {
+		CacheKey        string `json:"cacheKey"`
+		ArchiveLocation string `json:"archiveLocation"`
+	}
+
+	status, err := requestV1(ctx, cfg, http.MethodGet, "cache?"+query.Encode(), nil, &response)
+	if err != nil {
+		return entry{}, err
+	}
+
+	if status == http.StatusNoContent || response.ArchiveLocation == "" {
+		return entry{}, nil
+	}
+
+	return entry{key: response.CacheKey, url: response.ArchiveLocation}, nil
+}
+
+// lookupV2 finds the first entry matching key or restoreKeys using the v2 cache service.
+func lookupV2(ctx context.Context, cfg config, key string, restoreKeys []string, version string) (entry, error) {
+	request := struct {
+		Key         string   `json:"key"`
+		Version     string   `json:"version"`
+		RestoreKeys []string `json:"restore_keys,omitempty"`
+	}{
+		Key:         key,
+		Version:     version,
+		RestoreKeys: restoreKeys,
+	}
+
+	var response struct {
+		SignedDownloadURL string `json:"signed_download_url"`
+		MatchedKey        string `json:"matched_key"`
+		OK                bool   `json:"ok"`
+	}
+
+	client, err := twirpClient(cfg)
+	if err != nil {
+		return entry{}, err
+	}
+
+	if err := client.Call(ctx, service, "GetCacheEntryDownloadURL", request, &response); err != nil {
+		return entry{}, err
+	}
+
+	if !response.OK || response.SignedDownloadURL == "" {
+		return entry{}, nil
+	}
+
+	return entry{key: cmp.Or(response.MatchedKey, key), url: response.SignedDownloadURL}, nil
+}
+
+// saveV1 uploads archive to the v1 cache service, reserving the entry, uploading it in chunks
+// and then committing it.
+func saveV1(ctx context.Context, cfg config, key, version string, archive io.Reader, size int64) error {
+	reserve := struct {
+		Key       string `json:"key"`
+		Version   string `json:"version"`
+		CacheSize int64  `json:"cacheSize"`
+	}{
+		Key:       key,
+		Version:   version,
+		CacheSize: size,
+	}
+
+	var reserved struct {
+		CacheID int64 `json:"cacheId"`
+	}
+
+	status, err := requestV1(ctx, cfg, http.MethodPost, "caches", reserve, &reserved)
+	if err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict {
+			return fmt.Errorf("could not reserve cache with key %q: %w", key, ErrExists)
+		}
+
+		return err
+	}
+
+	if status == http.StatusNoContent || reserved.CacheID == 0 {
+		return fmt.Errorf("could not reserve cache with key %q: %w", key, ErrExists)
+	}
+
+	path := "caches/" + strconv.FormatInt(reserved.CacheID, 10)
+	chunk := make([]byte, cfg.chunkSize)
+
+	var offset int64
+
+	for offset < size {
+		n, err := io.ReadFull(archive, chunk)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("could not read cache archive: %w", err)
+		}
+
+		header := http.Header{
+			"Content-Type":  {"application/octet-stream"},
+			"Content-Range": {fmt.Sprintf("bytes %d-%d/*", offset, offset+int64(n)-1)},
+		}
+
+		if _, err := doV1(ctx, cfg, http.MethodPatch, path, header, bytes.NewReader(chunk[:n]), nil); err != nil {
+			return fmt.Errorf("could not upload cache chunk: %w", err)
+		}
+
+		offset += int64(n)
+	}
+
+	commit := struct {
+		Size int64 `json:"size"`
+	}{Size: size}
+
+	if _, err := requestV1(ctx, cfg, http.MethodPost, path, commit, nil); err != nil {
+		return fmt.Errorf("could not commit cache: %w", err)
+	}
+
+	return nil
+}
+
+// saveV2 uploads archive to the v2 cache service, creating the entry, uploading it to the
+// signed URL it returns and then finalising it.
+func saveV2(ctx context.Context, cfg config, key, version string, archive io.Reader, size int64) error {
+	client, err := twirpClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	create := struct {
+		Key     string `json:"key"`
+		Version string `json:"version"`
+	}{
+		Key:     key,
+		Version: version,
+	}
+
+	var created struct {
+		SignedUploadURL string `json:"signed_upload_url"`
+		OK              bool   `json:"ok"`
+	}
+
+	if err := client.Call(ctx, service, "CreateCacheEntry", create, &created); err != nil {
+		var twirpErr *twirp.Error
+		if errors.As(err, &twirpErr) && twirpErr.Code == "already_exists" {
+			return fmt.Errorf("could not reserve cache with key %q: %w", key, ErrExists)
+		}
+
+		return err
+	}
+
+	if !created.OK {
+		return fmt.Errorf("could not reserve cache with key %q: %w", key, ErrExists)
+	}
+
+	if _, err := blob.Upload(ctx, cfg.client, created.SignedUploadURL, archive, cfg.chunkSize); err != nil {
+		return fmt.Errorf("could not upload cache: %w", err)
+	}
+
+	finalize := struct {
+		Key       string `json:"key"`
+		Version   string `json:"version"`
+		SizeBytes int64  `json:"size_bytes,string"`
+	}{
+		Key:       key,
+		Version:   version,
+		SizeBytes: size,
+	}
+
+	var finalized struct {
+		EntryID int64 `json:"entry_id,string"`
+		OK      bool  `json:"ok"`
+	}
+
+	if err := client.Call(ctx, service, "FinalizeCacheEntryUpload", finalize, &finalized); err != nil {
+		return err
+	}
+
+	if !finalized.OK {
+		return fmt.Errorf("could not finalize cache with key %q", key)
+	}
+
+	return nil
+}
+
+// HTTPError is returned when the v1 cache service responds with an unsuccessful status code.
+type HTTPError struct {
+	Method     string // The HTTP method of the request
+	URL        string // The URL requested
+	Message    string // The message from the body of the response, if any
+	StatusCode int    // The HTTP status code of the response
+}
+
+// Error implements the error interface for [HTTPError].
+func (h *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s failed with status %d", h.Method, h.URL, h.StatusCode)
+	if h.Message != "" {
+		msg += ": " + h.Message
+	}
+
+	return msg
+}
+
+// requestV1 makes a JSON request to the v1 cache service, encoding body (if not nil) and
+// decoding the response into v (if not nil), returning the status code.
+func requestV1(ctx context.Context, cfg config, method, path string, body, v any) (int, error) {
+	header := http.Header{}
+
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("could not encode request body: %w", err)
+		}
+
+		reader = bytes.NewReader(data)
+
+		header.Set("Content-Type", "application/json")
+	}
+
+	return doV1(ctx, cfg, method, path, header, reader, v)
+}
+
+// doV1 makes a request to the v1 cache service, decoding any JSON response into v (if not nil).
+func doV1(ctx context.Context, cfg config, method, path string, header http.Header, body io.Reader, v any) (int, error) {
+	base := os.Getenv(cacheURLVar)
+	if base == "" {
+		return 0, fmt.Errorf("$%s is not set or is empty", cacheURLVar)
+	}
+
+	token := os.Getenv(tokenVar)
+	if token == "" {
+		return 0, fmt.Errorf("$%s is not set or is empty", tokenVar)
+	}
+
+	target := strings.TrimSuffix(base, "/") + "/_apis/artifactcache/" + path
+
+	request, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, fmt.Errorf("could not create request: %w", err)
+	}
+
+	request.Header = header
+	request.Header.Set("Accept", "application/json;api-version=6.0-preview.1")
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := cfg.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: %w", method, target, err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, fmt.Errorf("could not read response from %s %s: %w", method, target, err)
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		httpErr := &HTTPError{Method: method, URL: target, StatusCode: response.StatusCode}
+
+		var payload struct {
+			Message string `json:"message"`
+		}
+
+		if json.Unmarshal(data, &payload) == nil {
+			httpErr.Message = payload.Message
+		}
+
+		return response.StatusCode, httpErr
+	}
+
+	if v != nil && len(data) != 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			return response.StatusCode, fmt.Errorf("could not decode response from %s %s: %w", method, target, err)
+		}
+	}
+
+	return response.StatusCode, nil
+}
+
+// twirpClient returns a client for the v2 cache service.
+func twirpClient(cfg config) (*twirp.Client, error) {
+	base := os.Getenv(resultsURLVar)
+	if base == "" {
+		return nil, fmt.Errorf("$%s is not set or is empty", resultsURLVar)
+	}
+
+	token := os.Getenv(tokenVar)
+	if token == "" {
+		return nil, fmt.Errorf("$%s is not set or is empty", tokenVar)
+	}
+
+	return &twirp.Client{HTTP: cfg.client, BaseURL: base, Token: token}, nil
+}
+
+// newConfig builds the config from the default and options.
+func newConfig(options []Option) config {
+	cfg := config{
+		client:    http.DefaultClient,
+		chunkSize: defaultChunkSize,
+	}
+
+	for _, option := range options {
+		option.apply(&cfg)
+	}
+
+	return cfg
+}
+
+// v2 reports whether the v2 cache service should be used.
+func v2() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(serviceV2Var))
+	return enabled
+}
+
+// validateKey checks key is acceptable to the cache service.
+func validateKey(key string) error {
+	if key == "" {
+		return errors.New("cache key must not be empty")
+	}
+
+	if len(key) > maxKeyLength {
+		return fmt.Errorf("cache key %q is longer than %d characters", key, maxKeyLength)
+	}
+
+	if strings.Contains(key, ",") {
+		return fmt.Errorf("cache key %q must not contain commas", key)
+	}
+
+	return nil
+}
+
+// tempFile creates a uniquely named file in $RUNNER_TEMP to hold an archive.
+func tempFile() (*os.File, error) {
+	temp := os.Getenv(tempVar)
+	if temp == "" {
+		return nil, fmt.Errorf("$%s is not set or is empty", tempVar)
+	}
+
+	if err := os.MkdirAll(temp, dirPermissions); err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %w", err)
+	}
+
+	file, err := os.Create(filepath.Join(temp, rand.Text()+".tgz"))
+	if err != nil {
+		return nil, fmt.Errorf("could not create cache archive: %w", err)
+	}
+
+	return file, nil
+}
+
+// workspaceDir returns $GITHUB_WORKSPACE, or the current directory if that's not set.
+func workspaceDir() (string, error) {
+	if workspace := os.Getenv(workspaceVar); workspace != "" {
+		return filepath.Abs(workspace)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("could not get working directory: %w", err)
+	}
+
+	return cwd, nil
+}
+
+// resolve turns paths into clean absolute paths, expanding "~" and resolving relative paths
+// against workspace.
+func resolve(workspace string, paths []string) ([]string, error) {
+	resolved := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("could not expand %s: %w", path, err)
+			}
+
+			path = filepath.Join(home, path[1:])
+		}
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspace, path)
+		}
+
+		resolved = append(resolved, filepath.Clean(path))
+	}
+
+	if len(resolved) == 0 {
+		return nil, errors.New("at least one path is required")
+	}
+
+	return resolved, nil
+}
+
+// create writes a gzip compressed tar archive of paths to w.
+//
+// Entries are named relative to workspace (so paths outside of it start with "../") which
+// means they are restored to the same place even if the workspace moves, as it may
+// between self-hosted runners.
+func create(w io.Writer, workspace string, paths []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	found := false
+
+	for _, root := range paths {
+		if _, err := os.Lstat(root); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		found = true
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			return addEntry(tw, workspace, path, d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("none of the paths %s exist", strings.Join(paths, ", "))
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// addEntry adds the file, directory or symlink at path to tw.
+func addEntry(tw *tar.Writer, workspace, path string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	var link string
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err = os.Readlink(path)
+		if err != nil {
+			return err
+		}
+	} else if !info.Mode().IsRegular() && !info.IsDir() {
+		// Sockets, devices and the like can't be meaningfully cached
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+
+	name, err := filepath.Rel(workspace, path)
+	if err != nil {
+		return fmt.Errorf("could not cache %s: %w", path, err)
+	}
+
+	header.Name = filepath.ToSlash(name)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	// Ownership is deliberately not cached, restored files belong to whoever restores them
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+	if err = tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(tw, file)
+
+	return err
+}
+
+// extract extracts the gzip compressed tar archive read from r into workspace, restoring
+// only the entries that are within one of paths.
+//
+// Paths may be outside the workspace, so rather than extracting through an [os.Root], any
+// entry that would be written through a symlink extracted earlier from the archive is
+// rejected with an error.
+func extract(r io.Reader, workspace string, paths []string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	links := make(map[string]bool) // Symlinks extracted so far, by path
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(workspace, filepath.FromSlash(header.Name))
+		if !within(target, paths) {
+			continue
+		}
+
+		for dir := filepath.Dir(target); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if links[dir] {
+				return fmt.Errorf("illegal path %q in cache archive passes through symlink %q", header.Name, dir)
+			}
+		}
+
+		if err := extractEntry(tr, header, target); err != nil {
+			return err
+		}
+
+		links[target] = header.Typeflag == tar.TypeSymlink
+	}
+}
+
+// extractEntry writes a single tar entry to target.
+func extractEntry(tr *tar.Reader, header *tar.Header, target string) error {
+	mode := header.FileInfo().Mode()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, mode.Perm()|0o700)
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), dirPermissions); err != nil {
+			return err
+		}
+
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+
+		return os.Symlink(header.Linkname, target)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), dirPermissions); err != nil {
+			return err
+		}
+
+		// Remove rather than truncate so an existing symlink isn't followed
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode.Perm())
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(file, tr); err != nil {
+			return err
+		}
+
+		if err := file.Close(); err != nil {
+			return err
+		}
+
+		return os.Chtimes(target, header.ModTime, header.ModTime)
+	default:
+		return nil
+	}
+}
+
+// within reports whether target is one of paths, or inside one of them.
+func within(target string, paths []string) bool {
+	for _, path := range paths {
+		if rel, err := filepath.Rel(path, target); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+
+	return false
+}
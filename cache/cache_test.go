@@ -0,0 +1,559 @@
+package cache_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.followtheprocess.codes/actions/cache"
+	"go.followtheprocess.codes/test"
+)
+
+// fakeEntry is a committed entry in the fake cache service.
+type fakeEntry struct {
+	key     string
+	version string
+	data    []byte
+}
+
+// fakeCache is an in-memory implementation of both versions of the cache service, and the
+// blob storage the v2 service hands out signed URLs for.
+type fakeCache struct {
+	server  *httptest.Server
+	pending map[string]*fakeEntry // Entries by v1 cache ID or signed blob name, for uploads and downloads
+	blocks  map[string][]byte     // Staged v2 blocks, by blob name and block ID
+	entries []*fakeEntry          // Committed entries, oldest first
+	chunks  int                   // Number of upload requests, v1 chunks or v2 blocks
+	nextID  int                   // Last ID handed out
+	mu      sync.Mutex
+}
+
+// newFakeCache starts a fake cache service and sets up the environment to use it, along with
+// a workspace and runner temp directory.
+func newFakeCache(t *testing.T, v2 bool) *fakeCache {
+	t.Helper()
+
+	fake := &fakeCache{
+		pending: make(map[string]*fakeEntry),
+		blocks:  make(map[string][]byte),
+	}
+
+	fake.server = httptest.NewServer(fake)
+	t.Cleanup(fake.server.Close)
+
+	t.Setenv("ACTIONS_CACHE_URL", fake.server.URL+"/")
+	t.Setenv("ACTIONS_RESULTS_URL", fake.server.URL+"/")
+	t.Setenv("ACTIONS_CACHE_SERVICE_V2", strconv.FormatBool(v2))
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "runtime-token")
+	t.Setenv("RUNNER_TEMP", t.TempDir())
+	t.Setenv("GITHUB_WORKSPACE", t.TempDir())
+
+	return fake
+}
+
+// add adds a committed entry directly.
+func (f *fakeCache) add(key, version string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.entries = append(f.entries, &fakeEntry{key: key, version: version, data: data})
+}
+
+// match finds the entry for key, or failing that the first restore key to match exactly or
+// by prefix (newest first).
+func (f *fakeCache) match(key string, restoreKeys []string, version string) *fakeEntry {
+	for _, entry := range f.entries {
+		if entry.key == key && entry.version == version {
+			return entry
+		}
+	}
+
+	for _, restoreKey := range restoreKeys {
+		for i := len(f.entries) - 1; i >= 0; i-- {
+			entry := f.entries[i]
+			if strings.HasPrefix(entry.key, restoreKey) && entry.version == version {
+				return entry
+			}
+		}
+	}
+
+	return nil
+}
+
+// exists reports whether an entry for key and version is committed or pending.
+func (f *fakeCache) exists(key, version string) bool {
+	for _, entry := range f.entries {
+		if entry.key == key && entry.version == version {
+			return true
+		}
+	}
+
+	for _, entry := range f.pending {
+		if entry.key == key && entry.version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *fakeCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/_apis/artifactcache/"):
+		if r.Header.Get("Authorization") != "Bearer runtime-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		f.v1(w, r, strings.TrimPrefix(r.URL.Path, "/_apis/artifactcache/"))
+	case strings.HasPrefix(r.URL.Path, "/twirp/github.actions.results.api.v1.CacheService/"):
+		if r.Header.Get("Authorization") != "Bearer runtime-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		f.v2(w, r, strings.TrimPrefix(r.URL.Path, "/twirp/github.actions.results.api.v1.CacheService/"))
+	case strings.HasPrefix(r.URL.Path, "/blob/"):
+		f.blob(w, r, strings.TrimPrefix(r.URL.Path, "/blob/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCache) v1(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case r.Method == http.MethodGet && path == "cache":
+		keys := strings.Split(r.URL.Query().Get("keys"), ",")
+
+		entry := f.match(keys[0], keys[1:], r.URL.Query().Get("version"))
+		if entry == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		f.nextID++
+		name := strconv.Itoa(f.nextID)
+		f.pending["download-"+name] = entry
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"cacheKey":        entry.key,
+			"archiveLocation": f.server.URL + "/blob/download-" + name + "?sig=abc",
+		})
+	case r.Method == http.MethodPost && path == "caches":
+		var request struct {
+			Key       string `json:"key"`
+			Version   string `json:"version"`
+			CacheSize int64  `json:"cacheSize"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&request)
+
+		if f.exists(request.Key, request.Version) {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, `{"message": "Cache already exists. Scope: refs/heads/main, Key: %s"}`, request.Key)
+
+			return
+		}
+
+		f.nextID++
+		f.pending[strconv.Itoa(f.nextID)] = &fakeEntry{key: request.Key, version: request.Version}
+
+		json.NewEncoder(w).Encode(map[string]int{"cacheId": f.nextID})
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "caches/"):
+		entry := f.pending[strings.TrimPrefix(path, "caches/")]
+		if entry == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/*", &start, &end); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		chunk, _ := io.ReadAll(r.Body)
+		if start != len(entry.data) || end != start+len(chunk)-1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.chunks++
+		entry.data = append(entry.data, chunk...)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "caches/"):
+		id := strings.TrimPrefix(path, "caches/")
+
+		entry := f.pending[id]
+		if entry == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var request struct {
+			Size int `json:"size"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&request)
+
+		if request.Size != len(entry.data) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		delete(f.pending, id)
+		f.entries = append(f.entries, entry)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCache) v2(w http.ResponseWriter, r *http.Request, method string) {
+	var request struct {
+		Key         string   `json:"key"`
+		Version     string   `json:"version"`
+		SizeBytes   string   `json:"size_bytes"`
+		RestoreKeys []string `json:"restore_keys"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch method {
+	case "GetCacheEntryDownloadURL":
+		entry := f.match(request.Key, request.RestoreKeys, request.Version)
+		if entry == nil {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false})
+			return
+		}
+
+		f.nextID++
+		name := "download-" + strconv.Itoa(f.nextID)
+		f.pending[name] = entry
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":                  true,
+			"matched_key":         entry.key,
+			"signed_download_url": f.server.URL + "/blob/" + name + "?sig=abc",
+		})
+	case "CreateCacheEntry":
+		if f.exists(request.Key, request.Version) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"code": "already_exists", "msg": "cache entry exists"})
+
+			return
+		}
+
+		f.nextID++
+		name := "upload-" + strconv.Itoa(f.nextID)
+		f.pending[name] = &fakeEntry{key: request.Key, version: request.Version}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":                true,
+			"signed_upload_url": f.server.URL + "/blob/" + name + "?sig=abc",
+		})
+	case "FinalizeCacheEntryUpload":
+		for name, entry := range f.pending {
+			if !strings.HasPrefix(name, "upload-") || entry.key != request.Key || entry.version != request.Version {
+				continue
+			}
+
+			if request.SizeBytes != strconv.Itoa(len(entry.data)) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			delete(f.pending, name)
+			f.entries = append(f.entries, entry)
+
+			json.NewEncoder(w).Encode(map[string]any{"ok": true, "entry_id": strconv.Itoa(len(f.entries))})
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"code": "not_found", "msg": "no such entry"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCache) blob(w http.ResponseWriter, r *http.Request, name string) {
+	if r.URL.Query().Get("sig") != "abc" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	entry := f.pending[name]
+	if entry == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		w.Write(entry.data)
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "block":
+		f.chunks++
+		f.blocks[name+"/"+r.URL.Query().Get("blockid")], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		entry.data = nil
+		for _, id := range list.Latest {
+			entry.data = append(entry.data, f.blocks[name+"/"+id]...)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// writeFile writes contents to path, creating any parent directories.
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	test.Ok(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	test.Ok(t, os.WriteFile(path, []byte(contents), 0o644))
+}
+
+// readFile returns the contents of path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	contents, err := os.ReadFile(path)
+	test.Ok(t, err)
+
+	return string(contents)
+}
+
+func TestSaveRestore(t *testing.T) {
+	for _, v2 := range []bool{false, true} {
+		t.Run(fmt.Sprintf("v2=%v", v2), func(t *testing.T) {
+			fake := newFakeCache(t, v2)
+
+			workspace := os.Getenv("GITHUB_WORKSPACE")
+			outside := t.TempDir()
+
+			writeFile(t, filepath.Join(workspace, "deps", "a.txt"), "a")
+			writeFile(t, filepath.Join(workspace, "deps", "nested", "b.txt"), strings.Repeat("b", 100))
+			writeFile(t, filepath.Join(outside, "c.txt"), "c")
+			test.Ok(t, os.Symlink("a.txt", filepath.Join(workspace, "deps", "link")))
+
+			paths := []string{"deps", outside, "missing"}
+
+			test.Ok(t, cache.Save(t.Context(), paths, "deps-linux-abc", cache.ChunkSize(64)))
+			test.True(t, fake.chunks > 1) // Archive is bigger than one chunk
+
+			err := cache.Save(t.Context(), paths, "deps-linux-abc")
+			test.True(t, errors.Is(err, cache.ErrExists))
+
+			test.Ok(t, os.RemoveAll(filepath.Join(workspace, "deps")))
+			test.Ok(t, os.Remove(filepath.Join(outside, "c.txt")))
+
+			result, err := cache.Restore(t.Context(), paths, "deps-linux-abc", nil)
+			test.Ok(t, err)
+			test.True(t, result.Hit())
+			test.True(t, result.Exact)
+			test.Equal(t, result.Key, "deps-linux-abc")
+			test.True(t, result.Size > 0)
+
+			test.Equal(t, readFile(t, filepath.Join(workspace, "deps", "a.txt")), "a")
+			test.Equal(t, readFile(t, filepath.Join(workspace, "deps", "nested", "b.txt")), strings.Repeat("b", 100))
+			test.Equal(t, readFile(t, filepath.Join(outside, "c.txt")), "c")
+
+			link, err := os.Readlink(filepath.Join(workspace, "deps", "link"))
+			test.Ok(t, err)
+			test.Equal(t, link, "a.txt")
+
+			// Falls back to a restore key prefix
+			result, err = cache.Restore(t.Context(), paths, "deps-linux-def", []string{"nope-", "deps-linux-"})
+			test.Ok(t, err)
+			test.True(t, result.Hit())
+			test.False(t, result.Exact)
+			test.Equal(t, result.Key, "deps-linux-abc")
+
+			// Different paths are a different version
+			result, err = cache.Restore(t.Context(), []string{"deps"}, "deps-linux-abc", nil)
+			test.Ok(t, err)
+			test.False(t, result.Hit())
+
+			// Lookup only doesn't touch the files
+			test.Ok(t, os.RemoveAll(filepath.Join(workspace, "deps")))
+
+			result, err = cache.Restore(t.Context(), paths, "deps-linux-abc", nil, cache.LookupOnly())
+			test.Ok(t, err)
+			test.True(t, result.Exact)
+
+			_, err = os.Stat(filepath.Join(workspace, "deps"))
+			test.True(t, errors.Is(err, os.ErrNotExist))
+		})
+	}
+}
+
+func TestRestoreOnlyWithinPaths(t *testing.T) {
+	fake := newFakeCache(t, false)
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range []string{"deps/ok.txt", "other/bad.txt", "../escape.txt"} {
+		test.Ok(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 2, Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte("hi"))
+		test.Ok(t, err)
+	}
+
+	test.Ok(t, tw.Close())
+	test.Ok(t, gz.Close())
+
+	fake.add("key", cache.Version([]string{"deps"}), buf.Bytes())
+
+	result, err := cache.Restore(t.Context(), []string{"deps"}, "key", nil)
+	test.Ok(t, err)
+	test.True(t, result.Exact)
+
+	test.Equal(t, readFile(t, filepath.Join(workspace, "deps", "ok.txt")), "hi")
+
+	_, err = os.Stat(filepath.Join(workspace, "other"))
+	test.True(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(workspace), "escape.txt"))
+	test.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestRestoreThroughSymlink(t *testing.T) {
+	fake := newFakeCache(t, false)
+	outside := t.TempDir()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	test.Ok(t, tw.WriteHeader(&tar.Header{Name: "deps/link", Linkname: outside, Mode: 0o777, Typeflag: tar.TypeSymlink}))
+	test.Ok(t, tw.WriteHeader(&tar.Header{Name: "deps/link/passwd", Mode: 0o644, Size: 5, Typeflag: tar.TypeReg}))
+
+	_, err := tw.Write([]byte("pwned"))
+	test.Ok(t, err)
+
+	test.Ok(t, tw.Close())
+	test.Ok(t, gz.Close())
+
+	fake.add("key", cache.Version([]string{"deps"}), buf.Bytes())
+
+	_, err = cache.Restore(t.Context(), []string{"deps"}, "key", nil)
+	test.Err(t, err)
+	test.True(t, strings.Contains(err.Error(), "passes through symlink"), test.Context("wrong error: %v", err))
+
+	_, err = os.Stat(filepath.Join(outside, "passwd"))
+	test.True(t, errors.Is(err, os.ErrNotExist), test.Context("passwd was written outside the workspace"))
+}
+
+func TestValidation(t *testing.T) {
+	newFakeCache(t, false)
+
+	tests := []struct {
+		fn      func() error
+		name    string
+		wantErr string
+	}{
+		{
+			name: "empty key",
+			fn: func() error {
+				return cache.Save(t.Context(), []string{"deps"}, "")
+			},
+			wantErr: "cache key must not be empty",
+		},
+		{
+			name: "comma",
+			fn: func() error {
+				_, err := cache.Restore(t.Context(), []string{"deps"}, "a,b", nil)
+				return err
+			},
+			wantErr: `cache key "a,b" must not contain commas`,
+		},
+		{
+			name: "too long",
+			fn: func() error {
+				return cache.Save(t.Context(), []string{"deps"}, strings.Repeat("k", 513))
+			},
+			wantErr: fmt.Sprintf("cache key %q is longer than 512 characters", strings.Repeat("k", 513)),
+		},
+		{
+			name: "too many keys",
+			fn: func() error {
+				_, err := cache.Restore(t.Context(), []string{"deps"}, "key", strings.Split("a,b,c,d,e,f,g,h,i,j", ","))
+				return err
+			},
+			wantErr: "too many keys, the limit is 10 including the primary key",
+		},
+		{
+			name: "nothing to save",
+			fn: func() error {
+				return cache.Save(t.Context(), []string{"missing"}, "key")
+			},
+			wantErr: "could not create cache archive: none of the paths " +
+				filepath.Join(os.Getenv("GITHUB_WORKSPACE"), "missing") + " exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			test.Err(t, err)
+			test.Equal(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	a := cache.Version([]string{"a", "b"})
+
+	test.Equal(t, len(a), 64)
+	test.Equal(t, cache.Version([]string{"a", "b"}), a)
+	test.NotEqual(t, cache.Version([]string{"b", "a"}), a)
+	test.NotEqual(t, cache.Version([]string{"a"}), a)
+}
+
+func TestAvailable(t *testing.T) {
+	t.Setenv("ACTIONS_CACHE_SERVICE_V2", "")
+	t.Setenv("ACTIONS_CACHE_URL", "")
+	test.False(t, cache.Available())
+
+	t.Setenv("ACTIONS_CACHE_URL", "https://cache.example.com/")
+	test.True(t, cache.Available())
+
+	t.Setenv("ACTIONS_CACHE_SERVICE_V2", "true")
+	t.Setenv("ACTIONS_RESULTS_URL", "")
+	test.False(t, cache.Available())
+}
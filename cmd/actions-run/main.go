@@ -0,0 +1,386 @@
+// Command actions-run runs a GitHub Action locally in a fake runner environment, so that it
+// can be iterated on without pushing commits.
+//
+// Usage:
+//
+//	actions-run [flags] <action> [args...]
+//
+// The action is either an executable or, if it's a .go file or a directory, a main package
+// which is built with "go build" first. Any args are passed through to the action.
+//
+// The action is run with the inputs given by -inputs and -input, and the GITHUB_* and RUNNER_*
+// variables a real runner would set, the files such as $GITHUB_OUTPUT being created in a
+// temporary directory. If the directory given by -action contains an action.yml, defaults are
+// taken from the inputs declared there.
+//
+// The action's output is shown as it runs, followed by a report of its outputs, exported env,
+// state, $PATH additions, step summary and the workflow commands it wrote.
+//
+// The exit code is the action's, or 2 if the action could not be run at all.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"go.followtheprocess.codes/actions/internal/sandbox"
+	"go.followtheprocess.codes/actions/metadata"
+	"go.yaml.in/yaml/v3"
+)
+
+// usageCode is the exit code when the action could not be run.
+const usageCode = 2
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+// config is the parsed command line.
+type config struct {
+	inputs    pairs    // Inputs from -input
+	env       pairs    // Additional env vars from -env
+	state     pairs    // State from -state
+	inputFile string   // YAML file of inputs from -inputs
+	eventName string   // Name of the triggering event
+	eventPath string   // Path to the event payload fixture
+	workspace string   // Path to $GITHUB_WORKSPACE
+	actionDir string   // Directory containing action.yml
+	args      []string // The action and its arguments
+	debug     bool     // Whether to enable debug logging
+	keep      bool     // Whether to keep the temporary directory
+}
+
+// run runs the command, returning the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cfg, err := parseArgs(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "actions-run: %v\n", err)
+		return usageCode
+	}
+
+	code, err := execute(ctx, cfg, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "actions-run: %v\n", err)
+		return usageCode
+	}
+
+	return code
+}
+
+// parseArgs parses the command line arguments.
+func parseArgs(args []string, stderr io.Writer) (config, error) {
+	cfg := config{
+		inputs: make(pairs),
+		env:    make(pairs),
+		state:  make(pairs),
+	}
+
+	flags := flag.NewFlagSet("actions-run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: actions-run [flags] <action> [args...]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Run a GitHub Action locally in a fake runner environment.")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Flags:")
+		flags.PrintDefaults()
+	}
+
+	flags.Var(cfg.inputs, "input", "Set an input, as `name=value` (may be repeated)")
+	flags.Var(cfg.env, "env", "Set an environment variable, as `key=value` (may be repeated)")
+	flags.Var(cfg.state, "state", "Set state saved by a previous phase, as `key=value` (may be repeated)")
+	flags.StringVar(&cfg.inputFile, "inputs", "", "Read inputs from a YAML `file` mapping names to values")
+	flags.StringVar(&cfg.eventName, "event", "push", "The `name` of the event that triggered the workflow")
+	flags.StringVar(&cfg.eventPath, "event-path", "", "Read the event payload from a JSON `file`")
+	flags.StringVar(&cfg.workspace, "workspace", ".", "The `directory` to use as $GITHUB_WORKSPACE")
+	flags.StringVar(&cfg.actionDir, "action", ".", "The `directory` containing action.yml, for input defaults")
+	flags.BoolVar(&cfg.debug, "debug", false, "Enable debug logging, as if $RUNNER_DEBUG were set")
+	flags.BoolVar(&cfg.keep, "keep", false, "Keep the temporary directory containing the runner files")
+
+	if err := flags.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	cfg.args = flags.Args()
+	if len(cfg.args) == 0 {
+		flags.Usage()
+		return config{}, errors.New("no action given")
+	}
+
+	return cfg, nil
+}
+
+// execute runs the action described by cfg and prints the report, returning the action's
+// exit code. An error means the action could not be run.
+func execute(ctx context.Context, cfg config, stdout, stderr io.Writer) (int, error) {
+	var warnings []string
+
+	inputs, err := readInputs(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	warnings = append(warnings, applyDefaults(cfg.actionDir, inputs)...)
+
+	event := []byte("{}")
+	if cfg.eventPath != "" {
+		event, err = os.ReadFile(cfg.eventPath)
+		if err != nil {
+			return 0, fmt.Errorf("could not read event payload: %w", err)
+		}
+	}
+
+	root, err := os.MkdirTemp("", "actions-run-*")
+	if err != nil {
+		return 0, fmt.Errorf("could not create temporary directory: %w", err)
+	}
+
+	if cfg.keep {
+		fmt.Fprintf(stderr, "actions-run: runner files are in %s\n", root)
+	} else {
+		defer os.RemoveAll(root)
+	}
+
+	fake, err := sandbox.New(root, cfg.workspace, event)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd, err := command(ctx, cfg.args, root)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd.Dir = fake.Workspace
+	cmd.Env = environ(fake, cfg, inputs)
+
+	// Each stream has its own buffer, so a write to one can't land in the middle of a
+	// command on the other
+	stdoutBuf, stderrBuf := &strings.Builder{}, &strings.Builder{}
+	cmd.Stdout = io.MultiWriter(stdout, stdoutBuf)
+	cmd.Stderr = io.MultiWriter(stderr, stderrBuf)
+
+	code := 0
+
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return 0, fmt.Errorf("could not run %s: %w", cfg.args[0], err)
+		}
+
+		code = max(exitErr.ExitCode(), 1) // -1 if killed by a signal
+	}
+
+	results, err := collect(fake, stdoutBuf.String(), stderrBuf.String())
+	if err != nil {
+		return 0, err
+	}
+
+	results.warnings = warnings
+	results.code = code
+
+	results.write(stdout)
+
+	return code, nil
+}
+
+// readInputs returns the inputs from -inputs and -input, the latter taking precedence.
+func readInputs(cfg config) (map[string]string, error) {
+	inputs := make(map[string]string)
+
+	if cfg.inputFile != "" {
+		contents, err := os.ReadFile(cfg.inputFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read inputs: %w", err)
+		}
+
+		if err := yaml.Unmarshal(contents, &inputs); err != nil {
+			return nil, fmt.Errorf("invalid inputs file %s: %w", cfg.inputFile, err)
+		}
+	}
+
+	for name, value := range cfg.inputs {
+		inputs[name] = value
+	}
+
+	return inputs, nil
+}
+
+// applyDefaults fills in defaults for inputs not given from the action.yml in dir, if there
+// is one, returning warnings about anything the runner would warn about.
+func applyDefaults(dir string, inputs map[string]string) []string {
+	action, err := metadata.Load(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return []string{fmt.Sprintf("could not load action metadata, input defaults not applied: %v", err)}
+	}
+
+	var warnings []string
+
+	for name, input := range action.Inputs {
+		if _, ok := inputs[name]; ok {
+			if input.Deprecated() {
+				warnings = append(warnings, fmt.Sprintf("input %q is deprecated: %s", name, input.DeprecationMessage))
+			}
+
+			continue
+		}
+
+		switch {
+		case strings.Contains(input.Default, "${{"):
+			warnings = append(warnings, fmt.Sprintf("default of input %q is an expression, pass it explicitly", name))
+		case input.Required && input.Default == "":
+			warnings = append(warnings, fmt.Sprintf("input %q is required but was not given", name))
+		default:
+			inputs[name] = input.Default
+		}
+	}
+
+	var unexpected []string
+
+	for name := range inputs {
+		if _, ok := action.Inputs[name]; !ok {
+			unexpected = append(unexpected, name)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		slices.Sort(unexpected)
+		warnings = append(warnings, fmt.Sprintf("unexpected inputs %s, not declared in action.yml", strings.Join(unexpected, ", ")))
+	}
+
+	slices.Sort(warnings)
+
+	return warnings
+}
+
+// command returns the command running the action in args, building it into dir first if
+// it's a Go package rather than an executable.
+func command(ctx context.Context, args []string, dir string) (*exec.Cmd, error) {
+	target := args[0]
+
+	info, err := os.Stat(target)
+	if err != nil || (!info.IsDir() && !strings.HasSuffix(target, ".go")) {
+		if err == nil {
+			// A relative path must still work once run in the workspace
+			target, err = filepath.Abs(target)
+			if err != nil {
+				return nil, fmt.Errorf("could not resolve %s: %w", args[0], err)
+			}
+		}
+
+		return exec.CommandContext(ctx, target, args[1:]...), nil
+	}
+
+	binary := filepath.Join(dir, "bin", "action")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	build := exec.CommandContext(ctx, "go", "build", "-o", binary, target)
+	if output, err := build.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("could not build %s: %w\n%s", target, err, output)
+	}
+
+	return exec.CommandContext(ctx, binary, args[1:]...), nil
+}
+
+// environ returns the environment the action is run with, the current environment without
+// anything the runner would set, plus the sandbox's variables, inputs and overrides.
+func environ(fake sandbox.Sandbox, cfg config, inputs map[string]string) []string {
+	env := make(map[string]string)
+
+	for _, variable := range os.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+
+		isolated := false
+
+		for _, prefix := range []string{"GITHUB_", "RUNNER_", "INPUT_", "STATE_", "ACTIONS_"} {
+			if strings.HasPrefix(key, prefix) {
+				isolated = true
+				break
+			}
+		}
+
+		if !isolated {
+			env[key] = value
+		}
+	}
+
+	for key, value := range fake.Env(cfg.eventName) {
+		env[key] = value
+	}
+
+	if cfg.debug {
+		env["RUNNER_DEBUG"] = "1"
+	}
+
+	for name, value := range inputs {
+		env[sandbox.InputVar(name)] = value
+	}
+
+	for key, value := range cfg.state {
+		env["STATE_"+key] = value
+	}
+
+	for key, value := range cfg.env {
+		env[key] = value
+	}
+
+	environ := make([]string, 0, len(env))
+	for key, value := range env {
+		environ = append(environ, key+"="+value)
+	}
+
+	slices.Sort(environ)
+
+	return environ
+}
+
+// pairs is a repeatable flag of key=value pairs.
+type pairs map[string]string
+
+// String implements [flag.Value] for pairs.
+func (p pairs) String() string {
+	s := make([]string, 0, len(p))
+	for key, value := range p {
+		s = append(s, key+"="+value)
+	}
+
+	slices.Sort(s)
+
+	return strings.Join(s, ",")
+}
+
+// Set implements [flag.Value] for pairs.
+func (p pairs) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("%q must be of the form key=value", value)
+	}
+
+	p[key] = val
+
+	return nil
+}
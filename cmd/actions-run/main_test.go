@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions"
+	"go.followtheprocess.codes/actions/input"
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+// modeVar is the env var that, when set, makes the test binary behave as a fake action.
+const modeVar = "ACTIONS_RUN_TEST_MODE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(modeVar) == "action" {
+		os.Exit(fakeAction())
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeAction is the action run by the tests, which is the test binary itself.
+func fakeAction() int {
+	logger := log.New(os.Stdout)
+
+	name, _ := input.Get("name")
+	greeting, _ := input.Get("greeting")
+
+	ctx, err := actions.Context()
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	for _, err := range []error{
+		actions.SetOutput("message", greeting+" "+name),
+		actions.SetOutput("event", ctx.EventName),
+		actions.SetOutput("lines", "one\ntwo"),
+		actions.SetEnv("GREETED", name),
+		actions.SetState("pid", "42"),
+		actions.AddPath("/opt/fake/bin"),
+		actions.Summary("## Greeted " + name),
+	} {
+		if err != nil {
+			logger.Error(err.Error())
+			return 1
+		}
+	}
+
+	logger.Warning("careful now", log.File("main.go"), log.Lines(3, 3))
+	fmt.Fprintln(os.Stderr, "some plain output")
+
+	// A command split across writes, with a write to stderr in between
+	fmt.Fprint(os.Stdout, "::notice::")
+	fmt.Fprintln(os.Stderr, "::debug::from stderr")
+	fmt.Fprintln(os.Stdout, "split across writes")
+
+	if fail, _ := input.Bool("fail"); fail {
+		logger.Error("told to fail")
+		return 3
+	}
+
+	return 0
+}
+
+func TestRun(t *testing.T) {
+	actionDir := t.TempDir()
+	metadata := `name: Fake
+description: A fake action
+inputs:
+  name:
+    description: Who to greet
+    required: true
+  greeting:
+    description: The greeting
+    default: Hello
+  token:
+    description: A token
+    default: ${{ github.token }}
+  fail:
+    description: Whether to fail
+    default: "false"
+runs:
+  using: docker
+  image: Dockerfile
+`
+	test.Ok(t, os.WriteFile(filepath.Join(actionDir, "action.yml"), []byte(metadata), 0o644))
+
+	t.Run("success", func(t *testing.T) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+		code := run(context.Background(), []string{
+			"-input", "name=Gopher",
+			"-event", "workflow_dispatch",
+			"-env", modeVar + "=action",
+			"-workspace", t.TempDir(),
+			"-action", actionDir,
+			os.Args[0],
+		}, stdout, stderr)
+
+		test.Equal(t, code, 0)
+		test.True(t, strings.Contains(stderr.String(), "some plain output"))
+
+		report := stdout.String()
+		for _, want := range []string{
+			"::warning file=main.go,line=3,endLine=3::careful now", // Streamed as it runs
+			`default of input "token" is an expression`,
+			"  event   = workflow_dispatch\n",
+			"  lines   = one\n            two\n",
+			"  message = Hello Gopher\n",
+			"Env:\n  GREETED = Gopher\n",
+			"State:\n  pid = 42\n",
+			"Path:\n  /opt/fake/bin\n",
+			"Summary:\n  ## Greeted Gopher\n",
+			`  warning (endLine="3", file="main.go", line="3"): careful now`,
+			"  notice: split across writes\n",
+			"  debug: from stderr\n",
+			"Exit code: 0",
+		} {
+			test.True(t, strings.Contains(report, want), test.Context("report missing %q:\n%s", want, report))
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		inputs := filepath.Join(t.TempDir(), "inputs.yml")
+		test.Ok(t, os.WriteFile(inputs, []byte("name: Gopher\nfail: true\nextra: 1\n"), 0o644))
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+		code := run(context.Background(), []string{
+			"-inputs", inputs,
+			"-env", modeVar + "=action",
+			"-workspace", t.TempDir(),
+			"-action", actionDir,
+			os.Args[0],
+		}, stdout, stderr)
+
+		test.Equal(t, code, 3)
+
+		report := stdout.String()
+		for _, want := range []string{
+			"unexpected inputs extra, not declared in action.yml",
+			"  error: told to fail",
+			"Exit code: 3",
+		} {
+			test.True(t, strings.Contains(report, want), test.Context("report missing %q:\n%s", want, report))
+		}
+	})
+
+	t.Run("required input missing", func(t *testing.T) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+		run(context.Background(), []string{
+			"-env", modeVar + "=action",
+			"-workspace", t.TempDir(),
+			"-action", actionDir,
+			os.Args[0],
+		}, stdout, stderr)
+
+		test.True(t, strings.Contains(stdout.String(), `input "name" is required but was not given`))
+	})
+}
+
+func TestRunGoPackage(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	t.Setenv("GOWORK", "off")
+
+	dir := t.TempDir()
+	source := `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println("::notice::built from source")
+	os.Exit(4)
+}
+`
+	test.Ok(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0o644))
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	code := run(context.Background(), []string{"-workspace", t.TempDir(), filepath.Join(dir, "main.go")}, stdout, stderr)
+	test.Equal(t, code, 4, test.Context("stderr: %s", stderr.String()))
+	test.True(t, strings.Contains(stdout.String(), "  notice: built from source"))
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string   // Name of the test case
+		want string   // Expected message on stderr
+		args []string // Command line arguments
+		code int      // Expected exit code
+	}{
+		{
+			name: "help",
+			args: []string{"-h"},
+			want: "Usage: actions-run",
+			code: 0,
+		},
+		{
+			name: "no action",
+			args: []string{},
+			want: "no action given",
+			code: usageCode,
+		},
+		{
+			name: "bad input",
+			args: []string{"-input", "nonsense", "action"},
+			want: `"nonsense" must be of the form key=value`,
+			code: usageCode,
+		},
+		{
+			name: "missing action",
+			args: []string{"-workspace", os.TempDir(), "does-not-exist"},
+			want: "could not run does-not-exist",
+			code: usageCode,
+		},
+		{
+			name: "missing event",
+			args: []string{"-event-path", "missing.json", "action"},
+			want: "could not read event payload",
+			code: usageCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+			code := run(context.Background(), tt.args, stdout, stderr)
+			test.Equal(t, code, tt.code)
+			test.True(t, strings.Contains(stderr.String(), tt.want), test.Context("stderr: %s", stderr.String()))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.followtheprocess.codes/actions/internal/sandbox"
+	"go.followtheprocess.codes/actions/log"
+)
+
+// indent is the indentation of everything under a report heading.
+const indent = "  "
+
+// results is everything the action did, as reported once it finishes.
+type results struct {
+	outputs  map[string]string // Outputs written to $GITHUB_OUTPUT
+	env      map[string]string // Env vars written to $GITHUB_ENV
+	state    map[string]string // State written to $GITHUB_STATE
+	summary  string            // Contents of $GITHUB_STEP_SUMMARY
+	path     []string          // Directories written to $GITHUB_PATH
+	commands []log.Command     // Workflow commands written to stdout, then those written to stderr
+	warnings []string          // Warnings about how the action was run
+	code     int               // The action's exit code
+}
+
+// collect reads the results from the sandbox's files and the action's stdout and stderr.
+func collect(fake sandbox.Sandbox, stdout, stderr string) (results, error) {
+	var (
+		res results
+		err error
+	)
+
+	if res.outputs, err = fake.Parse("GITHUB_OUTPUT"); err != nil {
+		return results{}, err
+	}
+
+	if res.env, err = fake.Parse("GITHUB_ENV"); err != nil {
+		return results{}, err
+	}
+
+	if res.state, err = fake.Parse("GITHUB_STATE"); err != nil {
+		return results{}, err
+	}
+
+	if res.path, err = fake.Path(); err != nil {
+		return results{}, err
+	}
+
+	if res.summary, err = fake.Summary(); err != nil {
+		return results{}, err
+	}
+
+	// The runner parses each stream separately, so commands are too, those on stdout first
+	for _, output := range []string{stdout, stderr} {
+		for command, err := range log.Commands(strings.NewReader(output)) {
+			if err != nil {
+				return results{}, fmt.Errorf("could not read action output: %w", err)
+			}
+
+			res.commands = append(res.commands, command)
+		}
+	}
+
+	return res, nil
+}
+
+// write writes the human readable report of the results to w.
+func (r results) write(w io.Writer) {
+	fmt.Fprintln(w)
+
+	if len(r.warnings) > 0 {
+		heading(w, "Warnings", len(r.warnings))
+
+		for _, warning := range r.warnings {
+			fmt.Fprintf(w, "%s%s\n", indent, warning)
+		}
+	}
+
+	heading(w, "Outputs", len(r.outputs))
+	values(w, r.outputs)
+
+	heading(w, "Env", len(r.env))
+	values(w, r.env)
+
+	heading(w, "State", len(r.state))
+	values(w, r.state)
+
+	heading(w, "Path", len(r.path))
+
+	for _, dir := range r.path {
+		fmt.Fprintf(w, "%s%s\n", indent, dir)
+	}
+
+	heading(w, "Summary", len(strings.TrimSpace(r.summary)))
+
+	if summary := strings.TrimSpace(r.summary); summary != "" {
+		fmt.Fprintf(w, "%s%s\n", indent, indented(summary, indent))
+	}
+
+	heading(w, "Commands", len(r.commands))
+
+	for _, command := range r.commands {
+		s := &strings.Builder{}
+		s.WriteString(indent)
+		s.WriteString(command.Name)
+
+		if len(command.Properties) > 0 {
+			properties := make([]string, 0, len(command.Properties))
+			for _, key := range slices.Sorted(maps.Keys(command.Properties)) {
+				properties = append(properties, fmt.Sprintf("%s=%q", key, command.Properties[key]))
+			}
+
+			fmt.Fprintf(s, " (%s)", strings.Join(properties, ", "))
+		}
+
+		if command.Message != "" {
+			s.WriteString(": ")
+			s.WriteString(indented(command.Message, indent+indent))
+		}
+
+		fmt.Fprintln(w, s.String())
+	}
+
+	fmt.Fprintf(w, "\nExit code: %d\n", r.code)
+}
+
+// heading writes a report section heading, or the heading and "(none)" if count is 0.
+func heading(w io.Writer, title string, count int) {
+	if count == 0 {
+		fmt.Fprintf(w, "%s: (none)\n", title)
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+}
+
+// values writes key value pairs sorted by key, with multi-line values indented to line up.
+func values(w io.Writer, pairs map[string]string) {
+	width := 0
+	for key := range pairs {
+		width = max(width, len(key))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(pairs)) {
+		padding := strings.Repeat(" ", width-len(key))
+		fmt.Fprintf(w, "%s%s%s = %s\n", indent, key, padding, indented(pairs[key], indent+strings.Repeat(" ", width+3)))
+	}
+}
+
+// indented indents every line of s after the first by prefix.
+func indented(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,284 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RefType is the type of git ref that triggered a workflow run.
+type RefType int
+
+const (
+	// RefTypeUnknown means $GITHUB_REF_TYPE was not set or was not recognised.
+	RefTypeUnknown RefType = iota
+
+	// RefTypeBranch means the workflow run was triggered by a branch.
+	RefTypeBranch
+
+	// RefTypeTag means the workflow run was triggered by a tag.
+	RefTypeTag
+)
+
+// String implements [fmt.Stringer] for [RefType].
+func (r RefType) String() string {
+	switch r {
+	case RefTypeBranch:
+		return "branch"
+	case RefTypeTag:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
+
+// Repository identifies a GitHub repository by its owner and name.
+type Repository struct {
+	Owner string // The repository owner e.g. "octocat"
+	Name  string // The repository name e.g. "hello-world"
+}
+
+// String returns the repository in "owner/name" form, as it appears in $GITHUB_REPOSITORY.
+func (r Repository) String() string {
+	if r.Owner == "" && r.Name == "" {
+		return ""
+	}
+
+	return r.Owner + "/" + r.Name
+}
+
+// Environment holds the default environment variables set by the actions runner
+// for every workflow run, parsed into appropriate types.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/store-information-in-variables#default-environment-variables
+type Environment struct {
+	ServerURL         *url.URL   // $GITHUB_SERVER_URL e.g. https://github.com
+	APIURL            *url.URL   // $GITHUB_API_URL e.g. https://api.github.com
+	GraphQLURL        *url.URL   // $GITHUB_GRAPHQL_URL e.g. https://api.github.com/graphql
+	Repository        Repository // $GITHUB_REPOSITORY split into owner and name
+	Action            string     // $GITHUB_ACTION, the name of the currently running action or step ID
+	ActionPath        string     // $GITHUB_ACTION_PATH, the path where the action is located
+	ActionRepository  string     // $GITHUB_ACTION_REPOSITORY, the owner and repository of the action being run
+	Actor             string     // $GITHUB_ACTOR, the user or app that initiated the workflow
+	BaseRef           string     // $GITHUB_BASE_REF, the target branch of a pull request
+	HeadRef           string     // $GITHUB_HEAD_REF, the source branch of a pull request
+	EventName         string     // $GITHUB_EVENT_NAME, the name of the event that triggered the workflow
+	EventPath         string     // $GITHUB_EVENT_PATH, path to the file containing the full event payload
+	Job               string     // $GITHUB_JOB, the ID of the current job
+	Ref               string     // $GITHUB_REF, the fully formed ref that triggered the workflow run
+	RefName           string     // $GITHUB_REF_NAME, the short ref name that triggered the workflow run
+	SHA               string     // $GITHUB_SHA, the commit SHA that triggered the workflow
+	TriggeringActor   string     // $GITHUB_TRIGGERING_ACTOR, the user that initiated the workflow run
+	Workflow          string     // $GITHUB_WORKFLOW, the name of the workflow
+	WorkflowRef       string     // $GITHUB_WORKFLOW_REF, the ref path to the workflow
+	WorkflowSHA       string     // $GITHUB_WORKFLOW_SHA, the commit SHA for the workflow file
+	Workspace         string     // $GITHUB_WORKSPACE, the default working directory for steps
+	EnvFile           string     // $GITHUB_ENV, path to the file used to set environment variables
+	OutputFile        string     // $GITHUB_OUTPUT, path to the file used to set step outputs
+	PathFile          string     // $GITHUB_PATH, path to the file used to add to $PATH
+	StateFile         string     // $GITHUB_STATE, path to the file used to save action state
+	StepSummaryFile   string     // $GITHUB_STEP_SUMMARY, path to the file used for the step summary
+	RunnerArch        string     // $RUNNER_ARCH, the architecture of the runner e.g. X64, ARM64
+	RunnerEnvironment string     // $RUNNER_ENVIRONMENT, github-hosted or self-hosted
+	RunnerName        string     // $RUNNER_NAME, the name of the runner executing the job
+	RunnerOS          string     // $RUNNER_OS, the operating system of the runner e.g. Linux, Windows, macOS
+	RunnerTemp        string     // $RUNNER_TEMP, path to a temporary directory emptied after each job
+	RunnerToolCache   string     // $RUNNER_TOOL_CACHE, path to the directory containing preinstalled tools
+	ActorID           int64      // $GITHUB_ACTOR_ID, the account ID of the actor
+	RepositoryID      int64      // $GITHUB_REPOSITORY_ID, the ID of the repository
+	RepositoryOwnerID int64      // $GITHUB_REPOSITORY_OWNER_ID, the account ID of the repository owner
+	RunID             int64      // $GITHUB_RUN_ID, a unique number for each workflow run in a repository
+	RunNumber         int64      // $GITHUB_RUN_NUMBER, a unique number for each run of a particular workflow
+	RunAttempt        int        // $GITHUB_RUN_ATTEMPT, the attempt number of the current run, starting at 1
+	RetentionDays     int        // $GITHUB_RETENTION_DAYS, the number of days artifacts and logs are retained
+	RefType           RefType    // $GITHUB_REF_TYPE, the type of ref that triggered the run
+	CI                bool       // $CI, always true on a runner
+	Actions           bool       // $GITHUB_ACTIONS, always true when running on GitHub Actions
+	RefProtected      bool       // $GITHUB_REF_PROTECTED, whether branch protections are configured for the ref
+	RunnerDebug       bool       // $RUNNER_DEBUG, whether debug logging is enabled
+}
+
+// MissingEnvError is returned from [Context] when one or more of the default
+// variables that the runner always sets are missing, which typically means the
+// action is running outside of GitHub Actions.
+type MissingEnvError struct {
+	Vars []string // Names of the missing variables, without the leading $
+}
+
+// Error implements the error interface for [MissingEnvError].
+func (m MissingEnvError) Error() string {
+	vars := make([]string, 0, len(m.Vars))
+	for _, name := range m.Vars {
+		vars = append(vars, "$"+name)
+	}
+
+	return "missing required environment variables: " + strings.Join(vars, ", ")
+}
+
+// Context returns the default environment variables for the current workflow run.
+//
+// Every variable is parsed into an appropriate type, e.g. IDs become integers and
+// URLs become [*url.URL]. Variables that are not always set (like $GITHUB_HEAD_REF)
+// are simply left as the zero value when absent.
+//
+// If any of the variables the runner always sets are missing, a [MissingEnvError] naming
+// each of them is returned, and any variables that are present but malformed are reported
+// alongside it. In either case the returned Environment is populated with everything
+// that could be parsed, so callers that only need a subset may choose to inspect it anyway.
+func Context() (Environment, error) {
+	p := &envParser{}
+
+	env := Environment{
+		ServerURL:         p.url("GITHUB_SERVER_URL", true),
+		APIURL:            p.url("GITHUB_API_URL", true),
+		GraphQLURL:        p.url("GITHUB_GRAPHQL_URL", true),
+		Repository:        p.repository("GITHUB_REPOSITORY", true),
+		Action:            p.string("GITHUB_ACTION", false),
+		ActionPath:        p.string("GITHUB_ACTION_PATH", false),
+		ActionRepository:  p.string("GITHUB_ACTION_REPOSITORY", false),
+		Actor:             p.string("GITHUB_ACTOR", true),
+		BaseRef:           p.string("GITHUB_BASE_REF", false),
+		HeadRef:           p.string("GITHUB_HEAD_REF", false),
+		EventName:         p.string("GITHUB_EVENT_NAME", true),
+		EventPath:         p.string("GITHUB_EVENT_PATH", true),
+		Job:               p.string("GITHUB_JOB", false),
+		Ref:               p.string("GITHUB_REF", false),
+		RefName:           p.string("GITHUB_REF_NAME", false),
+		SHA:               p.string("GITHUB_SHA", true),
+		TriggeringActor:   p.string("GITHUB_TRIGGERING_ACTOR", false),
+		Workflow:          p.string("GITHUB_WORKFLOW", true),
+		WorkflowRef:       p.string("GITHUB_WORKFLOW_REF", false),
+		WorkflowSHA:       p.string("GITHUB_WORKFLOW_SHA", false),
+		Workspace:         p.string("GITHUB_WORKSPACE", true),
+		EnvFile:           p.string("GITHUB_ENV", false),
+		OutputFile:        p.string("GITHUB_OUTPUT", false),
+		PathFile:          p.string("GITHUB_PATH", false),
+		StateFile:         p.string("GITHUB_STATE", false),
+		StepSummaryFile:   p.string("GITHUB_STEP_SUMMARY", false),
+		RunnerArch:        p.string("RUNNER_ARCH", true),
+		RunnerEnvironment: p.string("RUNNER_ENVIRONMENT", false),
+		RunnerName:        p.string("RUNNER_NAME", false),
+		RunnerOS:          p.string("RUNNER_OS", true),
+		RunnerTemp:        p.string("RUNNER_TEMP", true),
+		RunnerToolCache:   p.string("RUNNER_TOOL_CACHE", true),
+		ActorID:           p.int("GITHUB_ACTOR_ID", false),
+		RepositoryID:      p.int("GITHUB_REPOSITORY_ID", false),
+		RepositoryOwnerID: p.int("GITHUB_REPOSITORY_OWNER_ID", false),
+		RunID:             p.int("GITHUB_RUN_ID", true),
+		RunNumber:         p.int("GITHUB_RUN_NUMBER", true),
+		RunAttempt:        int(p.int("GITHUB_RUN_ATTEMPT", true)),
+		RetentionDays:     int(p.int("GITHUB_RETENTION_DAYS", false)),
+		RefType:           p.refType("GITHUB_REF_TYPE"),
+		CI:                p.bool("CI", "true"),
+		Actions:           p.bool("GITHUB_ACTIONS", "true"),
+		RefProtected:      p.bool("GITHUB_REF_PROTECTED", "true"),
+		RunnerDebug:       p.bool("RUNNER_DEBUG", "1"),
+	}
+
+	return env, p.err()
+}
+
+// envParser reads and parses environment variables, accumulating the names of
+// required variables that are missing and any parse errors along the way so they
+// can all be reported at once.
+type envParser struct {
+	missing []string // Names of required variables that were not set
+	errs    []error  // Errors parsing variables that were set
+}
+
+// string returns the value of the environment variable key, recording it as
+// missing if it is required but not set.
+func (p *envParser) string(key string, required bool) string {
+	value := os.Getenv(key)
+	if value == "" && required {
+		p.missing = append(p.missing, key)
+	}
+
+	return value
+}
+
+// int parses the environment variable key as a base 10 integer.
+func (p *envParser) int(key string, required bool) int64 {
+	value := p.string(key, required)
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("$%s is invalid integer: %q", key, value))
+		return 0
+	}
+
+	return n
+}
+
+// bool reports whether the environment variable key is set to truthy, which differs
+// depending on the variable, e.g. $RUNNER_DEBUG is "1" but $CI is "true".
+func (p *envParser) bool(key, truthy string) bool {
+	return p.string(key, false) == truthy
+}
+
+// url parses the environment variable key as an absolute URL.
+func (p *envParser) url(key string, required bool) *url.URL {
+	value := p.string(key, required)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || !parsed.IsAbs() {
+		p.errs = append(p.errs, fmt.Errorf("$%s is invalid URL: %q", key, value))
+		return nil
+	}
+
+	return parsed
+}
+
+// repository parses the environment variable key as an "owner/name" pair.
+func (p *envParser) repository(key string, required bool) Repository {
+	value := p.string(key, required)
+	if value == "" {
+		return Repository{}
+	}
+
+	owner, name, ok := strings.Cut(value, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		p.errs = append(p.errs, fmt.Errorf("$%s is invalid repository, expected owner/name: %q", key, value))
+		return Repository{}
+	}
+
+	return Repository{Owner: owner, Name: name}
+}
+
+// refType parses the environment variable key as a [RefType].
+func (p *envParser) refType(key string) RefType {
+	value := p.string(key, false)
+
+	switch value {
+	case "branch":
+		return RefTypeBranch
+	case "tag":
+		return RefTypeTag
+	case "":
+		return RefTypeUnknown
+	default:
+		p.errs = append(p.errs, fmt.Errorf("$%s is invalid ref type: %q", key, value))
+		return RefTypeUnknown
+	}
+}
+
+// err returns the combined error from everything parsed so far, or nil if everything
+// was present and valid.
+func (p *envParser) err() error {
+	errs := slices.Clone(p.errs)
+	if len(p.missing) != 0 {
+		errs = slices.Insert(errs, 0, error(MissingEnvError{Vars: p.missing}))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,184 @@
+package actions //nolint: testpackage // See actions_test.go
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+)
+
+// defaultVars is every default variable read by Context.
+var defaultVars = []string{
+	"CI",
+	"GITHUB_ACTION",
+	"GITHUB_ACTION_PATH",
+	"GITHUB_ACTION_REPOSITORY",
+	"GITHUB_ACTIONS",
+	"GITHUB_ACTOR",
+	"GITHUB_ACTOR_ID",
+	"GITHUB_API_URL",
+	"GITHUB_BASE_REF",
+	"GITHUB_ENV",
+	"GITHUB_EVENT_NAME",
+	"GITHUB_EVENT_PATH",
+	"GITHUB_GRAPHQL_URL",
+	"GITHUB_HEAD_REF",
+	"GITHUB_JOB",
+	"GITHUB_OUTPUT",
+	"GITHUB_PATH",
+	"GITHUB_REF",
+	"GITHUB_REF_NAME",
+	"GITHUB_REF_PROTECTED",
+	"GITHUB_REF_TYPE",
+	"GITHUB_REPOSITORY",
+	"GITHUB_REPOSITORY_ID",
+	"GITHUB_REPOSITORY_OWNER_ID",
+	"GITHUB_RETENTION_DAYS",
+	"GITHUB_RUN_ATTEMPT",
+	"GITHUB_RUN_ID",
+	"GITHUB_RUN_NUMBER",
+	"GITHUB_SERVER_URL",
+	"GITHUB_SHA",
+	"GITHUB_STATE",
+	"GITHUB_STEP_SUMMARY",
+	"GITHUB_TRIGGERING_ACTOR",
+	"GITHUB_WORKFLOW",
+	"GITHUB_WORKFLOW_REF",
+	"GITHUB_WORKFLOW_SHA",
+	"GITHUB_WORKSPACE",
+	"RUNNER_ARCH",
+	"RUNNER_DEBUG",
+	"RUNNER_ENVIRONMENT",
+	"RUNNER_NAME",
+	"RUNNER_OS",
+	"RUNNER_TEMP",
+	"RUNNER_TOOL_CACHE",
+}
+
+// validEnv is a complete, valid set of default variables.
+var validEnv = map[string]string{
+	"CI":                         "true",
+	"GITHUB_ACTION":              "__run",
+	"GITHUB_ACTIONS":             "true",
+	"GITHUB_ACTOR":               "octocat",
+	"GITHUB_ACTOR_ID":            "583231",
+	"GITHUB_API_URL":             "https://api.github.com",
+	"GITHUB_EVENT_NAME":          "push",
+	"GITHUB_EVENT_PATH":          "/home/runner/work/_temp/_github_workflow/event.json",
+	"GITHUB_GRAPHQL_URL":         "https://api.github.com/graphql",
+	"GITHUB_JOB":                 "build",
+	"GITHUB_REF":                 "refs/heads/main",
+	"GITHUB_REF_NAME":            "main",
+	"GITHUB_REF_PROTECTED":       "true",
+	"GITHUB_REF_TYPE":            "branch",
+	"GITHUB_REPOSITORY":          "octocat/hello-world",
+	"GITHUB_REPOSITORY_ID":       "1296269",
+	"GITHUB_REPOSITORY_OWNER_ID": "583231",
+	"GITHUB_RETENTION_DAYS":      "90",
+	"GITHUB_RUN_ATTEMPT":         "2",
+	"GITHUB_RUN_ID":              "1658821493",
+	"GITHUB_RUN_NUMBER":          "42",
+	"GITHUB_SERVER_URL":          "https://github.com",
+	"GITHUB_SHA":                 "ffac537e6cbbf934b08745a378932722df287a53",
+	"GITHUB_WORKFLOW":            "CI",
+	"GITHUB_WORKSPACE":           "/home/runner/work/hello-world/hello-world",
+	"RUNNER_ARCH":                "X64",
+	"RUNNER_DEBUG":               "1",
+	"RUNNER_OS":                  "Linux",
+	"RUNNER_TEMP":                "/home/runner/work/_temp",
+	"RUNNER_TOOL_CACHE":          "/opt/hostedtoolcache",
+}
+
+// setDefaultVars clears every default variable (as this project is itself tested on
+// GitHub Actions where they are all set) then sets those in env.
+func setDefaultVars(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for _, name := range defaultVars {
+		t.Setenv(name, "")
+	}
+
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestContext(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		setDefaultVars(t, validEnv)
+
+		env, err := Context()
+		test.Ok(t, err)
+
+		test.True(t, env.CI)
+		test.True(t, env.Actions)
+		test.True(t, env.RefProtected)
+		test.True(t, env.RunnerDebug)
+		test.Equal(t, env.Repository.Owner, "octocat")
+		test.Equal(t, env.Repository.Name, "hello-world")
+		test.Equal(t, env.Repository.String(), "octocat/hello-world")
+		test.Equal(t, env.RunID, int64(1658821493))
+		test.Equal(t, env.RunNumber, int64(42))
+		test.Equal(t, env.RunAttempt, 2)
+		test.Equal(t, env.RetentionDays, 90)
+		test.Equal(t, env.ActorID, int64(583231))
+		test.Equal(t, env.RefType, RefTypeBranch)
+		test.Equal(t, env.RefType.String(), "branch")
+		test.Equal(t, env.ServerURL.Host, "github.com")
+		test.Equal(t, env.APIURL.String(), "https://api.github.com")
+		test.Equal(t, env.GraphQLURL.Path, "/graphql")
+		test.Equal(t, env.RunnerOS, "Linux")
+		test.Equal(t, env.RunnerToolCache, "/opt/hostedtoolcache")
+		test.Equal(t, env.HeadRef, "") // Optional and not set
+	})
+
+	t.Run("outside runner", func(t *testing.T) {
+		setDefaultVars(t, nil)
+
+		_, err := Context()
+		test.Err(t, err)
+
+		var missing MissingEnvError
+
+		test.True(t, errors.As(err, &missing))
+		test.Equal(t, len(missing.Vars), 17)
+		test.Equal(t, missing.Vars[0], "GITHUB_SERVER_URL")
+	})
+
+	t.Run("some missing", func(t *testing.T) {
+		env := maps.Clone(validEnv)
+
+		delete(env, "GITHUB_SHA")
+		delete(env, "RUNNER_TEMP")
+
+		setDefaultVars(t, env)
+
+		got, err := Context()
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "missing required environment variables: $GITHUB_SHA, $RUNNER_TEMP")
+
+		// Everything else should still be populated
+		test.Equal(t, got.Repository.Name, "hello-world")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		env := maps.Clone(validEnv)
+
+		env["GITHUB_RUN_ID"] = "not a number"
+		env["GITHUB_REPOSITORY"] = "no-slash"
+		env["GITHUB_API_URL"] = "relative/path"
+		env["GITHUB_REF_TYPE"] = "commit"
+
+		setDefaultVars(t, env)
+
+		_, err := Context()
+		test.Err(t, err)
+
+		want := `$GITHUB_API_URL is invalid URL: "relative/path"` + "\n" +
+			`$GITHUB_REPOSITORY is invalid repository, expected owner/name: "no-slash"` + "\n" +
+			`$GITHUB_RUN_ID is invalid integer: "not a number"` + "\n" +
+			`$GITHUB_REF_TYPE is invalid ref type: "commit"`
+		test.Equal(t, err.Error(), want)
+	})
+}
@@ -0,0 +1,391 @@
+// Package event provides strongly typed access to the webhook event payload that triggered
+// the current workflow run.
+//
+// The actions runner writes the full JSON payload of the triggering event to the file
+// at $GITHUB_EVENT_PATH, and the name of the event to $GITHUB_EVENT_NAME. This package
+// reads both and decodes the payload into the appropriate Go type so callers can
+// simply type switch on the result:
+//
+//	payload, err := event.Read()
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	switch payload := payload.(type) {
+//	case *event.PushEvent:
+//		fmt.Println("pushed to", payload.Ref)
+//	case *event.PullRequestEvent:
+//		fmt.Println("pull request", payload.Number)
+//	default:
+//		// Some other event
+//	}
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-when-your-workflow-runs/events-that-trigger-workflows
+package event // import "go.followtheprocess.codes/actions/event"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// nameVar is the env var containing the name of the event that triggered the workflow.
+	nameVar = "GITHUB_EVENT_NAME"
+
+	// pathVar is the env var containing the path to the file holding the event payload.
+	pathVar = "GITHUB_EVENT_PATH"
+)
+
+// Read reads the event payload for the current workflow run.
+//
+// The name of the event is taken from $GITHUB_EVENT_NAME and the payload is
+// read from the file at $GITHUB_EVENT_PATH, both of which are set by the actions
+// runner. See [Parse] for details of the types that may be returned.
+//
+// If either variable is not set, or the payload cannot be read or decoded, an
+// error is returned.
+func Read() (any, error) {
+	name := os.Getenv(nameVar)
+	if name == "" {
+		return nil, fmt.Errorf("$%s is not set or is empty", nameVar)
+	}
+
+	path := os.Getenv(pathVar)
+	if path == "" {
+		return nil, fmt.Errorf("$%s is not set or is empty", pathVar)
+	}
+
+	//nolint:gosec // G304: path is set by the trusted Actions runner, not user input
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open $%s file %s: %w", pathVar, path, err)
+	}
+	defer file.Close()
+
+	return Parse(name, file)
+}
+
+// Parse decodes the JSON event payload read from r according to the named event.
+//
+// The returned value is a pointer to one of the event types in this package:
+//
+//	push                -> *PushEvent
+//	pull_request        -> *PullRequestEvent
+//	pull_request_target -> *PullRequestTargetEvent
+//	issue_comment       -> *IssueCommentEvent
+//	workflow_dispatch   -> *WorkflowDispatchEvent
+//	release             -> *ReleaseEvent
+//	schedule            -> *ScheduleEvent
+//	merge_group         -> *MergeGroupEvent
+//
+// Any other event is decoded generically and returned as a map[string]any.
+func Parse(name string, r io.Reader) (any, error) {
+	if name == "" {
+		return nil, errors.New("event name cannot be empty")
+	}
+
+	var payload any
+
+	switch name {
+	case "push":
+		payload = &PushEvent{}
+	case "pull_request":
+		payload = &PullRequestEvent{}
+	case "pull_request_target":
+		payload = &PullRequestTargetEvent{}
+	case "issue_comment":
+		payload = &IssueCommentEvent{}
+	case "workflow_dispatch":
+		payload = &WorkflowDispatchEvent{}
+	case "release":
+		payload = &ReleaseEvent{}
+	case "schedule":
+		payload = &ScheduleEvent{}
+	case "merge_group":
+		payload = &MergeGroupEvent{}
+	default:
+		generic := make(map[string]any)
+		if err := json.NewDecoder(r).Decode(&generic); err != nil {
+			return nil, fmt.Errorf("could not decode %s event payload: %w", name, err)
+		}
+
+		return generic, nil
+	}
+
+	if err := json.NewDecoder(r).Decode(payload); err != nil {
+		return nil, fmt.Errorf("could not decode %s event payload: %w", name, err)
+	}
+
+	return payload, nil
+}
+
+// PushEvent is the payload of the push event, triggered when one or more commits are
+// pushed to a branch or tag.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type PushEvent struct {
+	HeadCommit *Commit     `json:"head_commit"` // The most recent commit on ref after the push, nil if deleted
+	Pusher     CommitActor `json:"pusher"`      // The user who pushed the commits
+	After      string      `json:"after"`       // The SHA of the most recent commit on ref after the push
+	Before     string      `json:"before"`      // The SHA of the most recent commit on ref before the push
+	BaseRef    string      `json:"base_ref"`    // The base ref, if any
+	Compare    string      `json:"compare"`     // URL that shows the changes in this ref update
+	Ref        string      `json:"ref"`         // The full git ref that was pushed e.g. refs/heads/main
+	Commits    []Commit    `json:"commits"`     // The pushed commits, at most 2048
+	Sender     User        `json:"sender"`      // The user that triggered the event
+	Repository Repository  `json:"repository"`  // The repository that was pushed to
+	Created    bool        `json:"created"`     // Whether the push created the ref
+	Deleted    bool        `json:"deleted"`     // Whether the push deleted the ref
+	Forced     bool        `json:"forced"`      // Whether the push was a force push
+}
+
+// PullRequestEvent is the payload of the pull_request event, triggered by activity
+// on a pull request.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads#pull_request
+type PullRequestEvent struct {
+	Action      string      `json:"action"`       // The activity that triggered the event e.g. opened, synchronize
+	Repository  Repository  `json:"repository"`   // The repository the pull request is in
+	Sender      User        `json:"sender"`       // The user that triggered the event
+	PullRequest PullRequest `json:"pull_request"` // The pull request itself
+	Number      int         `json:"number"`       // The pull request number
+}
+
+// PullRequestTargetEvent is the payload of the pull_request_target event.
+//
+// The payload is identical to [PullRequestEvent] but the workflow runs in the context
+// of the base of the pull request rather than the merge commit. It is a distinct type
+// so that callers can tell the two apart in a type switch.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-when-your-workflow-runs/events-that-trigger-workflows#pull_request_target
+type PullRequestTargetEvent PullRequestEvent
+
+// IssueCommentEvent is the payload of the issue_comment event, triggered by activity
+// on a comment on an issue or pull request.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads#issue_comment
+type IssueCommentEvent struct {
+	Action     string     `json:"action"`     // The activity that triggered the event e.g. created, edited
+	Comment    Comment    `json:"comment"`    // The comment itself
+	Repository Repository `json:"repository"` // The repository the issue is in
+	Sender     User       `json:"sender"`     // The user that triggered the event
+	Issue      Issue      `json:"issue"`      // The issue (or pull request) the comment belongs to
+}
+
+// WorkflowDispatchEvent is the payload of the workflow_dispatch event, triggered when
+// a workflow is run manually.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads#workflow_dispatch
+type WorkflowDispatchEvent struct {
+	Inputs     map[string]any `json:"inputs"`     // The inputs passed to the workflow, values may be strings, bools or numbers
+	Ref        string         `json:"ref"`        // The ref the workflow was dispatched on
+	Workflow   string         `json:"workflow"`   // Path to the workflow file relative to the repository root
+	Sender     User           `json:"sender"`     // The user that triggered the event
+	Repository Repository     `json:"repository"` // The repository containing the workflow
+}
+
+// ReleaseEvent is the payload of the release event, triggered by activity on a release.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads#release
+type ReleaseEvent struct {
+	Action     string     `json:"action"`     // The activity that triggered the event e.g. published, created
+	Repository Repository `json:"repository"` // The repository the release belongs to
+	Sender     User       `json:"sender"`     // The user that triggered the event
+	Release    Release    `json:"release"`    // The release itself
+}
+
+// ScheduleEvent is the payload of the schedule event, triggered by a cron schedule.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-when-your-workflow-runs/events-that-trigger-workflows#schedule
+type ScheduleEvent struct {
+	Schedule   string     `json:"schedule"`   // The cron expression that triggered the run
+	Repository Repository `json:"repository"` // The repository containing the workflow
+}
+
+// MergeGroupEvent is the payload of the merge_group event, triggered by activity
+// in a merge queue.
+//
+// See https://docs.github.com/en/webhooks/webhook-events-and-payloads#merge_group
+type MergeGroupEvent struct {
+	Action     string     `json:"action"`      // The activity that triggered the event e.g. checks_requested
+	MergeGroup MergeGroup `json:"merge_group"` // The merge group itself
+	Sender     User       `json:"sender"`      // The user that triggered the event
+	Repository Repository `json:"repository"`  // The repository the merge queue belongs to
+}
+
+// User is a GitHub user, bot or organisation.
+type User struct {
+	Login     string `json:"login"`      // The user's login name
+	NodeID    string `json:"node_id"`    // The GraphQL node ID of the user
+	AvatarURL string `json:"avatar_url"` // URL of the user's avatar
+	HTMLURL   string `json:"html_url"`   // URL of the user's profile page
+	Type      string `json:"type"`       // The type of user e.g. User, Bot, Organization
+	ID        int64  `json:"id"`         // The unique ID of the user
+	SiteAdmin bool   `json:"site_admin"` // Whether the user is a site administrator
+}
+
+// Repository is a GitHub repository.
+type Repository struct {
+	CloneURL      string `json:"clone_url"`      // HTTPS clone URL of the repository
+	Name          string `json:"name"`           // The name of the repository without the owner
+	FullName      string `json:"full_name"`      // The owner and name of the repository e.g. octocat/hello-world
+	NodeID        string `json:"node_id"`        // The GraphQL node ID of the repository
+	Description   string `json:"description"`    // The repository description
+	HTMLURL       string `json:"html_url"`       // URL of the repository on GitHub
+	DefaultBranch string `json:"default_branch"` // The default branch e.g. main
+	Visibility    string `json:"visibility"`     // The visibility of the repository e.g. public, private, internal
+	Owner         User   `json:"owner"`          // The owner of the repository
+	ID            int64  `json:"id"`             // The unique ID of the repository
+	Private       bool   `json:"private"`        // Whether the repository is private
+	Fork          bool   `json:"fork"`           // Whether the repository is a fork
+	Archived      bool   `json:"archived"`       // Whether the repository is archived
+}
+
+// CommitActor is the author or committer of a git commit.
+type CommitActor struct {
+	Name     string `json:"name"`     // The git name of the actor
+	Email    string `json:"email"`    // The git email of the actor
+	Username string `json:"username"` // The GitHub username of the actor, if known
+}
+
+// Commit is a git commit as it appears in push and merge_group payloads.
+type Commit struct {
+	Timestamp time.Time   `json:"timestamp"` // When the commit was made
+	Author    CommitActor `json:"author"`    // The author of the commit
+	Committer CommitActor `json:"committer"` // The committer of the commit
+	ID        string      `json:"id"`        // The SHA of the commit
+	TreeID    string      `json:"tree_id"`   // The SHA of the commit's tree
+	Message   string      `json:"message"`   // The commit message
+	URL       string      `json:"url"`       // URL pointing to the commit API resource
+	Added     []string    `json:"added"`     // Files added in the commit
+	Removed   []string    `json:"removed"`   // Files removed in the commit
+	Modified  []string    `json:"modified"`  // Files modified in the commit
+	Distinct  bool        `json:"distinct"`  // Whether the commit is distinct from any that have been pushed before
+}
+
+// Label is a label applied to an issue or pull request.
+type Label struct {
+	Name        string `json:"name"`        // The name of the label
+	Color       string `json:"color"`       // Hex colour code of the label, without the leading #
+	Description string `json:"description"` // The label description
+	ID          int64  `json:"id"`          // The unique ID of the label
+}
+
+// Branch is one side (head or base) of a pull request.
+type Branch struct {
+	Label string     `json:"label"` // The label of the branch e.g. octocat:main
+	Ref   string     `json:"ref"`   // The name of the branch e.g. main
+	SHA   string     `json:"sha"`   // The SHA of the tip of the branch
+	User  User       `json:"user"`  // The owner of the branch
+	Repo  Repository `json:"repo"`  // The repository the branch belongs to
+}
+
+// PullRequest is a GitHub pull request.
+type PullRequest struct {
+	CreatedAt      time.Time  `json:"created_at"`       // When the pull request was opened
+	UpdatedAt      time.Time  `json:"updated_at"`       // When the pull request was last updated
+	MergedAt       *time.Time `json:"merged_at"`        // When the pull request was merged, nil if not merged
+	Mergeable      *bool      `json:"mergeable"`        // Whether the pull request can be merged, nil if not yet computed
+	ClosedAt       *time.Time `json:"closed_at"`        // When the pull request was closed, nil if open
+	Title          string     `json:"title"`            // The title of the pull request
+	MergeCommitSHA string     `json:"merge_commit_sha"` // The SHA of the merge commit, if any
+	URL            string     `json:"url"`              // URL pointing to the pull request API resource
+	HTMLURL        string     `json:"html_url"`         // URL of the pull request on GitHub
+	DiffURL        string     `json:"diff_url"`         // URL of the pull request diff
+	NodeID         string     `json:"node_id"`          // The GraphQL node ID of the pull request
+	State          string     `json:"state"`            // The state of the pull request, open or closed
+	Body           string     `json:"body"`             // The body of the pull request
+	Labels         []Label    `json:"labels"`           // Labels applied to the pull request
+	User           User       `json:"user"`             // The author of the pull request
+	Head           Branch     `json:"head"`             // The branch containing the changes
+	Base           Branch     `json:"base"`             // The branch the changes are to be merged into
+	ID             int64      `json:"id"`               // The unique ID of the pull request
+	Number         int        `json:"number"`           // The pull request number
+	Commits        int        `json:"commits"`          // Number of commits in the pull request
+	Additions      int        `json:"additions"`        // Number of lines added
+	Deletions      int        `json:"deletions"`        // Number of lines deleted
+	ChangedFiles   int        `json:"changed_files"`    // Number of files changed
+	Locked         bool       `json:"locked"`           // Whether the conversation is locked
+	Draft          bool       `json:"draft"`            // Whether the pull request is a draft
+	Merged         bool       `json:"merged"`           // Whether the pull request has been merged
+}
+
+// IssuePullRequest is present on an [Issue] when the issue is actually a pull request.
+type IssuePullRequest struct {
+	URL     string `json:"url"`      // URL pointing to the pull request API resource
+	HTMLURL string `json:"html_url"` // URL of the pull request on GitHub
+}
+
+// Issue is a GitHub issue.
+//
+// Pull requests are also issues, in which case the PullRequest field will be non-nil.
+type Issue struct {
+	CreatedAt   time.Time         `json:"created_at"`   // When the issue was opened
+	UpdatedAt   time.Time         `json:"updated_at"`   // When the issue was last updated
+	ClosedAt    *time.Time        `json:"closed_at"`    // When the issue was closed, nil if open
+	PullRequest *IssuePullRequest `json:"pull_request"` // Set if the issue is a pull request
+	HTMLURL     string            `json:"html_url"`     // URL of the issue on GitHub
+	NodeID      string            `json:"node_id"`      // The GraphQL node ID of the issue
+	Title       string            `json:"title"`        // The title of the issue
+	Body        string            `json:"body"`         // The body of the issue
+	State       string            `json:"state"`        // The state of the issue, open or closed
+	Labels      []Label           `json:"labels"`       // Labels applied to the issue
+	User        User              `json:"user"`         // The author of the issue
+	ID          int64             `json:"id"`           // The unique ID of the issue
+	Number      int               `json:"number"`       // The issue number
+	Locked      bool              `json:"locked"`       // Whether the conversation is locked
+}
+
+// Comment is a comment on an issue or pull request.
+type Comment struct {
+	CreatedAt         time.Time `json:"created_at"`         // When the comment was made
+	UpdatedAt         time.Time `json:"updated_at"`         // When the comment was last edited
+	NodeID            string    `json:"node_id"`            // The GraphQL node ID of the comment
+	HTMLURL           string    `json:"html_url"`           // URL of the comment on GitHub
+	Body              string    `json:"body"`               // The contents of the comment
+	AuthorAssociation string    `json:"author_association"` // The author's association with the repository e.g. OWNER, MEMBER
+	User              User      `json:"user"`               // The author of the comment
+	ID                int64     `json:"id"`                 // The unique ID of the comment
+}
+
+// Asset is a file attached to a [Release].
+type Asset struct {
+	Name               string `json:"name"`                 // The file name of the asset
+	Label              string `json:"label"`                // The asset label
+	ContentType        string `json:"content_type"`         // The MIME type of the asset
+	State              string `json:"state"`                // The state of the asset, uploaded or open
+	BrowserDownloadURL string `json:"browser_download_url"` // URL from which the asset can be downloaded
+	ID                 int64  `json:"id"`                   // The unique ID of the asset
+	Size               int64  `json:"size"`                 // Size of the asset in bytes
+	DownloadCount      int    `json:"download_count"`       // Number of times the asset has been downloaded
+}
+
+// Release is a GitHub release.
+type Release struct {
+	CreatedAt       time.Time  `json:"created_at"`       // When the release was created
+	PublishedAt     *time.Time `json:"published_at"`     // When the release was published, nil if a draft
+	Body            string     `json:"body"`             // The release notes
+	TagName         string     `json:"tag_name"`         // The name of the tag the release points to
+	TargetCommitish string     `json:"target_commitish"` // The branch or commit the tag is created from
+	Name            string     `json:"name"`             // The name of the release
+	HTMLURL         string     `json:"html_url"`         // URL of the release on GitHub
+	UploadURL       string     `json:"upload_url"`       // URL template for uploading assets
+	Assets          []Asset    `json:"assets"`           // Files attached to the release
+	Author          User       `json:"author"`           // The user that created the release
+	ID              int64      `json:"id"`               // The unique ID of the release
+	Draft           bool       `json:"draft"`            // Whether the release is a draft
+	Prerelease      bool       `json:"prerelease"`       // Whether the release is marked as a pre-release
+}
+
+// MergeGroup is a group of pull requests being tested together in a merge queue.
+type MergeGroup struct {
+	HeadSHA    string `json:"head_sha"`    // The SHA of the merge group
+	HeadRef    string `json:"head_ref"`    // The full ref of the merge group
+	BaseSHA    string `json:"base_sha"`    // The SHA of the merge group's parent commit
+	BaseRef    string `json:"base_ref"`    // The full ref of the branch the merge group will be merged into
+	HeadCommit Commit `json:"head_commit"` // The commit at the head of the merge group
+}
@@ -0,0 +1,217 @@
+package event_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/event"
+	"go.followtheprocess.codes/test"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("push", func(t *testing.T) {
+		payload := `{
+			"ref": "refs/heads/main",
+			"before": "abc",
+			"after": "def",
+			"forced": true,
+			"head_commit": {"id": "def", "message": "fix things", "author": {"name": "Tom", "email": "tom@example.com"}},
+			"commits": [{"id": "def", "modified": ["main.go"]}],
+			"repository": {"full_name": "octocat/hello-world", "owner": {"login": "octocat"}}
+		}`
+
+		got, err := event.Parse("push", strings.NewReader(payload))
+		test.Ok(t, err)
+
+		push, ok := got.(*event.PushEvent)
+		test.True(t, ok, test.Context("expected *PushEvent, got %T", got))
+
+		test.Equal(t, push.Ref, "refs/heads/main")
+		test.Equal(t, push.Before, "abc")
+		test.Equal(t, push.After, "def")
+		test.True(t, push.Forced)
+		test.True(t, push.HeadCommit != nil)
+		test.Equal(t, push.HeadCommit.Message, "fix things")
+		test.Equal(t, push.HeadCommit.Author.Email, "tom@example.com")
+		test.Equal(t, len(push.Commits), 1)
+		test.Equal(t, push.Commits[0].Modified[0], "main.go")
+		test.Equal(t, push.Repository.FullName, "octocat/hello-world")
+		test.Equal(t, push.Repository.Owner.Login, "octocat")
+	})
+
+	t.Run("pull_request", func(t *testing.T) {
+		payload := `{
+			"action": "opened",
+			"number": 42,
+			"pull_request": {
+				"title": "Add things",
+				"draft": true,
+				"head": {"ref": "feature", "sha": "123"},
+				"base": {"ref": "main", "sha": "456"},
+				"labels": [{"name": "enhancement"}],
+				"merged_at": null
+			}
+		}`
+
+		got, err := event.Parse("pull_request", strings.NewReader(payload))
+		test.Ok(t, err)
+
+		pr, ok := got.(*event.PullRequestEvent)
+		test.True(t, ok, test.Context("expected *PullRequestEvent, got %T", got))
+
+		test.Equal(t, pr.Action, "opened")
+		test.Equal(t, pr.Number, 42)
+		test.Equal(t, pr.PullRequest.Title, "Add things")
+		test.True(t, pr.PullRequest.Draft)
+		test.Equal(t, pr.PullRequest.Head.Ref, "feature")
+		test.Equal(t, pr.PullRequest.Base.SHA, "456")
+		test.Equal(t, pr.PullRequest.Labels[0].Name, "enhancement")
+		test.True(t, pr.PullRequest.MergedAt == nil)
+	})
+
+	t.Run("pull_request_target", func(t *testing.T) {
+		got, err := event.Parse("pull_request_target", strings.NewReader(`{"action": "labeled", "number": 7}`))
+		test.Ok(t, err)
+
+		pr, ok := got.(*event.PullRequestTargetEvent)
+		test.True(t, ok, test.Context("expected *PullRequestTargetEvent, got %T", got))
+		test.Equal(t, pr.Action, "labeled")
+		test.Equal(t, pr.Number, 7)
+	})
+
+	t.Run("issue_comment", func(t *testing.T) {
+		payload := `{
+			"action": "created",
+			"issue": {"number": 3, "pull_request": {"url": "https://api.github.com/repos/o/r/pulls/3"}},
+			"comment": {"body": "/deploy", "user": {"login": "octocat"}}
+		}`
+
+		got, err := event.Parse("issue_comment", strings.NewReader(payload))
+		test.Ok(t, err)
+
+		comment, ok := got.(*event.IssueCommentEvent)
+		test.True(t, ok, test.Context("expected *IssueCommentEvent, got %T", got))
+		test.Equal(t, comment.Issue.Number, 3)
+		test.True(t, comment.Issue.PullRequest != nil)
+		test.Equal(t, comment.Comment.Body, "/deploy")
+		test.Equal(t, comment.Comment.User.Login, "octocat")
+	})
+
+	t.Run("workflow_dispatch", func(t *testing.T) {
+		payload := `{"ref": "refs/heads/main", "workflow": ".github/workflows/ci.yml", "inputs": {"name": "x", "dry-run": true}}`
+
+		got, err := event.Parse("workflow_dispatch", strings.NewReader(payload))
+		test.Ok(t, err)
+
+		dispatch, ok := got.(*event.WorkflowDispatchEvent)
+		test.True(t, ok, test.Context("expected *WorkflowDispatchEvent, got %T", got))
+		test.Equal(t, dispatch.Workflow, ".github/workflows/ci.yml")
+		test.Equal(t, dispatch.Inputs["name"], any("x"))
+		test.Equal(t, dispatch.Inputs["dry-run"], any(true))
+	})
+
+	t.Run("release", func(t *testing.T) {
+		payload := `{"action": "published", "release": {"tag_name": "v1.2.3", "prerelease": true, "assets": [{"name": "tool.tar.gz", "size": 1024}]}}`
+
+		got, err := event.Parse("release", strings.NewReader(payload))
+		test.Ok(t, err)
+
+		release, ok := got.(*event.ReleaseEvent)
+		test.True(t, ok, test.Context("expected *ReleaseEvent, got %T", got))
+		test.Equal(t, release.Release.TagName, "v1.2.3")
+		test.True(t, release.Release.Prerelease)
+		test.Equal(t, release.Release.Assets[0].Size, int64(1024))
+	})
+
+	t.Run("schedule", func(t *testing.T) {
+		got, err := event.Parse("schedule", strings.NewReader(`{"schedule": "0 0 * * *"}`))
+		test.Ok(t, err)
+
+		schedule, ok := got.(*event.ScheduleEvent)
+		test.True(t, ok, test.Context("expected *ScheduleEvent, got %T", got))
+		test.Equal(t, schedule.Schedule, "0 0 * * *")
+	})
+
+	t.Run("merge_group", func(t *testing.T) {
+		payload := `{"action": "checks_requested", "merge_group": {"head_sha": "abc", "base_ref": "refs/heads/main"}}`
+
+		got, err := event.Parse("merge_group", strings.NewReader(payload))
+		test.Ok(t, err)
+
+		group, ok := got.(*event.MergeGroupEvent)
+		test.True(t, ok, test.Context("expected *MergeGroupEvent, got %T", got))
+		test.Equal(t, group.MergeGroup.HeadSHA, "abc")
+		test.Equal(t, group.MergeGroup.BaseRef, "refs/heads/main")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		got, err := event.Parse("discussion", strings.NewReader(`{"action": "created", "discussion": {"number": 1}}`))
+		test.Ok(t, err)
+
+		generic, ok := got.(map[string]any)
+		test.True(t, ok, test.Context("expected map[string]any, got %T", got))
+		test.Equal(t, generic["action"], any("created"))
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		_, err := event.Parse("", strings.NewReader(`{}`))
+		test.Err(t, err)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := event.Parse("push", strings.NewReader(`{"ref": `))
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "could not decode push event payload"))
+	})
+
+	t.Run("invalid json unknown event", func(t *testing.T) {
+		_, err := event.Parse("discussion", strings.NewReader(`not json`))
+		test.Err(t, err)
+	})
+}
+
+func TestRead(t *testing.T) {
+	t.Run("name unset", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "")
+
+		_, err := event.Read()
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "$GITHUB_EVENT_NAME is not set or is empty")
+	})
+
+	t.Run("path unset", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "push")
+		t.Setenv("GITHUB_EVENT_PATH", "")
+
+		_, err := event.Read()
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "$GITHUB_EVENT_PATH is not set or is empty")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("GITHUB_EVENT_NAME", "push")
+		t.Setenv("GITHUB_EVENT_PATH", filepath.Join(t.TempDir(), "missing.json"))
+
+		_, err := event.Read()
+		test.Err(t, err)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "event.json")
+		err := os.WriteFile(path, []byte(`{"ref": "refs/tags/v1.0.0", "created": true}`), 0o644)
+		test.Ok(t, err)
+
+		t.Setenv("GITHUB_EVENT_NAME", "push")
+		t.Setenv("GITHUB_EVENT_PATH", path)
+
+		got, err := event.Read()
+		test.Ok(t, err)
+
+		push, ok := got.(*event.PushEvent)
+		test.True(t, ok, test.Context("expected *PushEvent, got %T", got))
+		test.Equal(t, push.Ref, "refs/tags/v1.0.0")
+		test.True(t, push.Created)
+	})
+}
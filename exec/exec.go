@@ -0,0 +1,245 @@
+// Package exec provides mechanisms for running external commands from within a GitHub Action.
+//
+// It wraps [os/exec] such that the output of the command is streamed live to the workflow
+// log, inside an expandable group titled with the command line being run.
+package exec // import "go.followtheprocess.codes/actions/exec"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.followtheprocess.codes/actions/log"
+)
+
+// ExitError is returned from [Run] when a command runs to completion but exits with
+// a non-zero exit code.
+type ExitError struct {
+	Err     error  // The underlying error from os/exec
+	Command string // The command line that was run
+	Code    int    // The exit code of the command
+}
+
+// Error implements the error interface for [ExitError].
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Command, e.Code)
+}
+
+// Unwrap returns the underlying error, allowing [ExitError] to be used with [errors.As]
+// to access the underlying [*os/exec.ExitError].
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// config holds the configuration for a single call to [Run].
+type config struct {
+	stdin          io.Reader // Where the command reads stdin from
+	stdout         io.Writer // Additional destination for stdout
+	stderr         io.Writer // Additional destination for stderr
+	dir            string    // Working directory of the command
+	env            []string  // Additional environment variables in "KEY=VALUE" form
+	ignoreExitCode bool      // Don't treat a non-zero exit as an error
+	noEcho         bool      // Don't echo the command line or wrap in a group
+}
+
+// Option is a configuration option for [Run].
+type Option interface {
+	// Apply the option to the config.
+	apply(cfg *config)
+}
+
+// option is a function that implements the Option interface, like log.annotator.
+type option func(cfg *config)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(cfg *config) {
+	o(cfg)
+}
+
+// Dir sets the working directory of the command.
+//
+// If not set, the command runs in the current working directory of the calling process.
+func Dir(dir string) Option {
+	f := func(cfg *config) {
+		cfg.dir = dir
+	}
+
+	return option(f)
+}
+
+// Env adds environment variables, each of the form "KEY=VALUE", to the environment of the command.
+//
+// The command always inherits the environment of the calling process, env is added on top.
+func Env(env ...string) Option {
+	f := func(cfg *config) {
+		cfg.env = append(cfg.env, env...)
+	}
+
+	return option(f)
+}
+
+// Stdin sets the standard input of the command.
+func Stdin(r io.Reader) Option {
+	f := func(cfg *config) {
+		cfg.stdin = r
+	}
+
+	return option(f)
+}
+
+// Stdout captures the standard output of the command into w.
+//
+// The output is still streamed to the workflow log, w receives a copy of it.
+func Stdout(w io.Writer) Option {
+	f := func(cfg *config) {
+		cfg.stdout = w
+	}
+
+	return option(f)
+}
+
+// Stderr captures the standard error of the command into w.
+//
+// The output is still streamed to the workflow log, w receives a copy of it.
+func Stderr(w io.Writer) Option {
+	f := func(cfg *config) {
+		cfg.stderr = w
+	}
+
+	return option(f)
+}
+
+// IgnoreExitCode prevents a non-zero exit code from being reported as an error.
+//
+// The exit code is still returned from [Run] for the caller to inspect.
+func IgnoreExitCode() Option {
+	f := func(cfg *config) {
+		cfg.ignoreExitCode = true
+	}
+
+	return option(f)
+}
+
+// NoEcho stops [Run] from echoing the command line to the workflow log and from
+// wrapping the output in a log group, the output of the command is streamed to the
+// log as is.
+//
+// This is useful if the command line contains sensitive information.
+func NoEcho() Option {
+	f := func(cfg *config) {
+		cfg.noEcho = true
+	}
+
+	return option(f)
+}
+
+// Run executes the program name with args, streaming its stdout and stderr to the
+// workflow log via logger.
+//
+// Unless [NoEcho] is passed, the output is wrapped in a log group titled with the
+// command line. The command is killed if ctx is cancelled before it completes.
+//
+// Run returns the exit code of the command. If the command exits with a non-zero
+// exit code, an [*ExitError] is also returned unless [IgnoreExitCode] was passed. If the
+// command could not be started at all, or was cancelled, the exit code is -1.
+//
+//	var stdout bytes.Buffer
+//	code, err := exec.Run(ctx, logger, "go", []string{"version"}, exec.Stdout(&stdout))
+func Run(ctx context.Context, logger log.Logger, name string, args []string, options ...Option) (int, error) {
+	cfg := config{}
+	for _, option := range options {
+		option.apply(&cfg)
+	}
+
+	cmdline := commandLine(name, args)
+
+	if !cfg.noEcho {
+		logger.StartGroup(cmdline)
+		defer logger.EndGroup()
+	}
+
+	// stdout and stderr are written to concurrently by os/exec, but the log (and
+	// possibly the capture writers) have no such protection so we must provide it
+	mu := &sync.Mutex{}
+
+	cmd := osexec.CommandContext(ctx, name, args...)
+	cmd.Dir = cfg.dir
+	cmd.Stdin = cfg.stdin
+	cmd.Stdout = &lockedWriter{mu: mu, w: teeWriter(logger, cfg.stdout)}
+	cmd.Stderr = &lockedWriter{mu: mu, w: teeWriter(logger, cfg.stderr)}
+
+	if len(cfg.env) != 0 {
+		cmd.Env = append(os.Environ(), cfg.env...)
+	}
+
+	err := cmd.Run()
+	if err == nil {
+		return 0, nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return -1, fmt.Errorf("%s: %w", cmdline, ctxErr)
+	}
+
+	var exitErr *osexec.ExitError
+	if !errors.As(err, &exitErr) {
+		// Never started, e.g. not found on $PATH
+		return -1, fmt.Errorf("could not run %s: %w", cmdline, err)
+	}
+
+	code := exitErr.ExitCode()
+	if cfg.ignoreExitCode {
+		return code, nil
+	}
+
+	return code, &ExitError{Command: cmdline, Code: code, Err: exitErr}
+}
+
+// commandLine renders the program name and args as a single string suitable for
+// display, quoting any arguments that are empty or contain whitespace.
+func commandLine(name string, args []string) string {
+	s := &strings.Builder{}
+	s.WriteString(name)
+
+	for _, arg := range args {
+		s.WriteByte(' ')
+
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			s.WriteString(strconv.Quote(arg))
+		} else {
+			s.WriteString(arg)
+		}
+	}
+
+	return s.String()
+}
+
+// teeWriter returns a writer that writes to out and, if it's not nil, to capture.
+func teeWriter(out, capture io.Writer) io.Writer {
+	if capture == nil {
+		return out
+	}
+
+	return io.MultiWriter(out, capture)
+}
+
+// lockedWriter is an [io.Writer] that serialises writes to an underlying writer
+// using a mutex that may be shared with other lockedWriters.
+type lockedWriter struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+// Write implements [io.Writer] for [lockedWriter].
+func (l *lockedWriter) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
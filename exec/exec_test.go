@@ -0,0 +1,186 @@
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/exec"
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+// helperEnv is set when the test binary is being run as a helper process by the tests.
+const helperEnv = "ACTIONS_EXEC_TEST_HELPER"
+
+// TestMain lets the test binary act as the command under test, avoiding any dependence
+// on programs that may or may not be installed where the tests are run.
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		os.Exit(helper(os.Args[1:]))
+	}
+
+	os.Exit(m.Run())
+}
+
+// helper implements the behaviour of the helper process.
+func helper(args []string) int {
+	if len(args) == 0 {
+		return 0
+	}
+
+	switch args[0] {
+	case "echo":
+		fmt.Fprintln(os.Stdout, strings.Join(args[1:], " "))
+	case "stderr":
+		fmt.Fprintln(os.Stderr, strings.Join(args[1:], " "))
+	case "env":
+		fmt.Fprintln(os.Stdout, os.Getenv(args[1]))
+	case "pwd":
+		dir, _ := os.Getwd()
+		fmt.Fprintln(os.Stdout, dir)
+	case "exit":
+		code, _ := strconv.Atoi(args[1])
+		return code
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+
+	return 0
+}
+
+// run runs the helper process with args.
+func run(ctx context.Context, logger log.Logger, args []string, options ...exec.Option) (int, error) {
+	options = append(options, exec.Env(helperEnv+"=1"))
+	return exec.Run(ctx, logger, os.Args[0], args, options...)
+}
+
+func TestRun(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		code, err := run(t.Context(), logger, []string{"echo", "hello", "there"})
+		test.Ok(t, err)
+		test.Equal(t, code, 0)
+
+		want := fmt.Sprintf("::group::%s echo hello there\nhello there\n::endgroup::\n", os.Args[0])
+		test.Diff(t, buf.String(), want)
+	})
+
+	t.Run("quoted args", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		_, err := run(t.Context(), logger, []string{"echo", "with space", ""})
+		test.Ok(t, err)
+
+		want := fmt.Sprintf("::group::%s echo \"with space\" \"\"\n", os.Args[0])
+		test.True(t, strings.HasPrefix(buf.String(), want), test.Context("got %q", buf.String()))
+	})
+
+	t.Run("no echo", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		_, err := run(t.Context(), logger, []string{"echo", "secret"}, exec.NoEcho())
+		test.Ok(t, err)
+
+		test.Equal(t, buf.String(), "secret\n")
+	})
+
+	t.Run("capture", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+
+		_, err := run(t.Context(), logger, []string{"echo", "out"}, exec.Stdout(stdout), exec.Stderr(stderr))
+		test.Ok(t, err)
+		test.Equal(t, stdout.String(), "out\n")
+		test.Equal(t, stderr.String(), "")
+
+		_, err = run(t.Context(), logger, []string{"stderr", "err"}, exec.Stdout(stdout), exec.Stderr(stderr))
+		test.Ok(t, err)
+		test.Equal(t, stderr.String(), "err\n")
+
+		// Both should have still gone to the log
+		test.True(t, strings.Contains(buf.String(), "out\n"))
+		test.True(t, strings.Contains(buf.String(), "err\n"))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+
+		_, err := run(
+			t.Context(),
+			log.New(&bytes.Buffer{}),
+			[]string{"env", "SOMETHING"},
+			exec.Env("SOMETHING=here"),
+			exec.Stdout(stdout),
+		)
+		test.Ok(t, err)
+		test.Equal(t, stdout.String(), "here\n")
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		dir := t.TempDir()
+		stdout := &bytes.Buffer{}
+
+		_, err := run(t.Context(), log.New(&bytes.Buffer{}), []string{"pwd"}, exec.Dir(dir), exec.Stdout(stdout))
+		test.Ok(t, err)
+
+		got, err := os.Stat(strings.TrimSpace(stdout.String()))
+		test.Ok(t, err)
+
+		want, err := os.Stat(dir)
+		test.Ok(t, err)
+
+		test.True(t, os.SameFile(got, want))
+	})
+
+	t.Run("non zero exit", func(t *testing.T) {
+		code, err := run(t.Context(), log.New(&bytes.Buffer{}), []string{"exit", "3"})
+		test.Err(t, err)
+		test.Equal(t, code, 3)
+
+		var exitErr *exec.ExitError
+
+		test.True(t, errors.As(err, &exitErr))
+		test.Equal(t, exitErr.Code, 3)
+		test.Equal(t, err.Error(), os.Args[0]+" exit 3 exited with code 3")
+	})
+
+	t.Run("ignore exit code", func(t *testing.T) {
+		code, err := run(t.Context(), log.New(&bytes.Buffer{}), []string{"exit", "3"}, exec.IgnoreExitCode())
+		test.Ok(t, err)
+		test.Equal(t, code, 3)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		code, err := exec.Run(t.Context(), log.New(&bytes.Buffer{}), "definitely-not-a-real-program", nil)
+		test.Err(t, err)
+		test.Equal(t, code, -1)
+
+		var exitErr *exec.ExitError
+
+		test.True(t, !errors.As(err, &exitErr))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+		defer cancel()
+
+		code, err := run(ctx, log.New(&bytes.Buffer{}), []string{"sleep"})
+		test.Err(t, err)
+		test.Equal(t, code, -1)
+		test.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
@@ -0,0 +1,240 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"go.followtheprocess.codes/actions/log"
+)
+
+// workspaceVar is the env var containing the path to the workspace, which annotation file paths
+// are made relative to.
+const workspaceVar = "GITHUB_WORKSPACE"
+
+// SourceError is an error associated with a location in a source file, when it (or an error
+// wrapping it) is returned from the function passed to [Main], the error annotation points at
+// that location.
+type SourceError struct {
+	Err    error  // The underlying error
+	File   string // Path to the file, relative to the root of the repository or absolute within $GITHUB_WORKSPACE
+	Line   uint   // Line number in File (starting at 1), 0 if unknown
+	Column uint   // Column on Line (starting at 1), 0 if unknown
+}
+
+// Error implements the error interface for [SourceError], prefixing the underlying error with
+// the location in the familiar file:line:column form.
+func (s *SourceError) Error() string {
+	location := s.File
+
+	if s.Line != 0 {
+		location += fmt.Sprintf(":%d", s.Line)
+
+		if s.Column != 0 {
+			location += fmt.Sprintf(":%d", s.Column)
+		}
+	}
+
+	return location + ": " + s.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (s *SourceError) Unwrap() error {
+	return s.Err
+}
+
+// Annotations returns the annotations pointing an error log at the location of the error.
+func (s *SourceError) Annotations() []log.Annotation {
+	file := s.File
+	if rel, ok := workspaceRel(file); ok {
+		file = rel
+	}
+
+	annotations := []log.Annotation{log.File(file)}
+
+	if s.Line != 0 {
+		annotations = append(annotations, log.Lines(s.Line, s.Line))
+
+		if s.Column != 0 {
+			annotations = append(annotations, log.Span(s.Column, s.Column))
+		}
+	}
+
+	return annotations
+}
+
+// Main runs fn as the whole of an action, taking care of reporting failure to the runner the
+// same way as core.setFailed in the actions toolkit, and exits the process. It should be the
+// last thing called in the action's main function:
+//
+//	func main() {
+//		actions.Main(func(ctx context.Context) error {
+//			return actions.Run(ctx, action)
+//		})
+//	}
+//
+// The context passed to fn is cancelled when the runner cancels the job. If fn returns an
+// error, it is logged as an error annotation and a failure section is added to the step
+// summary. Any error in the chain with an Annotations() []log.Annotation method, such as a
+// [*SourceError], sets the annotations so it points at a location in the source.
+//
+// If fn panics the panic is recovered and reported the same way, with the annotation pointing
+// at the line that panicked when it's within $GITHUB_WORKSPACE, followed by the stack trace.
+//
+// The process exits with code 0 on success and 1 on failure, unless an error in the chain
+// has an ExitCode() int method returning a positive code, such as an [*os/exec.ExitError],
+// in which case that code is used. Everything fn writes, including in deferred calls, is
+// flushed before exiting.
+func Main(fn func(ctx context.Context) error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := runMain(ctx, log.New(os.Stdout), fn)
+
+	stop()
+	os.Exit(code) //nolint:revive // deep-exit: Main is called in place of os.Exit at the end of main
+}
+
+// runMain implements [Main] without exiting, returning the exit code.
+func runMain(ctx context.Context, logger log.Logger, fn func(ctx context.Context) error) int {
+	err := safely(ctx, fn)
+
+	// Anything fn wrote must be flushed before the process exits
+	if closeErr := defaultFileCommands.Close(); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+
+	if err == nil {
+		return 0
+	}
+
+	var annotated interface{ Annotations() []log.Annotation }
+
+	var annotations []log.Annotation
+	if errors.As(err, &annotated) {
+		annotations = annotated.Annotations()
+	}
+
+	logger.Error(err.Error(), annotations...)
+
+	summary := NewSummary().
+		Heading("Action failed", 2).
+		CodeBlock(err.Error(), "")
+
+	var p *panicError
+	if errors.As(err, &p) {
+		fmt.Fprintf(logger, "%s\n", p.stack)
+		summary.Details("Stack trace", string(p.stack))
+	}
+
+	if summaryErr := summary.Write(); summaryErr != nil {
+		logger.Debug("could not write failure to step summary: %v", summaryErr)
+	}
+
+	return exitCode(err)
+}
+
+// safely calls fn, returning a [*panicError] if it panics.
+func safely(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &panicError{
+				value: value,
+				stack: debug.Stack(),
+				frame: panicFrame(),
+			}
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) && coder.ExitCode() > 0 {
+		return coder.ExitCode()
+	}
+
+	return 1
+}
+
+// panicError is the error returned by safely when fn panics.
+type panicError struct {
+	value any           // The value passed to panic
+	frame runtime.Frame // The frame that panicked, zero if it's not within $GITHUB_WORKSPACE
+	stack []byte        // The stack trace of the panicking goroutine
+}
+
+// Error implements the error interface for panicError.
+func (p *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// Unwrap returns the value passed to panic if it's an error, so e.g. panic(err) can be
+// inspected with [errors.Is] like any other error.
+func (p *panicError) Unwrap() error {
+	if err, ok := p.value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+// Annotations returns the annotations pointing at the frame that panicked, or none if it's
+// not within $GITHUB_WORKSPACE, as the annotation couldn't be shown on anything.
+func (p *panicError) Annotations() []log.Annotation {
+	file, ok := workspaceRel(p.frame.File)
+	if !ok || p.frame.Line < 1 {
+		return nil
+	}
+
+	line := uint(p.frame.Line)
+
+	return []log.Annotation{log.Title("panic"), log.File(file), log.Lines(line, line)}
+}
+
+// panicFrame returns the first frame below the panic that's within $GITHUB_WORKSPACE, so a panic
+// in e.g. the standard library points at the action code that called it, or the zero frame if
+// there isn't one. It must be called from the function deferred in safely.
+func panicFrame() runtime.Frame {
+	pcs := make([]uintptr, 64) //nolint:mnd // Plenty for all but absurdly deep stacks
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(0, pcs)])
+
+	panicking := false
+
+	for {
+		frame, more := frames.Next()
+
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		} else if _, ok := workspaceRel(frame.File); ok && panicking {
+			return frame
+		}
+
+		if !more {
+			return runtime.Frame{}
+		}
+	}
+}
+
+// workspaceRel returns path relative to $GITHUB_WORKSPACE, using forward slashes, reporting
+// whether path is absolute and within it.
+func workspaceRel(path string) (string, bool) {
+	workspace := os.Getenv(workspaceVar)
+	if workspace == "" || !filepath.IsAbs(path) {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(workspace, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
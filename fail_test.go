@@ -0,0 +1,224 @@
+package actions //nolint: testpackage // See actions_test.go
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+// exitError is an error with an exit code, like *exec.ExitError.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e exitError) ExitCode() int {
+	return e.code
+}
+
+func TestRunMain(t *testing.T) {
+	old := summaryFile
+	summaryFile = testSummaryName
+
+	t.Cleanup(func() { summaryFile = old })
+
+	tests := []struct {
+		fn      func(ctx context.Context) error // The action
+		name    string                          // Name of the test case
+		want    string                          // Expected log output
+		summary string                          // Expected to be in the step summary, empty if nothing is written
+		code    int                             // Expected exit code
+	}{
+		{
+			name: "success",
+			fn:   func(context.Context) error { return nil },
+			want: "",
+			code: 0,
+		},
+		{
+			name:    "error",
+			fn:      func(context.Context) error { return errors.New("bang") },
+			want:    "::error::bang\n",
+			summary: "<h2>Action failed</h2>\n<pre><code>bang</code></pre>\n",
+			code:    1,
+		},
+		{
+			name: "multiline error",
+			fn: func(context.Context) error {
+				return errors.Join(errors.New("one"), errors.New("two"))
+			},
+			want:    "::error::one%0Atwo\n",
+			summary: "<pre><code>one\ntwo</code></pre>",
+			code:    1,
+		},
+		{
+			name: "source error",
+			fn: func(context.Context) error {
+				err := &SourceError{Err: errors.New("unexpected key"), File: "config.yml", Line: 3, Column: 5}
+				return fmt.Errorf("could not load config: %w", err)
+			},
+			want:    "::error file=config.yml,line=3,endLine=3,col=5,endColumn=5::could not load config: config.yml:3:5: unexpected key\n",
+			summary: "could not load config: config.yml:3:5: unexpected key",
+			code:    1,
+		},
+		{
+			name: "exit code",
+			fn: func(context.Context) error {
+				return fmt.Errorf("golangci-lint failed: %w", exitError{code: 3})
+			},
+			want:    "::error::golangci-lint failed: exit status 3\n",
+			summary: "golangci-lint failed: exit status 3",
+			code:    3,
+		},
+		{
+			name: "negative exit code",
+			fn: func(context.Context) error {
+				return exitError{code: -1}
+			},
+			want:    "::error::exit status -1\n",
+			summary: "exit status -1",
+			code:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "summary.md")
+			t.Setenv(summaryFile, path)
+
+			buf := &bytes.Buffer{}
+			code := runMain(t.Context(), log.New(buf), tt.fn)
+
+			test.Equal(t, code, tt.code)
+			test.Equal(t, buf.String(), tt.want)
+
+			summary, err := os.ReadFile(path)
+			if tt.summary == "" {
+				test.True(t, errors.Is(err, os.ErrNotExist))
+				return
+			}
+
+			test.Ok(t, err)
+			test.True(t, strings.Contains(string(summary), tt.summary))
+		})
+	}
+}
+
+func TestRunMainPanic(t *testing.T) {
+	old := summaryFile
+	summaryFile = testSummaryName
+
+	t.Cleanup(func() { summaryFile = old })
+
+	_, file, _, ok := runtime.Caller(0)
+	test.True(t, ok)
+
+	t.Run("in workspace", func(t *testing.T) {
+		summary := filepath.Join(t.TempDir(), "summary.md")
+		t.Setenv(summaryFile, summary)
+		t.Setenv(workspaceVar, filepath.Dir(file))
+
+		var line int
+
+		buf := &bytes.Buffer{}
+		code := runMain(t.Context(), log.New(buf), func(context.Context) error {
+			var m map[string]int
+
+			_, _, line, _ = runtime.Caller(0)
+			m["boom"]++ // Line after the call to runtime.Caller
+
+			return nil
+		})
+
+		test.Equal(t, code, 1)
+
+		annotation, stack, _ := strings.Cut(buf.String(), "\n")
+		want := fmt.Sprintf(
+			"::error title=panic,file=fail_test.go,line=%d,endLine=%d::panic: assignment to entry in nil map",
+			line+1,
+			line+1,
+		)
+		test.Equal(t, annotation, want)
+		test.True(t, strings.Contains(stack, "goroutine"))
+
+		contents, err := os.ReadFile(summary)
+		test.Ok(t, err)
+		test.True(t, strings.Contains(string(contents), "<summary>Stack trace</summary>"))
+	})
+
+	t.Run("outside workspace", func(t *testing.T) {
+		t.Setenv(summaryFile, filepath.Join(t.TempDir(), "summary.md"))
+		t.Setenv(workspaceVar, t.TempDir())
+
+		buf := &bytes.Buffer{}
+		code := runMain(t.Context(), log.New(buf), func(context.Context) error {
+			panic("oh no")
+		})
+
+		test.Equal(t, code, 1)
+		test.True(t, strings.HasPrefix(buf.String(), "::error::panic: oh no\n"))
+	})
+
+	t.Run("error value", func(t *testing.T) {
+		t.Setenv(summaryFile, filepath.Join(t.TempDir(), "summary.md"))
+
+		sentinel := errors.New("sentinel")
+
+		err := safely(t.Context(), func(context.Context) error {
+			panic(sentinel)
+		})
+
+		test.Err(t, err)
+		test.True(t, errors.Is(err, sentinel))
+		test.Equal(t, err.Error(), "panic: sentinel")
+	})
+
+	t.Run("no summary file", func(t *testing.T) {
+		t.Setenv(summaryFile, "")
+
+		buf := &bytes.Buffer{}
+		code := runMain(t.Context(), log.New(buf), func(context.Context) error {
+			panic("oh no")
+		})
+
+		test.Equal(t, code, 1)
+		test.True(t, strings.Contains(buf.String(), "::debug::could not write failure to step summary"))
+	})
+}
+
+func TestWorkspaceRel(t *testing.T) {
+	workspace := filepath.Join(t.TempDir(), "repo")
+	t.Setenv(workspaceVar, workspace)
+
+	tests := []struct {
+		name string // Name of the test case
+		path string // Path to relativise
+		want string // Expected relative path
+		ok   bool   // Expected ok
+	}{
+		{name: "inside", path: filepath.Join(workspace, "cmd", "main.go"), want: "cmd/main.go", ok: true},
+		{name: "outside", path: filepath.Join(filepath.Dir(workspace), "other", "main.go"), ok: false},
+		{name: "sibling prefix", path: workspace + "-other", ok: false},
+		{name: "relative", path: "main.go", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := workspaceRel(tt.path)
+			test.Equal(t, ok, tt.ok)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package actions
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.followtheprocess.codes/actions/internal/filecmd"
+)
+
+// defaultFileCommands is the [FileCommands] used by [SetEnv], [SetOutput] and [SetState].
+//
+//nolint:gochecknoglobals // There is one set of files per process, so one writer for them.
+var defaultFileCommands = NewFileCommands()
+
+// FileCommands writes file commands to $GITHUB_ENV, $GITHUB_OUTPUT and $GITHUB_STATE, keeping
+// the files open between writes. It is safe for concurrent use, every value is written to its
+// file whole so concurrent writes never interleave.
+//
+// Only a [Batch] is synced to disk. Single values are written straight to the file so are
+// seen by the runner once the step ends, but aren't synced, which would make each one much
+// slower to set. Call [FileCommands.Close] once done, as [Main] does.
+//
+// [SetEnv], [SetOutput] and [SetState] use the writer returned by [DefaultFileCommands], which
+// is also the one to use for writing a [Batch].
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#environment-files
+type FileCommands struct {
+	files map[string]*commandFile // Open files keyed by the env var holding their path
+	mu    sync.Mutex              // Serialises writes, protecting files
+}
+
+// commandFile is a file command file held open by [FileCommands].
+type commandFile struct {
+	file *os.File // The open file
+	path string   // Path the file was opened at
+}
+
+// NewFileCommands returns a new [FileCommands], with no files open until they're written to.
+func NewFileCommands() *FileCommands {
+	return &FileCommands{files: make(map[string]*commandFile)}
+}
+
+// DefaultFileCommands returns the [FileCommands] used by [SetEnv], [SetOutput] and [SetState].
+func DefaultFileCommands() *FileCommands {
+	return defaultFileCommands
+}
+
+// SetEnv sets an environment variable by writing it to $GITHUB_ENV, see [SetEnv].
+func (f *FileCommands) SetEnv(key, value string) error {
+	return f.set(envFile, key, value)
+}
+
+// SetOutput sets an output variable by writing it to $GITHUB_OUTPUT, see [SetOutput].
+func (f *FileCommands) SetOutput(key, value string) error {
+	return f.set(outFile, key, value)
+}
+
+// SetState sets a state variable by writing it to $GITHUB_STATE, see [SetState].
+func (f *FileCommands) SetState(key, value string) error {
+	return f.set(stateFile, key, value)
+}
+
+// Batch calls fn to add values to a [Batch], then writes them together, so that a concurrent
+// writer can't interleave with them:
+//
+//	err := actions.DefaultFileCommands().Batch(func(batch *actions.Batch) error {
+//		for name, value := range results {
+//			if err := batch.SetOutput(name, value); err != nil {
+//				return err
+//			}
+//		}
+//
+//		return nil
+//	})
+//
+// If fn returns an error, or any of the files can't be opened, nothing is written. Otherwise
+// the values for each file are written in a single write, in the order they were added, and
+// the file is synced to disk. Writes are only atomic per file: if writing one file fails,
+// the files before it have already been written, and the env vars written to $GITHUB_ENV
+// have already been set in the process.
+func (f *FileCommands) Batch(fn func(batch *Batch) error) error {
+	batch := &Batch{
+		buffers: make(map[string]*bytes.Buffer),
+		env:     make(map[string]string),
+	}
+
+	if err := fn(batch); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Always written in the same order, so a failure part way through is predictable
+	names := make([]string, 0, len(batch.buffers))
+	files := make([]*commandFile, 0, len(batch.buffers))
+
+	for _, name := range []string{envFile, outFile, stateFile} {
+		if _, ok := batch.buffers[name]; !ok {
+			continue
+		}
+
+		// Open everything first so a missing file means nothing is written
+		file, err := f.open(name)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, name)
+		files = append(files, file)
+	}
+
+	for i, name := range names {
+		if err := write(name, files[i], batch.buffers[name].Bytes(), true); err != nil {
+			return err
+		}
+
+		if name != envFile {
+			continue
+		}
+
+		for key, value := range batch.env {
+			if err := os.Setenv(key, value); err != nil {
+				return fmt.Errorf("failed to set $%s: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close closes any open files, they are reopened if written to again.
+func (f *FileCommands) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+
+	for name, open := range f.files {
+		if err := open.file.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("could not close $%s file: %w", name, err))
+		}
+
+		delete(f.files, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// set validates and writes a single value to the file in the env var name.
+func (f *FileCommands) set(name, key, value string) error {
+	key, value, err := validate(name, key, value)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+
+	file, err := f.open(name)
+	if err == nil {
+		err = write(name, file, []byte(filecmd.Format(key, value)), false)
+	}
+
+	f.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	// If it's an env var, let's export the actual env var too
+	if name == envFile {
+		if err = os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set $%s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// open returns the file in the env var name, opening it if it isn't already. The caller must
+// hold f.mu.
+func (f *FileCommands) open(name string) (*commandFile, error) {
+	path := os.Getenv(name)
+	if path == "" {
+		return nil, fmt.Errorf("$%s is not set or is empty", name)
+	}
+
+	open, ok := f.files[name]
+	if ok && open.path == path {
+		return open, nil
+	}
+
+	// The path only changes in tests, but don't keep writing to the wrong file if it does
+	if ok {
+		delete(f.files, name)
+
+		if err := open.file.Close(); err != nil {
+			return nil, fmt.Errorf("could not close $%s file: %w", name, err)
+		}
+	}
+
+	//nolint:gosec // G703: path is set by the trusted Actions runner, not user input
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, filePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("could not open $%s file: %w", name, err)
+	}
+
+	open = &commandFile{file: file, path: path}
+	f.files[name] = open
+
+	return open, nil
+}
+
+// write appends data to open, the file in the env var name, syncing it to disk if sync is true.
+func write(name string, open *commandFile, data []byte, sync bool) error {
+	if _, err := open.file.Write(data); err != nil {
+		return fmt.Errorf("could not write to $%s file: %w", name, err)
+	}
+
+	if sync {
+		if err := open.file.Sync(); err != nil {
+			return fmt.Errorf("could not sync $%s file: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Batch is a set of values to be written together by [FileCommands.Batch].
+type Batch struct {
+	buffers map[string]*bytes.Buffer // Pending writes keyed by the env var holding the file path
+	env     map[string]string        // Env vars to export once $GITHUB_ENV is written
+}
+
+// SetEnv adds an environment variable to the batch, see [SetEnv].
+func (b *Batch) SetEnv(key, value string) error {
+	return b.set(envFile, key, value)
+}
+
+// SetOutput adds an output variable to the batch, see [SetOutput].
+func (b *Batch) SetOutput(key, value string) error {
+	return b.set(outFile, key, value)
+}
+
+// SetState adds a state variable to the batch, see [SetState].
+func (b *Batch) SetState(key, value string) error {
+	return b.set(stateFile, key, value)
+}
+
+// set validates and adds a single value for the file in the env var name.
+func (b *Batch) set(name, key, value string) error {
+	key, value, err := validate(name, key, value)
+	if err != nil {
+		return err
+	}
+
+	buf, ok := b.buffers[name]
+	if !ok {
+		buf = &bytes.Buffer{}
+		b.buffers[name] = buf
+	}
+
+	buf.WriteString(filecmd.Format(key, value))
+
+	if name == envFile {
+		b.env[key] = value
+	}
+
+	return nil
+}
+
+// validate trims key and value and checks they may be written to the file in the env var name.
+func validate(name, key, value string) (string, string, error) {
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	if key == "" {
+		return "", "", errors.New("key cannot be empty")
+	}
+
+	if value == "" {
+		return "", "", errors.New("value cannot be empty")
+	}
+
+	if name == envFile {
+		// If it's GITHUB_ENV, we aren't allowed to play with these env vars
+		if key == "CI" || key == "NODE_OPTIONS" || strings.HasPrefix(key, "GITHUB_") ||
+			strings.HasPrefix(key, "RUNNER_") {
+			return "", "", fmt.Errorf("setting $%s is disallowed", key)
+		}
+	}
+
+	return key, value, nil
+}
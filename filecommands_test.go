@@ -0,0 +1,200 @@
+package actions //nolint: testpackage // See actions_test.go
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.followtheprocess.codes/actions/internal/filecmd"
+	"go.followtheprocess.codes/test"
+)
+
+// commandFiles points the $TEST_GITHUB_* env vars for env, output and state at empty files
+// in a temporary directory, returning their paths keyed by the env var.
+func commandFiles(t *testing.T) map[string]string {
+	t.Helper()
+
+	oldEnv, oldOut, oldState := envFile, outFile, stateFile
+	envFile, outFile, stateFile = testEnvName, testOutName, testStateName
+
+	t.Cleanup(func() { envFile, outFile, stateFile = oldEnv, oldOut, oldState })
+
+	dir := t.TempDir()
+	paths := make(map[string]string)
+
+	for _, name := range []string{envFile, outFile, stateFile} {
+		path := filepath.Join(dir, name)
+		test.Ok(t, os.WriteFile(path, nil, filePermissions))
+
+		t.Setenv(name, path)
+		paths[name] = path
+	}
+
+	return paths
+}
+
+// parseFile returns the values in the file command file at path.
+func parseFile(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	test.Ok(t, err)
+	defer file.Close()
+
+	values, err := filecmd.Parse(file)
+	test.Ok(t, err)
+
+	return values
+}
+
+func TestFileCommandsConcurrent(t *testing.T) {
+	paths := commandFiles(t)
+
+	commands := NewFileCommands()
+	t.Cleanup(func() { test.Ok(t, commands.Close()) })
+
+	const n = 100
+
+	want := make(map[string]string, n)
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, n) // test.Ok can't be called from another goroutine
+
+	for i := range n {
+		key := fmt.Sprintf("output_%d", i)
+		value := strings.Repeat(fmt.Sprintf("line %d\n", i), 1+i%10) + "end"
+		want[key] = value
+
+		wg.Go(func() {
+			errs[i] = commands.SetOutput(key, value)
+		})
+	}
+
+	wg.Wait()
+
+	test.Ok(t, errors.Join(errs...))
+
+	test.EqualFunc(t, parseFile(t, paths[outFile]), want, maps.Equal)
+}
+
+func TestFileCommandsBatch(t *testing.T) {
+	t.Run("writes everything", func(t *testing.T) {
+		paths := commandFiles(t)
+
+		commands := NewFileCommands()
+		t.Cleanup(func() { test.Ok(t, commands.Close()) })
+
+		err := commands.Batch(func(batch *Batch) error {
+			test.Ok(t, batch.SetOutput("first", "one"))
+			test.Ok(t, batch.SetOutput("second", "two\nlines"))
+			test.Ok(t, batch.SetState("pid", "1234"))
+			test.Ok(t, batch.SetEnv("FILE_COMMANDS_BATCH", "yes"))
+
+			return nil
+		})
+		test.Ok(t, err)
+
+		outputs := map[string]string{"first": "one", "second": "two\nlines"}
+		test.EqualFunc(t, parseFile(t, paths[outFile]), outputs, maps.Equal)
+		test.EqualFunc(t, parseFile(t, paths[stateFile]), map[string]string{"pid": "1234"}, maps.Equal)
+		test.EqualFunc(t, parseFile(t, paths[envFile]), map[string]string{"FILE_COMMANDS_BATCH": "yes"}, maps.Equal)
+
+		test.Equal(t, os.Getenv("FILE_COMMANDS_BATCH"), "yes")
+		os.Unsetenv("FILE_COMMANDS_BATCH")
+	})
+
+	t.Run("error writes nothing", func(t *testing.T) {
+		paths := commandFiles(t)
+
+		commands := NewFileCommands()
+		t.Cleanup(func() { test.Ok(t, commands.Close()) })
+
+		sentinel := errors.New("bang")
+
+		err := commands.Batch(func(batch *Batch) error {
+			test.Ok(t, batch.SetOutput("first", "one"))
+			return sentinel
+		})
+		test.True(t, errors.Is(err, sentinel))
+
+		contents, err := os.ReadFile(paths[outFile])
+		test.Ok(t, err)
+		test.Equal(t, string(contents), "")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		commandFiles(t)
+
+		err := NewFileCommands().Batch(func(batch *Batch) error {
+			test.Err(t, batch.SetOutput("", "value"))
+			test.Err(t, batch.SetState("key", "  "))
+
+			return batch.SetEnv("GITHUB_TOKEN", "sneaky")
+		})
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "setting $GITHUB_TOKEN is disallowed")
+	})
+
+	t.Run("no file", func(t *testing.T) {
+		commandFiles(t)
+		t.Setenv(outFile, "")
+
+		err := NewFileCommands().Batch(func(batch *Batch) error {
+			return batch.SetOutput("key", "value")
+		})
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "$TEST_GITHUB_OUTPUT is not set or is empty")
+	})
+
+	t.Run("missing file writes nothing", func(t *testing.T) {
+		paths := commandFiles(t)
+		t.Setenv(outFile, filepath.Join(t.TempDir(), "missing"))
+
+		commands := NewFileCommands()
+		t.Cleanup(func() { test.Ok(t, commands.Close()) })
+
+		err := commands.Batch(func(batch *Batch) error {
+			test.Ok(t, batch.SetEnv("FILE_COMMANDS_BATCH_MISSING", "yes"))
+			return batch.SetOutput("key", "value")
+		})
+		test.Err(t, err)
+
+		// $GITHUB_ENV comes first but must not be written as $GITHUB_OUTPUT can't be
+		contents, err := os.ReadFile(paths[envFile])
+		test.Ok(t, err)
+		test.Equal(t, string(contents), "")
+
+		_, ok := os.LookupEnv("FILE_COMMANDS_BATCH_MISSING")
+		test.False(t, ok)
+	})
+}
+
+func TestFileCommandsReopen(t *testing.T) {
+	paths := commandFiles(t)
+
+	commands := NewFileCommands()
+	t.Cleanup(func() { test.Ok(t, commands.Close()) })
+
+	test.Ok(t, commands.SetOutput("before", "close"))
+	test.Ok(t, commands.Close())
+	test.Ok(t, commands.SetOutput("after", "close"))
+
+	want := map[string]string{"before": "close", "after": "close"}
+	test.EqualFunc(t, parseFile(t, paths[outFile]), want, maps.Equal)
+
+	// Pointing the env var somewhere else switches file
+	other := filepath.Join(t.TempDir(), "other")
+	test.Ok(t, os.WriteFile(other, nil, filePermissions))
+	t.Setenv(outFile, other)
+
+	test.Ok(t, commands.SetOutput("moved", "yes"))
+
+	test.EqualFunc(t, parseFile(t, other), map[string]string{"moved": "yes"}, maps.Equal)
+	test.EqualFunc(t, parseFile(t, paths[outFile]), want, maps.Equal)
+}
@@ -0,0 +1,539 @@
+// Package github provides an authenticated client for the GitHub REST API, configured
+// by default from the environment set up by the actions runner.
+//
+// The client picks up its base URL from $GITHUB_API_URL (so it works unchanged on GitHub
+// Enterprise Server) and its token from $GITHUB_TOKEN, transparently waits out primary and
+// secondary rate limits, and returns an [*Error] carrying the details GitHub gives for any
+// unsuccessful response:
+//
+//	client, err := github.New(github.Token(token))
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	var repo struct {
+//		DefaultBranch string `json:"default_branch"`
+//	}
+//
+//	if _, err := client.Get(ctx, "repos/FollowTheProcess/actions", &repo); err != nil {
+//		// Handle error
+//	}
+//
+// List endpoints can be iterated over with [Paginate], which follows the Link header.
+//
+// The same client also talks to the GraphQL API at $GITHUB_GRAPHQL_URL with [Client.GraphQL],
+// and connections can be walked with [PaginateGraphQL].
+//
+// See https://docs.github.com/en/rest and https://docs.github.com/en/graphql
+package github // import "go.followtheprocess.codes/actions/github"
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiURLVar is the env var containing the URL of the GitHub REST API.
+	apiURLVar = "GITHUB_API_URL"
+
+	// graphQLURLVar is the env var containing the URL of the GitHub GraphQL API.
+	graphQLURLVar = "GITHUB_GRAPHQL_URL"
+
+	// tokenVar is the env var conventionally containing the token used to authenticate.
+	tokenVar = "GITHUB_TOKEN"
+
+	// defaultAPIURL is the REST API URL used if $GITHUB_API_URL is not set.
+	defaultAPIURL = "https://api.github.com"
+
+	// apiVersion is the version of the REST API requested.
+	apiVersion = "2022-11-28"
+
+	// defaultUserAgent is the User-Agent sent with every request unless overridden.
+	defaultUserAgent = "go.followtheprocess.codes/actions"
+
+	// secondaryWait is the minimum time to wait after hitting a secondary rate limit
+	// that doesn't say how long to wait for.
+	secondaryWait = time.Minute
+)
+
+// Client is a GitHub API client.
+//
+// A Client is safe for concurrent use by multiple goroutines.
+type Client struct {
+	http       *http.Client  // The underlying HTTP client
+	baseURL    *url.URL      // Base URL of the REST API, always ends in a "/"
+	graphQL    *url.URL      // URL of the GraphQL API
+	token      string        // Token to authenticate with, may be empty
+	userAgent  string        // User-Agent header to send
+	apiURL     string        // Raw REST API URL, parsed in New
+	graphQLURL string        // Raw GraphQL API URL, parsed in New
+	retries    int           // Maximum number of retries after being rate limited
+	maxWait    time.Duration // Longest the client will wait for a rate limit to reset
+}
+
+// Option is a configuration option for a [Client].
+type Option interface {
+	// Apply the option to the client.
+	apply(client *Client)
+}
+
+// option is a function that implements the Option interface.
+type option func(client *Client)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(client *Client) {
+	o(client)
+}
+
+// Token sets the token used to authenticate, by default $GITHUB_TOKEN.
+//
+// Note that the runner does not set $GITHUB_TOKEN automatically, workflows must pass it
+// to the action either in env or, more typically, as an input which should then be passed
+// to the client with this option.
+func Token(token string) Option {
+	f := func(client *Client) {
+		client.token = token
+	}
+
+	return option(f)
+}
+
+// BaseURL sets the URL of the REST API, by default $GITHUB_API_URL or https://api.github.com
+// if that's not set.
+func BaseURL(url string) Option {
+	f := func(client *Client) {
+		client.apiURL = url
+	}
+
+	return option(f)
+}
+
+// GraphQLURL sets the URL of the GraphQL API, by default $GITHUB_GRAPHQL_URL or, if that's
+// not set, derived from the REST API URL.
+func GraphQLURL(url string) Option {
+	f := func(client *Client) {
+		client.graphQLURL = url
+	}
+
+	return option(f)
+}
+
+// HTTPClient sets the [*http.Client] used to make requests, by default [http.DefaultClient].
+func HTTPClient(http *http.Client) Option {
+	f := func(client *Client) {
+		client.http = http
+	}
+
+	return option(f)
+}
+
+// UserAgent sets the User-Agent header sent with each request.
+func UserAgent(agent string) Option {
+	f := func(client *Client) {
+		client.userAgent = agent
+	}
+
+	return option(f)
+}
+
+// RateLimit configures how the client behaves when it is rate limited.
+//
+// A rate limited request is retried at most retries times, but only if GitHub indicates the
+// limit will reset within maxWait, otherwise the [*Error] is returned straight away. The
+// default is 3 retries waiting at most 5 minutes. Pass 0 retries to disable waiting entirely.
+func RateLimit(retries int, maxWait time.Duration) Option {
+	f := func(client *Client) {
+		client.retries = max(retries, 0)
+		client.maxWait = maxWait
+	}
+
+	return option(f)
+}
+
+// New returns a new [Client] configured with options.
+//
+// An error is returned only if the configured API URLs are invalid. A missing token is
+// not an error, in which case requests are unauthenticated and subject to much lower
+// rate limits.
+func New(options ...Option) (*Client, error) {
+	client := &Client{
+		http:       http.DefaultClient,
+		token:      os.Getenv(tokenVar),
+		userAgent:  defaultUserAgent,
+		retries:    3,
+		maxWait:    5 * time.Minute,
+		apiURL:     cmp.Or(os.Getenv(apiURLVar), defaultAPIURL),
+		graphQLURL: os.Getenv(graphQLURLVar),
+	}
+
+	for _, option := range options {
+		option.apply(client)
+	}
+
+	base, err := parseBaseURL(client.apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %w", client.apiURL, err)
+	}
+
+	client.baseURL = base
+
+	if client.graphQLURL == "" {
+		client.graphQLURL = defaultGraphQL(base)
+	}
+
+	graphQL, err := url.Parse(client.graphQLURL)
+	if err != nil || !graphQL.IsAbs() {
+		return nil, fmt.Errorf("invalid GraphQL URL %q", client.graphQLURL)
+	}
+
+	client.graphQL = graphQL
+
+	return client, nil
+}
+
+// Response is the response to a successful request.
+type Response struct {
+	Header     http.Header // The response headers
+	Next       string      // URL of the next page of results, empty if this is the last page
+	Rate       Rate        // The rate limit status after the request
+	StatusCode int         // The HTTP status code
+}
+
+// Rate is the state of a rate limit, as reported by the X-RateLimit-* headers.
+type Rate struct {
+	Reset     time.Time // When the current window resets
+	Resource  string    // The rate limit resource the request counted against e.g. "core"
+	Limit     int       // Maximum number of requests allowed in the window
+	Remaining int       // Number of requests remaining in the window
+	Used      int       // Number of requests made in the window
+}
+
+// Error is returned when the API responds with an unsuccessful status code.
+type Error struct {
+	Method           string        // The HTTP method of the request
+	URL              string        // The URL requested
+	Message          string        // The error message from GitHub
+	DocumentationURL string        // Link to the relevant API documentation
+	RequestID        string        // The X-GitHub-Request-Id, useful when contacting GitHub support
+	Errors           []ErrorDetail // Detailed errors, typically validation failures
+	StatusCode       int           // The HTTP status code
+	RateLimited      bool          // Whether the request failed due to a primary or secondary rate limit
+}
+
+// ErrorDetail is a single detailed error within an [Error].
+type ErrorDetail struct {
+	Resource string `json:"resource,omitempty"` // The resource the error relates to
+	Field    string `json:"field,omitempty"`    // The field the error relates to
+	Code     string `json:"code,omitempty"`     // The error code e.g. "missing_field"
+	Message  string `json:"message,omitempty"`  // A description of the error
+}
+
+// Error implements the error interface for [Error].
+func (e *Error) Error() string {
+	s := &strings.Builder{}
+	fmt.Fprintf(s, "%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+
+	if e.Message != "" {
+		fmt.Fprintf(s, ": %s", e.Message)
+	}
+
+	for _, detail := range e.Errors {
+		switch {
+		case detail.Message != "":
+			fmt.Fprintf(s, "; %s", detail.Message)
+		case detail.Field != "":
+			fmt.Fprintf(s, "; %s.%s: %s", detail.Resource, detail.Field, detail.Code)
+		}
+	}
+
+	if e.RequestID != "" {
+		fmt.Fprintf(s, " (request ID %s)", e.RequestID)
+	}
+
+	if e.DocumentationURL != "" {
+		fmt.Fprintf(s, ", see %s", e.DocumentationURL)
+	}
+
+	return s.String()
+}
+
+// Get makes a GET request to path, decoding the JSON response into v if it is not nil.
+//
+// The path is resolved relative to the base URL e.g. "repos/owner/repo/issues?state=open",
+// or may be an absolute URL.
+func (c *Client) Get(ctx context.Context, path string, v any) (*Response, error) {
+	return c.Do(ctx, http.MethodGet, path, nil, v)
+}
+
+// Post makes a POST request to path with the JSON encoding of body, decoding the JSON response
+// into v if it is not nil.
+func (c *Client) Post(ctx context.Context, path string, body, v any) (*Response, error) {
+	return c.Do(ctx, http.MethodPost, path, body, v)
+}
+
+// Patch makes a PATCH request to path with the JSON encoding of body, decoding the JSON response
+// into v if it is not nil.
+func (c *Client) Patch(ctx context.Context, path string, body, v any) (*Response, error) {
+	return c.Do(ctx, http.MethodPatch, path, body, v)
+}
+
+// Put makes a PUT request to path with the JSON encoding of body, decoding the JSON response
+// into v if it is not nil.
+func (c *Client) Put(ctx context.Context, path string, body, v any) (*Response, error) {
+	return c.Do(ctx, http.MethodPut, path, body, v)
+}
+
+// Delete makes a DELETE request to path.
+func (c *Client) Delete(ctx context.Context, path string) (*Response, error) {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// Do makes a request to path, which is resolved relative to the base URL or may be absolute.
+//
+// If body is not nil it is encoded as JSON and sent as the request body, and if v is not nil
+// the JSON response is decoded into it. If v is an [io.Writer], the raw response body is
+// copied to it instead.
+//
+// If the request is rate limited, Do waits for the limit to reset and retries as configured
+// by [RateLimit]. Any unsuccessful response is returned as an [*Error], along with the
+// [*Response] so the caller can inspect the headers.
+func (c *Client) Do(ctx context.Context, method, path string, body, v any) (*Response, error) {
+	target, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+
+	if body != nil {
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode request body: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, target, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		response := newResponse(resp)
+
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			err := decode(resp, v)
+			resp.Body.Close()
+
+			if err != nil {
+				return response, fmt.Errorf("could not decode response from %s %s: %w", method, target, err)
+			}
+
+			return response, nil
+		}
+
+		apiErr := newError(resp, method, target)
+		resp.Body.Close()
+
+		wait, limited := rateLimitWait(resp, response.Rate, apiErr, attempt)
+		apiErr.RateLimited = limited
+
+		if !limited || attempt >= c.retries || wait > c.maxWait {
+			return response, apiErr
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, errors.Join(apiErr, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes a single request to target.
+func (c *Client) send(ctx context.Context, method string, target *url.URL, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", apiVersion)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// Only send the token to the API host, never to wherever an absolute URL points
+	if c.token != "" && (target.Host == c.baseURL.Host || target.Host == c.graphQL.Host) {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, target, err)
+	}
+
+	return resp, nil
+}
+
+// resolve resolves path against the base URL, absolute URLs are returned as is.
+func (c *Client) resolve(path string) (*url.URL, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+
+	if ref.IsAbs() {
+		return ref, nil
+	}
+
+	// A leading slash would discard any path in the base URL e.g. GHES's /api/v3
+	ref.Path = strings.TrimPrefix(ref.Path, "/")
+
+	return c.baseURL.ResolveReference(ref), nil
+}
+
+// newResponse builds a [Response] from resp.
+func newResponse(resp *http.Response) *Response {
+	return &Response{
+		Header:     resp.Header,
+		StatusCode: resp.StatusCode,
+		Next:       nextLink(resp.Header.Values("Link")),
+		Rate:       parseRate(resp.Header),
+	}
+}
+
+// newError builds an [Error] from the unsuccessful response resp.
+func newError(resp *http.Response, method string, target *url.URL) *Error {
+	apiErr := &Error{
+		Method:     method,
+		URL:        target.String(),
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Github-Request-Id"),
+	}
+
+	var payload struct {
+		Message          string        `json:"message"`
+		DocumentationURL string        `json:"documentation_url"`
+		Errors           []ErrorDetail `json:"errors"`
+	}
+
+	// Error bodies are best effort, some (e.g. from proxies) aren't JSON at all
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err == nil && json.Unmarshal(data, &payload) == nil {
+		apiErr.Message = payload.Message
+		apiErr.DocumentationURL = payload.DocumentationURL
+		apiErr.Errors = payload.Errors
+	}
+
+	return apiErr
+}
+
+// rateLimitWait determines whether the unsuccessful response was due to a rate limit and
+// if so, how long to wait before retrying.
+//
+// See https://docs.github.com/en/rest/using-the-rest-api/best-practices-for-using-the-rest-api#handle-rate-limit-errors-appropriately
+func rateLimitWait(resp *http.Response, rate Rate, apiErr *Error, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	// Secondary rate limits usually say exactly how long to wait
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	// Primary rate limit, wait until the window resets
+	if resp.Header.Get("X-Ratelimit-Remaining") == "0" && !rate.Reset.IsZero() {
+		return max(time.Until(rate.Reset), 0), true
+	}
+
+	// A secondary rate limit without a Retry-After, GitHub says to wait at least a minute
+	// and then back off exponentially
+	if strings.Contains(strings.ToLower(apiErr.Message), "secondary rate limit") {
+		return secondaryWait << attempt, true
+	}
+
+	// A 429 is always a rate limit, even if we don't know how long for
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return secondaryWait << attempt, true
+	}
+
+	return 0, false
+}
+
+// parseRate parses the X-RateLimit-* headers.
+func parseRate(header http.Header) Rate {
+	rate := Rate{Resource: header.Get("X-Ratelimit-Resource")}
+
+	rate.Limit, _ = strconv.Atoi(header.Get("X-Ratelimit-Limit"))
+	rate.Remaining, _ = strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	rate.Used, _ = strconv.Atoi(header.Get("X-Ratelimit-Used"))
+
+	if reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+		rate.Reset = time.Unix(reset, 0)
+	}
+
+	return rate
+}
+
+// decode decodes the JSON body of resp into v, if v is not nil.
+func decode(resp *http.Response, v any) error {
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if w, ok := v.(io.Writer); ok {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}
+
+	err := json.NewDecoder(resp.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		// Empty body
+		return nil
+	}
+
+	return err
+}
+
+// parseBaseURL parses raw as a base URL, ensuring its path ends in a "/" so that relative
+// paths are resolved beneath it.
+func parseBaseURL(raw string) (*url.URL, error) {
+	base, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Scheme == "" || base.Host == "" {
+		return nil, errors.New("must be an absolute URL")
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	return base, nil
+}
@@ -0,0 +1,279 @@
+package github_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/github"
+	"go.followtheprocess.codes/test"
+)
+
+// repo is a cut down repository response.
+type repo struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func TestNew(t *testing.T) {
+	t.Run("invalid url", func(t *testing.T) {
+		_, err := github.New(github.BaseURL("not a url"))
+		test.Err(t, err)
+	})
+
+	t.Run("defaults from env", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.URL.Path, "/api/v3/repos/owner/repo")
+			test.Equal(t, r.Header.Get("Authorization"), "Bearer env-token")
+			test.Equal(t, r.Header.Get("Accept"), "application/vnd.github+json")
+			test.Equal(t, r.Header.Get("X-Github-Api-Version"), "2022-11-28")
+			test.Equal(t, r.Header.Get("User-Agent"), "go.followtheprocess.codes/actions")
+
+			w.Write([]byte(`{"full_name": "owner/repo", "default_branch": "main"}`))
+		}))
+		defer server.Close()
+
+		// GHES style base URL with a path
+		t.Setenv("GITHUB_API_URL", server.URL+"/api/v3")
+		t.Setenv("GITHUB_TOKEN", "env-token")
+
+		client, err := github.New()
+		test.Ok(t, err)
+
+		var got repo
+
+		response, err := client.Get(t.Context(), "/repos/owner/repo", &got)
+		test.Ok(t, err)
+		test.Equal(t, response.StatusCode, http.StatusOK)
+		test.Equal(t, got, repo{FullName: "owner/repo", DefaultBranch: "main"})
+	})
+}
+
+func TestDo(t *testing.T) {
+	t.Run("post", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Method, http.MethodPost)
+			test.Equal(t, r.URL.Path, "/repos/owner/repo/issues")
+			test.Equal(t, r.Header.Get("Content-Type"), "application/json")
+			test.Equal(t, r.Header.Get("Authorization"), "Bearer token")
+
+			var body map[string]string
+			test.Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			test.Equal(t, body["title"], "Bug")
+
+			w.Header().Set("X-Ratelimit-Limit", "5000")
+			w.Header().Set("X-Ratelimit-Remaining", "4999")
+			w.Header().Set("X-Ratelimit-Used", "1")
+			w.Header().Set("X-Ratelimit-Reset", "1700000000")
+			w.Header().Set("X-Ratelimit-Resource", "core")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 42}`))
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL), github.Token("token"))
+		test.Ok(t, err)
+
+		var issue struct {
+			Number int `json:"number"`
+		}
+
+		response, err := client.Post(t.Context(), "repos/owner/repo/issues", map[string]string{"title": "Bug"}, &issue)
+		test.Ok(t, err)
+		test.Equal(t, issue.Number, 42)
+		test.Equal(t, response.StatusCode, http.StatusCreated)
+		test.Equal(t, response.Rate, github.Rate{
+			Limit:     5000,
+			Remaining: 4999,
+			Used:      1,
+			Reset:     time.Unix(1700000000, 0),
+			Resource:  "core",
+		})
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Method, http.MethodDelete)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		response, err := client.Delete(t.Context(), "repos/owner/repo/labels/bug")
+		test.Ok(t, err)
+		test.Equal(t, response.StatusCode, http.StatusNoContent)
+	})
+
+	t.Run("token not sent elsewhere", func(t *testing.T) {
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Header.Get("Authorization"), "")
+		}))
+		defer other.Close()
+
+		client, err := github.New(github.BaseURL("https://api.github.com"), github.Token("secret"))
+		test.Ok(t, err)
+
+		_, err = client.Get(t.Context(), other.URL+"/somewhere", nil)
+		test.Ok(t, err)
+	})
+}
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Github-Request-Id", "ABCD:1234")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{
+			"message": "Validation Failed",
+			"errors": [{"resource": "Issue", "field": "title", "code": "missing_field"}],
+			"documentation_url": "https://docs.github.com/rest/issues/issues#create-an-issue"
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := github.New(github.BaseURL(server.URL))
+	test.Ok(t, err)
+
+	response, err := client.Post(t.Context(), "repos/owner/repo/issues", map[string]string{}, nil)
+	test.Err(t, err)
+	test.Equal(t, response.StatusCode, http.StatusUnprocessableEntity)
+
+	var apiErr *github.Error
+
+	test.True(t, errors.As(err, &apiErr))
+	test.Equal(t, apiErr.StatusCode, http.StatusUnprocessableEntity)
+	test.Equal(t, apiErr.RequestID, "ABCD:1234")
+	test.Equal(t, apiErr.DocumentationURL, "https://docs.github.com/rest/issues/issues#create-an-issue")
+	test.Equal(t, apiErr.Message, "Validation Failed")
+	test.Equal(t, len(apiErr.Errors), 1)
+	test.Equal(t, apiErr.Errors[0].Code, "missing_field")
+	test.False(t, apiErr.RateLimited)
+
+	want := "POST " + server.URL + "/repos/owner/repo/issues: 422 Unprocessable Entity: Validation Failed; " +
+		"Issue.title: missing_field (request ID ABCD:1234), see https://docs.github.com/rest/issues/issues#create-an-issue"
+	test.Equal(t, err.Error(), want)
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("primary", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("X-Ratelimit-Remaining", "0")
+				w.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+
+				return
+			}
+
+			w.Write([]byte(`{"full_name": "owner/repo"}`))
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		var got repo
+
+		_, err = client.Get(t.Context(), "repos/owner/repo", &got)
+		test.Ok(t, err)
+		test.Equal(t, got.FullName, "owner/repo")
+		test.Equal(t, calls.Load(), 2)
+	})
+
+	t.Run("secondary", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"message": "You have exceeded a secondary rate limit"}`))
+
+				return
+			}
+
+			w.Write([]byte(`{"full_name": "owner/repo"}`))
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		_, err = client.Get(t.Context(), "repos/owner/repo", nil)
+		test.Ok(t, err)
+		test.Equal(t, calls.Load(), 3)
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL), github.RateLimit(3, time.Minute))
+		test.Ok(t, err)
+
+		_, err = client.Get(t.Context(), "repos/owner/repo", nil)
+		test.Err(t, err)
+		test.Equal(t, calls.Load(), 1)
+
+		var apiErr *github.Error
+
+		test.True(t, errors.As(err, &apiErr))
+		test.True(t, apiErr.RateLimited)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL), github.RateLimit(2, time.Minute))
+		test.Ok(t, err)
+
+		_, err = client.Get(t.Context(), "repos/owner/repo", nil)
+		test.Err(t, err)
+		test.Equal(t, calls.Load(), 3)
+	})
+
+	t.Run("forbidden is not a rate limit", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		_, err = client.Get(t.Context(), "repos/owner/repo", nil)
+		test.Err(t, err)
+		test.Equal(t, calls.Load(), 1)
+
+		var apiErr *github.Error
+
+		test.True(t, errors.As(err, &apiErr))
+		test.False(t, apiErr.RateLimited)
+	})
+}
@@ -0,0 +1,235 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GraphQLError is a single entry in the errors array of a GraphQL response.
+type GraphQLError struct {
+	Extensions map[string]any `json:"extensions,omitempty"` // Additional error information
+	Message    string         `json:"message"`              // Description of the error
+	Type       string         `json:"type,omitempty"`       // GitHub's error type e.g. NOT_FOUND, FORBIDDEN
+	Path       []any          `json:"path,omitempty"`       // Path to the field that errored, of strings and integer indexes
+	Locations  []Location     `json:"locations,omitempty"`  // Locations in the query the error relates to
+}
+
+// Location is a position in a GraphQL query.
+type Location struct {
+	Line   int `json:"line"`   // The line in the query
+	Column int `json:"column"` // The column in the query
+}
+
+// Error implements the error interface for [GraphQLError].
+func (e GraphQLError) Error() string {
+	s := &strings.Builder{}
+
+	if e.Type != "" {
+		fmt.Fprintf(s, "%s: ", e.Type)
+	}
+
+	s.WriteString(e.Message)
+
+	if len(e.Path) != 0 {
+		parts := make([]string, 0, len(e.Path))
+		for _, part := range e.Path {
+			parts = append(parts, fmt.Sprint(part))
+		}
+
+		fmt.Fprintf(s, " (at %s)", strings.Join(parts, "."))
+	}
+
+	return s.String()
+}
+
+// GraphQLErrors is returned from [Client.GraphQL] when the response contains a
+// non-empty errors array.
+//
+// GraphQL responses may contain both data and errors, in which case whatever data there
+// was is still decoded before this is returned.
+type GraphQLErrors []GraphQLError
+
+// Error implements the error interface for [GraphQLErrors].
+func (e GraphQLErrors) Error() string {
+	if len(e) == 1 {
+		return "graphql: " + e[0].Error()
+	}
+
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("graphql: %d errors: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Unwrap returns each individual error so they can be inspected with [errors.As].
+func (e GraphQLErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// GraphQL sends a GraphQL query (or mutation) with variables, decoding the "data" field
+// of the response into v if it is not nil.
+//
+//	var resp struct {
+//		Repository struct {
+//			StargazerCount int `json:"stargazerCount"`
+//		} `json:"repository"`
+//	}
+//
+//	query := `query($owner: String!, $name: String!) {
+//		repository(owner: $owner, name: $name) { stargazerCount }
+//	}`
+//
+//	err := client.GraphQL(ctx, query, map[string]any{"owner": "octocat", "name": "hello-world"}, &resp)
+//
+// If the response contains errors, they are returned as [GraphQLErrors]. Transport level
+// failures are returned as an [*Error], as with the REST API.
+//
+// See https://docs.github.com/en/graphql/guides/forming-calls-with-graphql
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, v any) error {
+	request := struct {
+		Variables map[string]any `json:"variables,omitempty"`
+		Query     string         `json:"query"`
+	}{
+		Query:     query,
+		Variables: variables,
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}
+
+	if _, err := c.Do(ctx, http.MethodPost, c.graphQL.String(), request, &response); err != nil {
+		return err
+	}
+
+	if v != nil && len(response.Data) != 0 && string(response.Data) != "null" {
+		if err := json.Unmarshal(response.Data, v); err != nil {
+			return fmt.Errorf("could not decode GraphQL data: %w", err)
+		}
+	}
+
+	if len(response.Errors) != 0 {
+		return response.Errors
+	}
+
+	return nil
+}
+
+// PageInfo is the pageInfo object of a GraphQL connection.
+type PageInfo struct {
+	EndCursor   string `json:"endCursor"`   // Cursor to pass as "after" to get the next page
+	HasNextPage bool   `json:"hasNextPage"` // Whether there are more pages
+}
+
+// Connection is a GraphQL connection, a single page of nodes along with its [PageInfo].
+//
+// The query must select both nodes and pageInfo { hasNextPage endCursor } for the
+// connection being paginated.
+type Connection[N any] struct {
+	Nodes    []N      `json:"nodes"`    // The nodes in this page
+	PageInfo PageInfo `json:"pageInfo"` // Information on how to get the next page
+}
+
+// PaginateGraphQL iterates over every node in a GraphQL connection, running query once
+// per page and following pageInfo until hasNextPage is false.
+//
+// The query must accept a nullable $cursor variable and pass it as the "after" argument
+// of the connection. Each response is decoded into R, and connection extracts the
+// [Connection] to be walked from it:
+//
+//	type response struct {
+//		Repository struct {
+//			Issues github.Connection[Issue] `json:"issues"`
+//		} `json:"repository"`
+//	}
+//
+//	query := `query($owner: String!, $name: String!, $cursor: String) {
+//		repository(owner: $owner, name: $name) {
+//			issues(first: 100, after: $cursor) {
+//				nodes { number title }
+//				pageInfo { hasNextPage endCursor }
+//			}
+//		}
+//	}`
+//
+//	issues := github.PaginateGraphQL(ctx, client, query, vars, func(r response) github.Connection[Issue] {
+//		return r.Repository.Issues
+//	})
+//
+//	for issue, err := range issues {
+//		// ...
+//	}
+//
+// If a request fails, the error is yielded and iteration stops.
+func PaginateGraphQL[R, N any](
+	ctx context.Context,
+	client *Client,
+	query string,
+	variables map[string]any,
+	connection func(R) Connection[N],
+) iter.Seq2[N, error] {
+	return func(yield func(N, error) bool) {
+		var zero N
+
+		// Don't modify the caller's variables
+		vars := maps.Clone(variables)
+		if vars == nil {
+			vars = make(map[string]any)
+		}
+
+		vars["cursor"] = nil
+
+		for {
+			var response R
+
+			if err := client.GraphQL(ctx, query, vars, &response); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			page := connection(response)
+
+			for _, node := range page.Nodes {
+				if !yield(node, nil) {
+					return
+				}
+			}
+
+			if !page.PageInfo.HasNextPage || page.PageInfo.EndCursor == "" {
+				return
+			}
+
+			vars["cursor"] = page.PageInfo.EndCursor
+		}
+	}
+}
+
+// defaultGraphQL derives the GraphQL API URL from the REST API base URL.
+//
+// On github.com the GraphQL API is at /graphql under the REST API, whereas on GitHub
+// Enterprise Server the REST API is at /api/v3 and GraphQL at /api/graphql.
+func defaultGraphQL(base *url.URL) string {
+	graphQL := *base
+
+	if strings.HasSuffix(graphQL.Path, "/api/v3/") {
+		graphQL.Path = strings.TrimSuffix(graphQL.Path, "v3/") + "graphql"
+	} else {
+		graphQL.Path += "graphql"
+	}
+
+	return graphQL.String()
+}
@@ -0,0 +1,204 @@
+package github_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.followtheprocess.codes/actions/github"
+	"go.followtheprocess.codes/test"
+)
+
+// graphQLRequest is the body of a GraphQL request.
+type graphQLRequest struct {
+	Variables map[string]any `json:"variables"`
+	Query     string         `json:"query"`
+}
+
+func TestGraphQL(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Method, http.MethodPost)
+			test.Equal(t, r.URL.Path, "/graphql")
+			test.Equal(t, r.Header.Get("Authorization"), "Bearer token")
+
+			var req graphQLRequest
+			test.Ok(t, json.NewDecoder(r.Body).Decode(&req))
+			test.Equal(t, req.Query, "query { viewer { login } }")
+			test.Equal(t, req.Variables["owner"], "octocat")
+
+			w.Write([]byte(`{"data": {"viewer": {"login": "octocat"}}}`))
+		}))
+		defer server.Close()
+
+		t.Setenv("GITHUB_GRAPHQL_URL", server.URL+"/graphql")
+
+		client, err := github.New(github.BaseURL(server.URL), github.Token("token"))
+		test.Ok(t, err)
+
+		var resp struct {
+			Viewer struct {
+				Login string `json:"login"`
+			} `json:"viewer"`
+		}
+
+		err = client.GraphQL(t.Context(), "query { viewer { login } }", map[string]any{"owner": "octocat"}, &resp)
+		test.Ok(t, err)
+		test.Equal(t, resp.Viewer.Login, "octocat")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{
+				"data": {"repository": null, "viewer": {"login": "octocat"}},
+				"errors": [{
+					"type": "NOT_FOUND",
+					"path": ["repository"],
+					"locations": [{"line": 1, "column": 9}],
+					"message": "Could not resolve to a Repository with the name 'octocat/nope'."
+				}]
+			}`))
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		var resp struct {
+			Viewer struct {
+				Login string `json:"login"`
+			} `json:"viewer"`
+		}
+
+		err = client.GraphQL(t.Context(), "query { ... }", nil, &resp)
+		test.Err(t, err)
+		test.Equal(
+			t,
+			err.Error(),
+			"graphql: NOT_FOUND: Could not resolve to a Repository with the name 'octocat/nope'. (at repository)",
+		)
+
+		// Partial data is still decoded
+		test.Equal(t, resp.Viewer.Login, "octocat")
+
+		var gqlErrs github.GraphQLErrors
+
+		test.True(t, errors.As(err, &gqlErrs))
+		test.Equal(t, len(gqlErrs), 1)
+		test.Equal(t, gqlErrs[0].Locations[0], github.Location{Line: 1, Column: 9})
+
+		var gqlErr github.GraphQLError
+
+		test.True(t, errors.As(err, &gqlErr))
+		test.Equal(t, gqlErr.Type, "NOT_FOUND")
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Bad credentials"}`))
+		}))
+		defer server.Close()
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		err = client.GraphQL(t.Context(), "query { viewer { login } }", nil, nil)
+		test.Err(t, err)
+
+		var apiErr *github.Error
+
+		test.True(t, errors.As(err, &apiErr))
+		test.Equal(t, apiErr.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("enterprise url", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.URL.Path, "/api/graphql")
+			w.Write([]byte(`{"data": {}}`))
+		}))
+		defer server.Close()
+
+		t.Setenv("GITHUB_GRAPHQL_URL", "")
+
+		client, err := github.New(github.BaseURL(server.URL + "/api/v3"))
+		test.Ok(t, err)
+
+		test.Ok(t, client.GraphQL(t.Context(), "query { viewer { login } }", nil, nil))
+	})
+}
+
+type issue struct {
+	Title  string `json:"title"`
+	Number int    `json:"number"`
+}
+
+type issuesResponse struct {
+	Repository struct {
+		Issues github.Connection[issue] `json:"issues"`
+	} `json:"repository"`
+}
+
+func TestPaginateGraphQL(t *testing.T) {
+	var cursors []any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		test.Ok(t, json.NewDecoder(r.Body).Decode(&req))
+		test.Equal(t, req.Variables["owner"], "octocat")
+
+		cursor := req.Variables["cursor"]
+		cursors = append(cursors, cursor)
+
+		switch cursor {
+		case nil:
+			fmt.Fprint(w, `{"data": {"repository": {"issues": {
+				"nodes": [{"number": 1, "title": "one"}, {"number": 2, "title": "two"}],
+				"pageInfo": {"hasNextPage": true, "endCursor": "abc"}
+			}}}}`)
+		case "abc":
+			fmt.Fprint(w, `{"data": {"repository": {"issues": {
+				"nodes": [{"number": 3, "title": "three"}],
+				"pageInfo": {"hasNextPage": false, "endCursor": "def"}
+			}}}}`)
+		default:
+			t.Errorf("unexpected cursor %v", cursor)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client, err := github.New(github.BaseURL(server.URL))
+	test.Ok(t, err)
+
+	variables := map[string]any{"owner": "octocat"}
+
+	issues := github.PaginateGraphQL(
+		t.Context(),
+		client,
+		"query { ... }",
+		variables,
+		func(r issuesResponse) github.Connection[issue] {
+			return r.Repository.Issues
+		},
+	)
+
+	var got []int
+
+	for issue, err := range issues {
+		test.Ok(t, err)
+
+		got = append(got, issue.Number)
+	}
+
+	test.EqualFunc(t, got, []int{1, 2, 3}, slices.Equal)
+	test.EqualFunc(t, cursors, []any{nil, "abc"}, slices.Equal)
+
+	// The caller's variables are left alone
+	_, ok := variables["cursor"]
+	test.False(t, ok)
+}
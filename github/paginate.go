@@ -0,0 +1,120 @@
+package github
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// perPage is the page size requested by [Paginate] if the path doesn't specify one, the
+// maximum most endpoints allow.
+const perPage = "100"
+
+// Paginate iterates over every item returned by the list endpoint at path, fetching each
+// page in turn by following the "next" relation in the Link header.
+//
+// Each page must be a JSON array of T. If the path doesn't already set the per_page query
+// parameter, 100 is requested to minimise the number of requests made.
+//
+//	for issue, err := range github.Paginate[Issue](ctx, client, "repos/owner/repo/issues?state=open") {
+//		if err != nil {
+//			// Handle error
+//		}
+//		fmt.Println(issue.Title)
+//	}
+//
+// If a request fails, the error is yielded and iteration stops.
+//
+// See https://docs.github.com/en/rest/using-the-rest-api/using-pagination-in-the-rest-api
+func Paginate[T any](ctx context.Context, client *Client, path string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		next, err := withPerPage(path)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for next != "" {
+			var page []T
+
+			response, err := client.Get(ctx, next, &page)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			next = response.Next
+		}
+	}
+}
+
+// withPerPage adds the per_page query parameter to path if it is not already set.
+func withPerPage(path string) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	if query.Has("per_page") {
+		return path, nil
+	}
+
+	query.Set("per_page", perPage)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// nextLink returns the URL of the "next" relation in the Link header values, or ""
+// if there is none.
+//
+// The header looks like:
+//
+//	<https://api.github.com/repositories/1/issues?page=2>; rel="next", <https://api.github.com/repositories/1/issues?page=5>; rel="last"
+func nextLink(values []string) string {
+	for _, value := range values {
+		// URLs may themselves contain commas and semicolons, so find each <url> explicitly
+		// rather than splitting the header
+		rest := value
+
+		for {
+			start := strings.IndexByte(rest, '<')
+			if start == -1 {
+				break
+			}
+
+			end := strings.IndexByte(rest[start:], '>')
+			if end == -1 {
+				break
+			}
+
+			target := rest[start+1 : start+end]
+			rest = rest[start+end+1:]
+
+			params := rest
+			if next := strings.IndexByte(rest, '<'); next != -1 {
+				params = rest[:next]
+			}
+
+			for param := range strings.SplitSeq(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(param), ",")), "=")
+				if key == "rel" && slices.Contains(strings.Fields(strings.Trim(val, `"`)), "next") {
+					return target
+				}
+			}
+		}
+	}
+
+	return ""
+}
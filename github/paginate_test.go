@@ -0,0 +1,134 @@
+package github_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.followtheprocess.codes/actions/github"
+	"go.followtheprocess.codes/test"
+)
+
+// pages serves numbered items in pages of 2 up to total, linking each page to the next.
+func pages(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.Equal(t, r.URL.Query().Get("per_page"), "100")
+		test.Equal(t, r.URL.Query().Get("labels"), "bug,help wanted")
+
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			_, err := fmt.Sscanf(p, "%d", &page)
+			test.Ok(t, err)
+		}
+
+		if page == 4 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		start := (page-1)*2 + 1
+		end := min(start+1, total)
+
+		if end < total {
+			// Commas in the URL must not confuse the Link parsing
+			next := fmt.Sprintf("%s/items?labels=bug,help+wanted&per_page=100&page=%d", server.URL, page+1)
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s/items?page=99>; rel="last"`, next, server.URL))
+		}
+
+		fmt.Fprint(w, "[")
+
+		for i := start; i <= end; i++ {
+			if i > start {
+				fmt.Fprint(w, ",")
+			}
+
+			fmt.Fprintf(w, `{"number": %d}`, i)
+		}
+
+		fmt.Fprint(w, "]")
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+type item struct {
+	Number int `json:"number"`
+}
+
+func TestPaginate(t *testing.T) {
+	t.Run("all pages", func(t *testing.T) {
+		server := pages(t, 5)
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		var got []int
+
+		for item, err := range github.Paginate[item](t.Context(), client, "items?labels=bug,help+wanted") {
+			test.Ok(t, err)
+
+			got = append(got, item.Number)
+		}
+
+		test.EqualFunc(t, got, []int{1, 2, 3, 4, 5}, slices.Equal)
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		server := pages(t, 5)
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		var got []int
+
+		for item, err := range github.Paginate[item](t.Context(), client, "items?labels=bug,help+wanted") {
+			test.Ok(t, err)
+
+			got = append(got, item.Number)
+			if item.Number == 3 {
+				break
+			}
+		}
+
+		test.EqualFunc(t, got, []int{1, 2, 3}, slices.Equal)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server := pages(t, 10)
+
+		client, err := github.New(github.BaseURL(server.URL))
+		test.Ok(t, err)
+
+		var (
+			got  []int
+			errs int
+		)
+
+		for item, err := range github.Paginate[item](t.Context(), client, "items?labels=bug,help+wanted") {
+			if err != nil {
+				errs++
+
+				var apiErr *github.Error
+
+				test.True(t, errors.As(err, &apiErr))
+				test.Equal(t, apiErr.StatusCode, http.StatusInternalServerError)
+
+				continue
+			}
+
+			got = append(got, item.Number)
+		}
+
+		test.Equal(t, errs, 1)
+		test.EqualFunc(t, got, []int{1, 2, 3, 4, 5, 6}, slices.Equal)
+	})
+}
@@ -0,0 +1,432 @@
+// Package glob matches files against glob patterns with the same semantics as the
+// @actions/glob package of the actions toolkit, which is how most actions interpret
+// inputs like "path" or "files".
+//
+// Patterns are typically given one per line, so the output of [input.Lines] can be passed
+// straight in:
+//
+//	patterns, err := input.Lines("path")
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	globber, err := glob.New(patterns)
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	for path, err := range globber.All() {
+//		if err != nil {
+//			// Handle error
+//		}
+//		// Do something with path
+//	}
+//
+// The pattern syntax is:
+//
+//   - "*" matches zero or more characters within a path segment
+//   - "?" matches a single character within a path segment
+//   - "[...]" matches a range of characters within a path segment e.g. "[a-z]"
+//   - "**" as a whole segment matches zero or more directories
+//   - A leading "!" negates the pattern, excluding anything it matches
+//   - A trailing separator only matches directories
+//   - A leading "#" is a comment, and blank lines are ignored
+//   - A leading "~" is expanded to the home directory
+//
+// Patterns are evaluated in order so later patterns take precedence over earlier ones, a
+// negated pattern can exclude the matches of an earlier pattern and a later pattern can
+// add them back in again. Relative patterns are relative to the current directory, unless
+// [Dir] is passed.
+//
+// On Windows both "/" and "\" are separators and matching is case insensitive, elsewhere
+// "\" escapes a metacharacter.
+//
+// See https://github.com/actions/toolkit/tree/main/packages/glob
+package glob // import "go.followtheprocess.codes/actions/glob"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Globber matches a set of compiled patterns against the filesystem.
+type Globber struct {
+	dir                 string    // Directory relative patterns are resolved against
+	patterns            []pattern // The compiled patterns, in order
+	searchPaths         []string  // Directories to search, none of which are within another
+	followSymlinks      bool      // Whether to follow symlinks to directories
+	implicitDescendants bool      // Whether matching a directory also matches everything below it
+	matchDirectories    bool      // Whether directories are included in the results
+	omitBrokenSymlinks  bool      // Whether broken symlinks are silently skipped
+	excludeHidden       bool      // Whether hidden files and directories are skipped
+}
+
+// Option is a configuration option for a [Globber].
+type Option interface {
+	// Apply the option to the globber.
+	apply(globber *Globber)
+}
+
+// option is a function that implements the Option interface.
+type option func(globber *Globber)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(globber *Globber) {
+	o(globber)
+}
+
+// Dir sets the directory relative patterns are resolved against, by default the
+// current working directory.
+func Dir(dir string) Option {
+	f := func(globber *Globber) {
+		globber.dir = dir
+	}
+
+	return option(f)
+}
+
+// FollowSymlinks sets whether symlinks to directories are followed, by default true.
+//
+// When following symlinks, cycles are detected and not followed a second time.
+func FollowSymlinks(follow bool) Option {
+	f := func(globber *Globber) {
+		globber.followSymlinks = follow
+	}
+
+	return option(f)
+}
+
+// ImplicitDescendants sets whether a pattern matching a directory also matches everything
+// below it, by default true.
+func ImplicitDescendants(implicit bool) Option {
+	f := func(globber *Globber) {
+		globber.implicitDescendants = implicit
+	}
+
+	return option(f)
+}
+
+// MatchDirectories sets whether directories are included in the results, by default true.
+//
+// When false, patterns still match directories for the purposes of [ImplicitDescendants],
+// but only files are returned.
+func MatchDirectories(match bool) Option {
+	f := func(globber *Globber) {
+		globber.matchDirectories = match
+	}
+
+	return option(f)
+}
+
+// OmitBrokenSymlinks sets whether broken symlinks are silently skipped, by default true.
+//
+// When false, a broken symlink is an error. Only relevant when following symlinks.
+func OmitBrokenSymlinks(omit bool) Option {
+	f := func(globber *Globber) {
+		globber.omitBrokenSymlinks = omit
+	}
+
+	return option(f)
+}
+
+// ExcludeHidden sets whether hidden files and directories (those whose name starts with a ".")
+// are skipped, by default false.
+func ExcludeHidden(exclude bool) Option {
+	f := func(globber *Globber) {
+		globber.excludeHidden = exclude
+	}
+
+	return option(f)
+}
+
+// New compiles patterns into a [Globber].
+//
+// Each pattern is a single line, but patterns containing newlines are split so that a
+// raw multi-line input may also be passed.
+func New(patterns []string, options ...Option) (*Globber, error) {
+	globber := &Globber{
+		followSymlinks:      true,
+		implicitDescendants: true,
+		matchDirectories:    true,
+		omitBrokenSymlinks:  true,
+	}
+
+	for _, option := range options {
+		option.apply(globber)
+	}
+
+	if globber.dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("could not get working directory: %w", err)
+		}
+
+		globber.dir = cwd
+	}
+
+	dir, err := filepath.Abs(globber.dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", globber.dir, err)
+	}
+
+	globber.dir = dir
+
+	for _, text := range patterns {
+		for line := range strings.Lines(text) {
+			p, ok, err := parse(line, globber.dir)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", strings.TrimSpace(line), err)
+			}
+
+			if !ok {
+				continue
+			}
+
+			globber.patterns = append(globber.patterns, p)
+
+			if globber.implicitDescendants {
+				if descendants, ok := p.descendants(); ok {
+					globber.patterns = append(globber.patterns, descendants)
+				}
+			}
+		}
+	}
+
+	globber.searchPaths = searchPaths(globber.patterns)
+
+	return globber, nil
+}
+
+// Glob returns every path matched by patterns, see [New] and [Globber.All].
+func Glob(patterns []string, options ...Option) ([]string, error) {
+	globber, err := New(patterns, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	for path, err := range globber.All() {
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// SearchPaths returns the directories that will be searched for matches, which are the
+// longest literal prefixes of the patterns.
+func (g *Globber) SearchPaths() []string {
+	return slices.Clone(g.searchPaths)
+}
+
+// Match reports whether path is matched by the patterns, without touching the filesystem.
+//
+// A path with a trailing separator is taken to be a directory.
+func (g *Globber) Match(path string) bool {
+	kind := matchFile
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		kind = matchDirectory
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.dir, path)
+	}
+
+	return g.match(path)&kind != 0
+}
+
+// All returns an iterator over every path matched by the patterns, walking the search paths
+// in the order of the patterns and the contents of each directory in lexical order.
+//
+// Paths are absolute. If an error occurs, it is yielded and iteration stops.
+func (g *Globber) All() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		type item struct {
+			path  string
+			depth int
+		}
+
+		stack := make([]item, 0, len(g.searchPaths))
+		for _, searchPath := range slices.Backward(g.searchPaths) {
+			stack = append(stack, item{path: searchPath})
+		}
+
+		// Resolved paths of the directories from the current search path down to the
+		// current item, for detecting symlink cycles
+		var chain []string
+
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if g.excludeHidden && strings.HasPrefix(filepath.Base(current.path), ".") {
+				continue
+			}
+
+			kind := g.match(current.path)
+			partial := kind != matchNone || g.partial(current.path)
+
+			if !partial {
+				continue
+			}
+
+			info, err := g.stat(current.path, current.depth)
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			if info == nil {
+				continue
+			}
+
+			if !info.IsDir() {
+				if kind&matchFile != 0 && !yield(current.path, nil) {
+					return
+				}
+
+				continue
+			}
+
+			if g.followSymlinks {
+				var resolved string
+
+				resolved, err = filepath.EvalSymlinks(current.path)
+				if err != nil {
+					yield("", fmt.Errorf("could not resolve %s: %w", current.path, err))
+					return
+				}
+
+				chain = chain[:min(current.depth, len(chain))]
+				if slices.Contains(chain, resolved) {
+					// Symlink cycle, we've been here before
+					continue
+				}
+
+				chain = append(chain, resolved)
+			}
+
+			if kind&matchDirectory != 0 && g.matchDirectories {
+				if !yield(current.path, nil) {
+					return
+				}
+			}
+
+			entries, err := os.ReadDir(current.path)
+			if err != nil {
+				yield("", fmt.Errorf("could not read directory %s: %w", current.path, err))
+				return
+			}
+
+			for _, entry := range slices.Backward(entries) {
+				stack = append(stack, item{path: filepath.Join(current.path, entry.Name()), depth: current.depth + 1})
+			}
+		}
+	}
+}
+
+// stat returns information about the item at path, or nil if it should be skipped.
+func (g *Globber) stat(path string, depth int) (fs.FileInfo, error) {
+	var (
+		info fs.FileInfo
+		err  error
+	)
+
+	if g.followSymlinks {
+		info, err = os.Stat(path)
+	} else {
+		info, err = os.Lstat(path)
+	}
+
+	if err == nil {
+		return info, nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not stat %s: %w", path, err)
+	}
+
+	if depth == 0 {
+		// A search path that doesn't exist just doesn't match anything
+		return nil, nil //nolint:nilnil // nil info is how skipped items are signalled
+	}
+
+	// It was listed by its parent so must be a broken symlink
+	if g.omitBrokenSymlinks {
+		return nil, nil //nolint:nilnil // nil info is how skipped items are signalled
+	}
+
+	return nil, fmt.Errorf("broken symlink %s", path)
+}
+
+// match returns the kind of item the absolute path is matched as, patterns later in the
+// list overriding earlier ones.
+func (g *Globber) match(path string) matchKind {
+	segments := split(filepath.ToSlash(path))
+	kind := matchNone
+
+	for _, p := range g.patterns {
+		if p.negate {
+			kind &^= p.match(segments)
+		} else {
+			kind |= p.match(segments)
+		}
+	}
+
+	return kind
+}
+
+// partial reports whether the directory at the absolute path could contain a match.
+func (g *Globber) partial(path string) bool {
+	segments := split(filepath.ToSlash(path))
+
+	for _, p := range g.patterns {
+		if !p.negate && p.partial(segments) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchPaths returns the search paths of the non-negated patterns, in the order of the
+// patterns and without any that are within another.
+func searchPaths(patterns []pattern) []string {
+	var paths []string
+
+	for _, p := range patterns {
+		if !p.negate && !slices.Contains(paths, p.searchPath()) {
+			paths = append(paths, p.searchPath())
+		}
+	}
+
+	result := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		if !slices.ContainsFunc(paths, func(parent string) bool { return within(parent, path) }) {
+			result = append(result, path)
+		}
+	}
+
+	return result
+}
+
+// within reports whether path is strictly inside the directory parent.
+func within(parent, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil || rel == "." {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,267 @@
+package glob_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"go.followtheprocess.codes/actions/glob"
+	"go.followtheprocess.codes/test"
+)
+
+// makeTree creates files (and their parent directories) under a temporary directory,
+// returning its path.
+func makeTree(t *testing.T, files ...string) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+
+		test.Ok(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		test.Ok(t, os.WriteFile(path, []byte(file), 0o644))
+	}
+
+	return root
+}
+
+// relative returns paths relative to root, slash separated.
+func relative(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+
+	rel := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		r, err := filepath.Rel(root, path)
+		test.Ok(t, err)
+
+		rel = append(rel, filepath.ToSlash(r))
+	}
+
+	return rel
+}
+
+func TestGlob(t *testing.T) {
+	root := makeTree(
+		t,
+		".hidden",
+		"a.txt",
+		"b.go",
+		"docs/readme.md",
+		"src/main.go",
+		"src/util/helper.go",
+		"src/util/helper_test.go",
+		"vendor/lib.go",
+	)
+
+	tests := []struct {
+		name     string
+		patterns []string
+		options  []glob.Option
+		want     []string
+	}{
+		{
+			name:     "star",
+			patterns: []string{"*.txt"},
+			want:     []string{"a.txt"},
+		},
+		{
+			name:     "globstar",
+			patterns: []string{"**/*.go"},
+			want:     []string{"b.go", "src/main.go", "src/util/helper.go", "src/util/helper_test.go", "vendor/lib.go"},
+		},
+		{
+			name:     "negation",
+			patterns: []string{"**/*.go", "!**/*_test.go"},
+			want:     []string{"b.go", "src/main.go", "src/util/helper.go", "vendor/lib.go"},
+		},
+		{
+			name:     "negated directory excludes descendants",
+			patterns: []string{"**/*.go", "!vendor"},
+			want:     []string{"b.go", "src/main.go", "src/util/helper.go", "src/util/helper_test.go"},
+		},
+		{
+			name:     "later patterns override",
+			patterns: []string{"**/*.go", "!**/*_test.go", "src/util/*_test.go"},
+			want:     []string{"b.go", "src/main.go", "src/util/helper.go", "src/util/helper_test.go", "vendor/lib.go"},
+		},
+		{
+			name:     "double negation",
+			patterns: []string{"!!a.txt"},
+			want:     []string{"a.txt"},
+		},
+		{
+			name:     "multi-line with comments",
+			patterns: []string{"# Docs\n\n  docs/*.md  \n"},
+			want:     []string{"docs/readme.md"},
+		},
+		{
+			name:     "implicit descendants",
+			patterns: []string{"src"},
+			want:     []string{"src", "src/main.go", "src/util", "src/util/helper.go", "src/util/helper_test.go"},
+		},
+		{
+			name:     "no implicit descendants",
+			patterns: []string{"src"},
+			options:  []glob.Option{glob.ImplicitDescendants(false)},
+			want:     []string{"src"},
+		},
+		{
+			name:     "no directories",
+			patterns: []string{"src"},
+			options:  []glob.Option{glob.MatchDirectories(false)},
+			want:     []string{"src/main.go", "src/util/helper.go", "src/util/helper_test.go"},
+		},
+		{
+			name:     "trailing separator",
+			patterns: []string{"*/"},
+			options:  []glob.Option{glob.ImplicitDescendants(false)},
+			want:     []string{"docs", "src", "vendor"},
+		},
+		{
+			name:     "hidden",
+			patterns: []string{"*"},
+			options:  []glob.Option{glob.ImplicitDescendants(false)},
+			want:     []string{".hidden", "a.txt", "b.go", "docs", "src", "vendor"},
+		},
+		{
+			name:     "exclude hidden",
+			patterns: []string{"*"},
+			options:  []glob.Option{glob.ImplicitDescendants(false), glob.ExcludeHidden(true)},
+			want:     []string{"a.txt", "b.go", "docs", "src", "vendor"},
+		},
+		{
+			name:     "character classes",
+			patterns: []string{"s?c/[mu]*"},
+			options:  []glob.Option{glob.ImplicitDescendants(false)},
+			want:     []string{"src/main.go", "src/util"},
+		},
+		{
+			name:     "absolute",
+			patterns: []string{filepath.Join(root, "a.txt")},
+			want:     []string{"a.txt"},
+		},
+		{
+			name:     "no matches",
+			patterns: []string{"missing/**", "*.rs"},
+			want:     []string{},
+		},
+		{
+			name:     "only negations",
+			patterns: []string{"!a.txt"},
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]glob.Option{glob.Dir(root)}, tt.options...)
+
+			got, err := glob.Glob(tt.patterns, options...)
+			test.Ok(t, err)
+			test.EqualFunc(t, relative(t, root, got), tt.want, slices.Equal)
+		})
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+	root := t.TempDir()
+
+	globber, err := glob.New(
+		[]string{"src/**/*.go", "src/util/*.go", "docs/*.md", "!vendor", "a/b\\*/c"},
+		glob.Dir(root),
+	)
+	test.Ok(t, err)
+
+	want := []string{filepath.Join(root, "src"), filepath.Join(root, "docs")}
+	if runtime.GOOS != "windows" {
+		// An escaped metacharacter is literal
+		want = append(want, filepath.Join(root, "a", "b*", "c"))
+	}
+
+	test.EqualFunc(t, globber.SearchPaths(), want, slices.Equal)
+}
+
+func TestMatch(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	globber, err := glob.New([]string{"**/*.go", "!**/*_test.go", "build/", "~/.cache/*"}, glob.Dir("/work"))
+	test.Ok(t, err)
+
+	test.True(t, globber.Match("main.go"))
+	test.True(t, globber.Match("/work/pkg/thing.go"))
+	test.False(t, globber.Match("pkg/thing_test.go"))
+	test.False(t, globber.Match("/elsewhere/main.go"))
+	test.True(t, globber.Match("build/"))
+	test.True(t, globber.Match("build/output.bin")) // Implicit descendant
+	test.True(t, globber.Match(filepath.Join(home, ".cache", "go-build")))
+
+	dirs, err := glob.New([]string{"build/"}, glob.Dir("/work"), glob.ImplicitDescendants(false))
+	test.Ok(t, err)
+
+	test.True(t, dirs.Match("build/"))
+	test.False(t, dirs.Match("build")) // Only matches directories
+}
+
+func TestSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on windows")
+	}
+
+	root := makeTree(t, "real/file.txt")
+
+	test.Ok(t, os.Symlink(filepath.Join(root, "real"), filepath.Join(root, "link")))
+	test.Ok(t, os.Symlink(filepath.Join(root, "real"), filepath.Join(root, "real", "loop")))
+	test.Ok(t, os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "broken")))
+
+	t.Run("follow", func(t *testing.T) {
+		got, err := glob.Glob([]string{"**/*.txt"}, glob.Dir(root))
+		test.Ok(t, err)
+
+		// The loop is detected rather than followed forever
+		test.EqualFunc(t, relative(t, root, got), []string{"link/file.txt", "real/file.txt"}, slices.Equal)
+	})
+
+	t.Run("no follow", func(t *testing.T) {
+		got, err := glob.Glob([]string{"**"}, glob.Dir(root), glob.FollowSymlinks(false))
+		test.Ok(t, err)
+
+		test.EqualFunc(
+			t,
+			relative(t, root, got),
+			[]string{".", "broken", "link", "real", "real/file.txt", "real/loop"},
+			slices.Equal,
+		)
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		_, err := glob.Glob([]string{"*"}, glob.Dir(root), glob.OmitBrokenSymlinks(false))
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "broken symlink "+filepath.Join(root, "broken"))
+	})
+}
+
+func TestAllStops(t *testing.T) {
+	root := makeTree(t, "a.txt", "b.txt", "c.txt")
+
+	globber, err := glob.New([]string{"*.txt"}, glob.Dir(root))
+	test.Ok(t, err)
+
+	var got []string
+
+	for path, err := range globber.All() {
+		test.Ok(t, err)
+
+		got = append(got, path)
+		if len(got) == 2 {
+			break
+		}
+	}
+
+	test.EqualFunc(t, relative(t, root, got), []string{"a.txt", "b.txt"}, slices.Equal)
+}
@@ -0,0 +1,154 @@
+package glob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// workspaceVar is the env var containing the path to the workspace, which [HashFiles]
+// resolves patterns against.
+const workspaceVar = "GITHUB_WORKSPACE"
+
+// HashFiles returns the same value as the hashFiles expression function in a workflow, so
+// that keys built in an action match those built in workflow YAML:
+//
+//	hash, err := glob.HashFiles(ctx, []string{"**/go.sum"})
+//	key := "go-" + runtime.GOOS + "-" + hash
+//
+// Patterns are resolved against $GITHUB_WORKSPACE and only files within it are hashed, the
+// result is the hex encoded SHA-256 of the SHA-256 of each matched file, in the order
+// [Globber.All] finds them. If nothing matches, the result is an empty string.
+//
+// Symlinks are not followed unless [FollowSymlinks] is passed, the equivalent of the
+// --follow-symbolic-links argument to hashFiles. Files are hashed in parallel.
+//
+// See https://docs.github.com/en/actions/reference/workflows-and-actions/expressions#hashfiles
+func HashFiles(ctx context.Context, patterns []string, options ...Option) (string, error) {
+	workspace := os.Getenv(workspaceVar)
+	if workspace == "" {
+		return "", fmt.Errorf("$%s is not set or is empty", workspaceVar)
+	}
+
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve $%s: %w", workspaceVar, err)
+	}
+
+	options = append([]Option{Dir(workspace), FollowSymlinks(false)}, options...)
+
+	globber, err := New(patterns, options...)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+
+	for path, err := range globber.All() {
+		if err != nil {
+			return "", err
+		}
+
+		if !strings.HasPrefix(path, workspace+string(filepath.Separator)) {
+			// The runner ignores anything outside the workspace
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("could not stat %s: %w", path, err)
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		files = append(files, path)
+	}
+
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	sums, err := hashAll(ctx, files)
+	if err != nil {
+		return "", err
+	}
+
+	combined := sha256.New()
+	for _, sum := range sums {
+		combined.Write(sum[:])
+	}
+
+	return hex.EncodeToString(combined.Sum(nil)), nil
+}
+
+// hashAll returns the SHA-256 of each of files, hashing them in parallel.
+func hashAll(ctx context.Context, files []string) ([][sha256.Size]byte, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	sums := make([][sha256.Size]byte, len(files))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(runtime.GOMAXPROCS(0), len(files)) {
+		wg.Go(func() {
+			for i := range indexes {
+				sum, err := hashFile(files[i])
+				if err != nil {
+					cancel(err)
+					return
+				}
+
+				sums[i] = sum
+			}
+		})
+	}
+
+send:
+	for i := range files {
+		select {
+		case <-ctx.Done():
+			break send
+		case indexes <- i:
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	return sums, nil
+}
+
+// hashFile returns the SHA-256 of the file at path.
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	file, err := os.Open(path)
+	if err != nil {
+		return sum, fmt.Errorf("could not hash %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return sum, fmt.Errorf("could not hash %s: %w", path, err)
+	}
+
+	hash.Sum(sum[:0])
+
+	return sum, nil
+}
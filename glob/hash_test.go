@@ -0,0 +1,102 @@
+package glob_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/actions/glob"
+	"go.followtheprocess.codes/test"
+)
+
+func TestHashFiles(t *testing.T) {
+	// Each file's contents is its path, see makeTree
+	root := makeTree(t, "a.txt", "src/b.go", "src/c.rs")
+	outside := makeTree(t, "outside.txt")
+
+	t.Setenv("GITHUB_WORKSPACE", root)
+
+	tests := []struct {
+		name     string
+		want     string
+		patterns []string
+	}{
+		{
+			name:     "matches",
+			patterns: []string{"a.txt", "**/*.go"},
+			want:     "15ca803cd1da7a77e5dd44f2b6b23a6b44d93f00b8d5ff4e6a5d8532341bf8a5",
+		},
+		{
+			name:     "pattern order",
+			patterns: []string{"src/*.go\na.txt"},
+			want:     "a18678309977c657acc0d970806f1357b523dcfaab25d8dae4a2dc7fbd145e6a",
+		},
+		{
+			name:     "directories skipped",
+			patterns: []string{"*", "!src/*.rs"},
+			want:     "15ca803cd1da7a77e5dd44f2b6b23a6b44d93f00b8d5ff4e6a5d8532341bf8a5",
+		},
+		{
+			name:     "outside workspace ignored",
+			patterns: []string{"a.txt", "src/b.go", filepath.Join(outside, "outside.txt")},
+			want:     "15ca803cd1da7a77e5dd44f2b6b23a6b44d93f00b8d5ff4e6a5d8532341bf8a5",
+		},
+		{
+			name:     "no matches",
+			patterns: []string{"**/*.py"},
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := glob.HashFiles(context.Background(), tt.patterns)
+			test.Ok(t, err)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestHashFilesWalkOrder(t *testing.T) {
+	// "-" sorts before "/" so sorting the paths would put foo-bar/go.sum first, but the
+	// runner walks foo before foo-bar
+	root := makeTree(t, "foo/go.sum", "foo-bar/go.sum")
+	t.Setenv("GITHUB_WORKSPACE", root)
+
+	combined := sha256.New()
+	for _, file := range []string{"foo/go.sum", "foo-bar/go.sum"} {
+		sum := sha256.Sum256([]byte(file))
+		combined.Write(sum[:])
+	}
+
+	got, err := glob.HashFiles(context.Background(), []string{"**/go.sum"})
+	test.Ok(t, err)
+	test.Equal(t, got, hex.EncodeToString(combined.Sum(nil)))
+}
+
+func TestHashFilesErrors(t *testing.T) {
+	t.Run("no workspace", func(t *testing.T) {
+		t.Setenv("GITHUB_WORKSPACE", "")
+
+		_, err := glob.HashFiles(context.Background(), []string{"*"})
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "$GITHUB_WORKSPACE is not set or is empty")
+	})
+
+	t.Run("unreadable", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can read anything")
+		}
+
+		root := makeTree(t, "secret.txt")
+		t.Setenv("GITHUB_WORKSPACE", root)
+
+		test.Ok(t, os.Chmod(filepath.Join(root, "secret.txt"), 0o000))
+
+		_, err := glob.HashFiles(context.Background(), []string{"*.txt"})
+		test.Err(t, err)
+	})
+}
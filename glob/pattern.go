@@ -0,0 +1,224 @@
+package glob
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// matchKind is the kind of filesystem item a pattern matches.
+type matchKind int
+
+const (
+	matchNone      matchKind = 0                          // Matches nothing
+	matchDirectory matchKind = 1                          // Matches directories
+	matchFile      matchKind = 2                          // Matches files
+	matchAll       matchKind = matchDirectory | matchFile // Matches anything
+)
+
+// pattern is a single compiled glob pattern.
+type pattern struct {
+	segments []string // Slash separated segments of the absolute pattern, the first being the root
+	negate   bool     // Whether the pattern is negated i.e. excludes matches
+	dirsOnly bool     // Whether the pattern had a trailing separator, so only matches directories
+}
+
+// parse compiles a single line into a pattern, resolving it against dir. Comments and
+// blank lines report ok as false.
+func parse(line, dir string) (p pattern, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	for strings.HasPrefix(line, "!") {
+		p.negate = !p.negate
+		line = strings.TrimSpace(line[1:])
+	}
+
+	if line == "" {
+		return pattern{}, false, nil
+	}
+
+	if line == "~" || strings.HasPrefix(line, "~/") || (windows() && strings.HasPrefix(line, `~\`)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return pattern{}, false, err
+		}
+
+		line = home + line[1:]
+	}
+
+	if windows() {
+		line = strings.ReplaceAll(line, `\`, "/")
+	}
+
+	p.dirsOnly = strings.HasSuffix(line, "/")
+
+	if !filepath.IsAbs(filepath.FromSlash(line)) {
+		line = filepath.ToSlash(dir) + "/" + line
+	}
+
+	p.segments = split(path.Clean(line))
+
+	return p, true, nil
+}
+
+// match reports the kind of item the segments of an absolute path match.
+func (p pattern) match(item []string) matchKind {
+	if !matchSegments(p.segments, item) {
+		return matchNone
+	}
+
+	if p.dirsOnly {
+		return matchDirectory
+	}
+
+	return matchAll
+}
+
+// partial reports whether the directory with the given segments could contain a match.
+func (p pattern) partial(item []string) bool {
+	segments := p.segments
+
+	for len(item) > 0 {
+		if len(segments) == 0 {
+			return false
+		}
+
+		if segments[0] == "**" {
+			return true
+		}
+
+		if !matchSegment(segments[0], item[0]) {
+			return false
+		}
+
+		segments, item = segments[1:], item[1:]
+	}
+
+	return true
+}
+
+// searchPath returns the longest literal prefix of the pattern, where searching for matches
+// should begin.
+func (p pattern) searchPath() string {
+	var literal []string
+
+	for _, segment := range p.segments {
+		if hasMeta(segment) {
+			break
+		}
+
+		literal = append(literal, unescape(segment))
+	}
+
+	joined := strings.Join(literal, "/")
+	if len(literal) == 1 {
+		// Just the root e.g. "/" or "C:/"
+		joined += "/"
+	}
+
+	return filepath.FromSlash(joined)
+}
+
+// descendants returns a pattern matching everything below the matches of p, or false if p
+// already matches everything below its matches.
+func (p pattern) descendants() (pattern, bool) {
+	if p.segments[len(p.segments)-1] == "**" && !p.dirsOnly {
+		return pattern{}, false
+	}
+
+	segments := make([]string, len(p.segments), len(p.segments)+1)
+	copy(segments, p.segments)
+
+	return pattern{segments: append(segments, "**"), negate: p.negate}, true
+}
+
+// matchSegments reports whether the segments of an item match the segments of a pattern,
+// where "**" matches zero or more segments.
+func matchSegments(pattern, item []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+
+			for i := range len(item) + 1 {
+				if matchSegments(pattern[1:], item[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(item) == 0 || !matchSegment(pattern[0], item[0]) {
+			return false
+		}
+
+		pattern, item = pattern[1:], item[1:]
+	}
+
+	return len(item) == 0
+}
+
+// matchSegment reports whether a single path segment matches a single pattern segment.
+func matchSegment(pattern, segment string) bool {
+	if windows() {
+		pattern, segment = strings.ToLower(pattern), strings.ToLower(segment)
+	}
+
+	matched, err := path.Match(pattern, segment)
+
+	return err == nil && matched
+}
+
+// split splits an absolute slash separated path into segments, the first of which is the root
+// e.g. "" for "/" or "C:" for "C:/".
+func split(slashed string) []string {
+	return strings.Split(strings.TrimSuffix(slashed, "/"), "/")
+}
+
+// hasMeta reports whether segment contains any unescaped glob metacharacters.
+func hasMeta(segment string) bool {
+	for i := 0; i < len(segment); i++ {
+		switch segment[i] {
+		case '\\':
+			if !windows() {
+				i++ // Skip the escaped character
+			}
+		case '*', '?', '[':
+			return true
+		}
+	}
+
+	return false
+}
+
+// unescape removes backslash escapes from a literal segment.
+func unescape(segment string) string {
+	if windows() || !strings.Contains(segment, `\`) {
+		return segment
+	}
+
+	s := &strings.Builder{}
+
+	for i := 0; i < len(segment); i++ {
+		if segment[i] == '\\' && i+1 < len(segment) {
+			i++
+		}
+
+		s.WriteByte(segment[i])
+	}
+
+	return s.String()
+}
+
+// windows reports whether paths are windows paths, which are matched case insensitively
+// and where a backslash is a separator rather than an escape.
+func windows() bool {
+	return runtime.GOOS == "windows"
+}
@@ -2,7 +2,10 @@ module go.followtheprocess.codes/actions
 
 go 1.26
 
-require go.followtheprocess.codes/test v1.4.0
+require (
+	go.followtheprocess.codes/test v1.4.0
+	go.yaml.in/yaml/v3 v3.0.5
+)
 
 require (
 	go.followtheprocess.codes/diff v0.2.0 // indirect
@@ -0,0 +1,286 @@
+package input
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+//nolint:gochecknoglobals // These are built once and reused.
+var (
+	// textUnmarshalerType is the type of [encoding.TextUnmarshaler], for checking whether a
+	// field decodes itself.
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+	// durationType is the type of [time.Duration], which is parsed rather than decoded as
+	// the int64 it's based on.
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// Decode populates the struct pointed to by v from the action's inputs.
+//
+// Fields are mapped to inputs with the "input" struct tag, the first element of which
+// is the name of the input as declared in action.yml. Fields without an "input" tag (or
+// tagged with "-") are ignored, with the exception of untagged struct fields which are
+// decoded recursively, allowing related inputs to be grouped together.
+//
+//	type Config struct {
+//		Token   string        `input:"token,required"`
+//		Timeout time.Duration `input:"timeout" default:"5m"`
+//		Level   string        `input:"level" default:"info" enum:"debug,info,warn,error"`
+//		Paths   []string      `input:"paths,lines"`
+//		Retries int           `input:"retries" default:"3"`
+//	}
+//
+// The following tags are supported:
+//
+//   - input:"name,required": The input must be provided (or have a default), otherwise it's an error
+//   - input:"name,lines": A slice field is split on newlines only, see [Lines]. By default slices are
+//     split on commas or newlines, see [List]
+//   - default:"value": The value to use if the input is not provided or is empty
+//   - enum:"a,b,c": The input must be one of the listed values
+//
+// Supported field types are string, bool, all integer and float types, [time.Duration], any
+// type implementing [encoding.TextUnmarshaler], pointers to any of these and slices of any of
+// these, where each item in the list is decoded individually.
+//
+// Slices of slices e.g. [][]string are also supported, each line of the input is one inner
+// slice and is split on commas:
+//
+//	matrix: |
+//	  ubuntu-latest, 1.25
+//	  macos-latest, 1.26
+//
+// Slices nested any deeper than that are not supported.
+//
+// As with [Lines] and [List], blank lines and empty items are kept rather than dropped: they
+// decode as "" in a []string and as an empty inner slice in a [][]string, and are invalid
+// for types that can't be decoded from an empty string such as int.
+//
+// Decode does not stop at the first bad input, the returned error names every input that
+// was missing or invalid.
+func Decode(v any) error {
+	return decoder{lookup: Get}.decode(v)
+}
+
+// decoder implements [Decode] and [Source.Decode].
+type decoder struct {
+	lookup   func(name string) (value string, ok bool) // Gets the value of the named input
+	required func(name string) bool                    // Reports whether the named input is always required, may be nil
+}
+
+// decode decodes the inputs into v, which must be a pointer to a struct.
+func (d decoder) decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("input.Decode requires a non-nil pointer to a struct, got %T", v)
+	}
+
+	var errs []error
+
+	d.decodeStruct(rv.Elem(), &errs)
+
+	return errors.Join(errs...)
+}
+
+// decodeStruct decodes each tagged field of the struct sv, appending any errors to errs.
+func (d decoder) decodeStruct(sv reflect.Value, errs *[]error) {
+	typ := sv.Type()
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("input")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct && !reflect.PointerTo(field.Type).Implements(textUnmarshalerType) {
+				d.decodeStruct(sv.Field(i), errs)
+			}
+
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			*errs = append(*errs, fmt.Errorf("field %s has no input name in its tag", field.Name))
+			continue
+		}
+
+		var required, lines bool
+
+		for option := range strings.SplitSeq(options, ",") {
+			switch option {
+			case "required":
+				required = true
+			case "lines":
+				lines = true
+			}
+		}
+
+		if d.required != nil && d.required(name) {
+			required = true
+		}
+
+		value, _ := d.lookup(name)
+		if value == "" {
+			value = field.Tag.Get("default")
+		}
+
+		if value == "" {
+			if required {
+				*errs = append(*errs, fmt.Errorf("input variable %q is required but not set", name))
+			}
+
+			continue
+		}
+
+		var allowed []string
+		if enum, ok := field.Tag.Lookup("enum"); ok {
+			allowed = strings.Split(enum, ",")
+		}
+
+		if err := setField(sv.Field(i), name, value, lines, allowed); err != nil {
+			*errs = append(*errs, err)
+		}
+	}
+}
+
+// setField sets the field fv from the raw value of the input name.
+//
+// Slices (other than those implementing [encoding.TextUnmarshaler] themselves) are split
+// into items, each of which is decoded with [setValue]. Slices of slices are split into
+// lines, each of which is split again on commas.
+func setField(fv reflect.Value, name, value string, lines bool, allowed []string) error {
+	if !isSlice(fv.Type()) {
+		return setValue(fv, name, value, allowed)
+	}
+
+	nested := isSlice(fv.Type().Elem())
+	if nested && isSlice(fv.Type().Elem().Elem()) {
+		return fmt.Errorf("input variable %q has unsupported type %s", name, fv.Type())
+	}
+
+	split := parseList
+	if lines || nested {
+		split = parseLines
+	}
+
+	items, err := split(value)
+	if err != nil {
+		return fmt.Errorf("input variable %q is invalid: %w", name, err)
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+
+	var errs []error
+
+	for i, item := range items {
+		if nested {
+			err = setField(slice.Index(i), name, item, false, allowed)
+		} else {
+			err = setValue(slice.Index(i), name, item, allowed)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	fv.Set(slice)
+
+	return nil
+}
+
+// isSlice reports whether typ is a slice that is split into items, rather than one that
+// implements [encoding.TextUnmarshaler] itself.
+func isSlice(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && !reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// setValue sets the single value fv from the raw value of the input name.
+func setValue(fv reflect.Value, name, value string, allowed []string) error {
+	if len(allowed) != 0 && !slices.Contains(allowed, value) {
+		return fmt.Errorf("input variable %q is invalid: %q is not one of %s", name, value, strings.Join(allowed, ", "))
+	}
+
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), name, value, nil); err != nil {
+			return err
+		}
+
+		fv.Set(ptr)
+
+		return nil
+	}
+
+	if unmarshaler, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("input variable %q is invalid: %w", name, err)
+		}
+
+		return nil
+	}
+
+	if fv.Type() == durationType {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("input variable %q is invalid duration: %q", name, value)
+		}
+
+		fv.SetInt(int64(duration))
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		val, err := parseBool(name, value)
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, err := parseInt(name, value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(val)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val, err := parseUint(name, value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(val)
+	case reflect.Float32, reflect.Float64:
+		val, err := parseFloat(name, value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(val)
+	default:
+		return fmt.Errorf("input variable %q has unsupported type %s", name, fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package input_test
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/input"
+	"go.followtheprocess.codes/test"
+)
+
+// Level is an enum implementing encoding.TextUnmarshaler.
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelDebug
+)
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*l = LevelInfo
+	case "debug":
+		*l = LevelDebug
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+
+	return nil
+}
+
+// Retry is a nested group of inputs.
+type Retry struct {
+	Attempts uint          `input:"retry-attempts" default:"3"`
+	Delay    time.Duration `input:"retry-delay"    default:"10s"`
+}
+
+type Config struct {
+	Optional *int            `input:"optional"`
+	Number   *int            `input:"number"`
+	Token    string          `input:"token,required"`
+	Mode     string          `input:"mode"           default:"fast" enum:"fast,slow"`
+	Ignored  string          `input:"-"`
+	Addrs    []netip.Addr    `input:"addrs"`
+	Ports    []int           `input:"ports"`
+	Timeouts []time.Duration `input:"timeouts"`
+	Tags     []string        `input:"tags"`
+	Paths    []string        `input:"paths,lines"`
+	Retry
+	Timeout time.Duration `input:"timeout" default:"1m"`
+	Ratio   float64       `input:"ratio"`
+	Level   Level         `input:"level"   default:"info"`
+	Count   int8          `input:"count"`
+	Debug   bool          `input:"debug"   default:"false"`
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("INPUT_TOKEN", " secret ")
+		t.Setenv("INPUT_PATHS", "src/a, b.go\nsrc/c.go")
+		t.Setenv("INPUT_TAGS", "one,two\nthree")
+		t.Setenv("INPUT_PORTS", "80, 443")
+		t.Setenv("INPUT_TIMEOUTS", "1s,2m")
+		t.Setenv("INPUT_ADDRS", "127.0.0.1,::1")
+		t.Setenv("INPUT_RETRY-DELAY", "1s")
+		t.Setenv("INPUT_RATIO", "0.5")
+		t.Setenv("INPUT_LEVEL", "debug")
+		t.Setenv("INPUT_COUNT", "-12")
+		t.Setenv("INPUT_DEBUG", "true")
+		t.Setenv("INPUT_NUMBER", "42")
+		t.Setenv("INPUT_OPTIONAL", "") // Declared but not provided
+		t.Setenv("INPUT_-", "nope")
+
+		var cfg Config
+
+		err := input.Decode(&cfg)
+		test.Ok(t, err)
+
+		test.Equal(t, cfg.Token, "secret")
+		test.Equal(t, cfg.Mode, "fast")
+		test.EqualFunc(t, cfg.Paths, []string{"src/a, b.go", "src/c.go"}, slices.Equal)
+		test.EqualFunc(t, cfg.Tags, []string{"one", "two", "three"}, slices.Equal)
+		test.EqualFunc(t, cfg.Ports, []int{80, 443}, slices.Equal)
+		test.EqualFunc(t, cfg.Timeouts, []time.Duration{time.Second, 2 * time.Minute}, slices.Equal)
+		test.EqualFunc(t, cfg.Addrs, []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.IPv6Loopback()}, slices.Equal)
+		test.Equal(t, cfg.Ignored, "")
+		test.Equal(t, cfg.Attempts, 3)
+		test.Equal(t, cfg.Delay, time.Second)
+		test.Equal(t, cfg.Timeout, time.Minute)
+		test.Equal(t, cfg.Ratio, 0.5)
+		test.Equal(t, cfg.Level, LevelDebug)
+		test.Equal(t, cfg.Count, -12)
+		test.True(t, cfg.Debug)
+		test.True(t, cfg.Optional == nil)
+		test.True(t, cfg.Number != nil)
+		test.Equal(t, *cfg.Number, 42)
+	})
+
+	t.Run("aggregates errors", func(t *testing.T) {
+		t.Setenv("INPUT_MODE", "medium")
+		t.Setenv("INPUT_PORTS", "80,http")
+		t.Setenv("INPUT_TIMEOUT", "soon")
+		t.Setenv("INPUT_LEVEL", "trace")
+		t.Setenv("INPUT_COUNT", "1000")
+		t.Setenv("INPUT_DEBUG", "yes")
+		t.Setenv("INPUT_RETRY-ATTEMPTS", "-1")
+
+		var cfg Config
+
+		err := input.Decode(&cfg)
+		test.Err(t, err)
+
+		want := []string{
+			`input variable "token" is required but not set`,
+			`input variable "mode" is invalid: "medium" is not one of fast, slow`,
+			`input variable "ports" is invalid integer: "http"`,
+			`input variable "retry-attempts" is invalid integer: "-1"`,
+			`input variable "timeout" is invalid duration: "soon"`,
+			`input variable "level" is invalid: unknown level "trace"`,
+			`input variable "count" is invalid integer: "1000"`,
+			`input variable "debug" is invalid bool: "yes"`,
+		}
+
+		test.Equal(t, err.Error(), strings.Join(want, "\n"))
+	})
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		var cfg Config
+
+		test.Err(t, input.Decode(cfg))
+		test.Err(t, input.Decode((*Config)(nil)))
+
+		s := "hello"
+		test.Err(t, input.Decode(&s))
+	})
+
+	t.Run("nested slices", func(t *testing.T) {
+		t.Setenv("INPUT_MATRIX", "ubuntu-latest, 1.25\nmacos-latest,1.26\nwindows-latest")
+		t.Setenv("INPUT_PORTS", "80, 443\n8080")
+
+		var cfg struct {
+			Matrix [][]string `input:"matrix"`
+			Ports  [][]int    `input:"ports"`
+		}
+
+		err := input.Decode(&cfg)
+		test.Ok(t, err)
+
+		want := [][]string{{"ubuntu-latest", "1.25"}, {"macos-latest", "1.26"}, {"windows-latest"}}
+		test.EqualFunc(t, cfg.Matrix, want, func(a, b [][]string) bool {
+			return slices.EqualFunc(a, b, slices.Equal)
+		})
+		test.EqualFunc(t, cfg.Ports, [][]int{{80, 443}, {8080}}, func(a, b [][]int) bool {
+			return slices.EqualFunc(a, b, slices.Equal)
+		})
+	})
+
+	t.Run("nested slices invalid", func(t *testing.T) {
+		t.Setenv("INPUT_PORTS", "80, http")
+		t.Setenv("INPUT_DEEP", "a")
+
+		var cfg struct {
+			Ports [][]int      `input:"ports"`
+			Deep  [][][]string `input:"deep"`
+		}
+
+		err := input.Decode(&cfg)
+		test.Err(t, err)
+
+		want := []string{
+			`input variable "ports" is invalid integer: "http"`,
+			`input variable "deep" has unsupported type [][][]string`,
+		}
+
+		test.Equal(t, err.Error(), strings.Join(want, "\n"))
+	})
+
+	t.Run("blank items kept", func(t *testing.T) {
+		t.Setenv("INPUT_TAGS", "one,,two")
+		t.Setenv("INPUT_PATHS", "a\n\nb")
+		t.Setenv("INPUT_MATRIX", "ubuntu-latest\n\nmacos-latest")
+
+		var cfg struct {
+			Tags   []string   `input:"tags"`
+			Paths  []string   `input:"paths,lines"`
+			Matrix [][]string `input:"matrix"`
+		}
+
+		err := input.Decode(&cfg)
+		test.Ok(t, err)
+
+		test.EqualFunc(t, cfg.Tags, []string{"one", "", "two"}, slices.Equal)
+		test.EqualFunc(t, cfg.Paths, []string{"a", "", "b"}, slices.Equal)
+		test.EqualFunc(t, cfg.Matrix, [][]string{{"ubuntu-latest"}, {}, {"macos-latest"}}, func(a, b [][]string) bool {
+			return slices.EqualFunc(a, b, slices.Equal)
+		})
+	})
+
+	t.Run("blank items invalid", func(t *testing.T) {
+		t.Setenv("INPUT_PORTS", "80,,443")
+
+		var cfg struct {
+			Ports []int `input:"ports"`
+		}
+
+		err := input.Decode(&cfg)
+		test.Err(t, err)
+		test.Equal(t, err.Error(), `input variable "ports" is invalid integer: ""`)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		t.Setenv("INPUT_THING", "value")
+
+		var cfg struct {
+			Thing map[string]string `input:"thing"`
+		}
+
+		err := input.Decode(&cfg)
+		test.Err(t, err)
+		test.Equal(t, err.Error(), `input variable "thing" has unsupported type map[string]string`)
+	})
+}
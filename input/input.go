@@ -44,6 +44,11 @@ func Bool(name string) (bool, error) {
 		return false, fmt.Errorf("input variable %q not defined", name)
 	}
 
+	return parseBool(name, value)
+}
+
+// parseBool parses the value of the input variable name as a bool, see [Bool].
+func parseBool(name, value string) (bool, error) {
 	switch value {
 	case "true", "True", "TRUE":
 		return true, nil
@@ -66,6 +71,11 @@ func Lines(name string) ([]string, error) {
 		return nil, fmt.Errorf("input variable %q not defined", name)
 	}
 
+	return parseLines(value)
+}
+
+// parseLines splits value into lines, see [Lines].
+func parseLines(value string) ([]string, error) {
 	scanner := bufio.NewScanner(strings.NewReader(value))
 	scanner.Split(bufio.ScanLines)
 
@@ -93,7 +103,27 @@ func Int(name string) (int, error) {
 		return 0, fmt.Errorf("input variable %q not defined", name)
 	}
 
-	val, err := strconv.Atoi(value)
+	val, err := parseInt(name, value, strconv.IntSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(val), nil
+}
+
+// parseInt parses the value of the input variable name as an integer of the given bit size.
+func parseInt(name, value string, bitSize int) (int64, error) {
+	val, err := strconv.ParseInt(value, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("input variable %q is invalid integer: %q", name, value)
+	}
+
+	return val, nil
+}
+
+// parseUint parses the value of the input variable name as an unsigned integer of the given bit size.
+func parseUint(name, value string, bitSize int) (uint64, error) {
+	val, err := strconv.ParseUint(value, 10, bitSize)
 	if err != nil {
 		return 0, fmt.Errorf("input variable %q is invalid integer: %q", name, value)
 	}
@@ -111,7 +141,12 @@ func Float(name string) (float64, error) {
 		return 0, fmt.Errorf("input variable %q not defined", name)
 	}
 
-	val, err := strconv.ParseFloat(value, 64)
+	return parseFloat(name, value, 64)
+}
+
+// parseFloat parses the value of the input variable name as a float of the given bit size.
+func parseFloat(name, value string, bitSize int) (float64, error) {
+	val, err := strconv.ParseFloat(value, bitSize)
 	if err != nil {
 		return 0, fmt.Errorf("input variable %q is invalid float: %q", name, value)
 	}
@@ -131,6 +166,11 @@ func List(name string) ([]string, error) {
 		return nil, fmt.Errorf("input variable %q not defined", name)
 	}
 
+	return parseList(value)
+}
+
+// parseList splits value into comma or line separated items, see [List].
+func parseList(value string) ([]string, error) {
 	scanner := bufio.NewScanner(strings.NewReader(value))
 	scanner.Split(scanItems)
 
@@ -0,0 +1,201 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/actions/metadata"
+)
+
+// Source gets action inputs like the package level functions, but additionally applies the
+// rules declared for each input in the action's metadata file.
+//
+// When the action runs on GitHub, the runner applies the defaults from action.yml before
+// setting the INPUT_ environment variables, but when running the action binary locally or
+// from a composite action, this doesn't happen. A Source fills that gap:
+//
+//   - Inputs not supplied (or supplied as empty) fall back to their declared default
+//   - Inputs declared with required: true that have no value (and no default) are an error
+//   - Supplying an input that declares a deprecationMessage logs a warning, once per input
+//
+// Inputs are matched to their declaration case insensitively, just as GitHub does.
+type Source struct {
+	inputs map[string]metadata.Input // Declared inputs keyed by lower cased id
+	warned map[string]bool           // Deprecated inputs already warned about
+	logger log.Logger                // Logger used for deprecation warnings
+	mu     sync.Mutex                // Protects warned
+}
+
+// NewSource returns a new [Source] applying the rules declared in action, logging any deprecation
+// warnings to logger.
+func NewSource(action metadata.Action, logger log.Logger) *Source {
+	inputs := make(map[string]metadata.Input, len(action.Inputs))
+	for id, input := range action.Inputs {
+		inputs[strings.ToLower(id)] = input
+	}
+
+	return &Source{
+		inputs: inputs,
+		warned: make(map[string]bool),
+		logger: logger,
+	}
+}
+
+// Get gets the value of an actions input variable, like the package level [Get].
+//
+// If the input was not supplied or is empty, the default declared in the metadata is returned
+// instead, with ok reporting whether the resulting value is non-empty. Required inputs are not
+// checked, use [Source.Check] or one of the typed getters for that.
+//
+// If the caller has supplied a deprecated input, a warning containing its deprecation message is
+// logged the first time it is looked up. A supplied value equal to the declared default is not
+// considered to be supplied, as the runner sets this for every input the caller omits.
+func (s *Source) Get(name string) (value string, ok bool) {
+	value, _ = Get(name)
+
+	declared, isDeclared := s.inputs[strings.ToLower(name)]
+	if !isDeclared {
+		return value, value != ""
+	}
+
+	if value == "" {
+		return declared.Default, declared.Default != ""
+	}
+
+	if declared.Deprecated() && value != declared.Default {
+		s.warn(name, declared.DeprecationMessage)
+	}
+
+	return value, true
+}
+
+// Check checks that every input declared with required: true has a value, returning an error
+// naming each one that doesn't.
+func (s *Source) Check() error {
+	var missing []string
+
+	for id, declared := range s.inputs {
+		if !declared.Required {
+			continue
+		}
+
+		if _, ok := s.Get(id); !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	slices.Sort(missing)
+
+	errs := make([]error, 0, len(missing))
+	for _, name := range missing {
+		errs = append(errs, fmt.Errorf("input variable %q is required but not set", name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Bool gets the boolean value of an actions input variable, see [Bool].
+func (s *Source) Bool(name string) (bool, error) {
+	value, err := s.value(name)
+	if err != nil {
+		return false, err
+	}
+
+	return parseBool(name, value)
+}
+
+// Lines gets the values of a multiline actions input variable, see [Lines].
+func (s *Source) Lines(name string) ([]string, error) {
+	value, err := s.value(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLines(value)
+}
+
+// Int gets the integer value of an actions input variable, see [Int].
+func (s *Source) Int(name string) (int, error) {
+	value, err := s.value(name)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := parseInt(name, value, strconv.IntSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(val), nil
+}
+
+// Float gets the float value of an actions input variable, see [Float].
+func (s *Source) Float(name string) (float64, error) {
+	value, err := s.value(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseFloat(name, value, 64)
+}
+
+// List fetches input given as a list of comma-separated or line-separated values, see [List].
+func (s *Source) List(name string) ([]string, error) {
+	value, err := s.value(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseList(value)
+}
+
+// Decode populates the struct pointed to by v from the action's inputs, see [Decode].
+//
+// Values are looked up with [Source.Get], so declared defaults apply and inputs declared
+// as required in the metadata are treated as if they were tagged required.
+func (s *Source) Decode(v any) error {
+	return decoder{lookup: s.Get, required: s.required}.decode(v)
+}
+
+// value gets the value of the named input, returning an error if it has no value.
+func (s *Source) value(name string) (string, error) {
+	value, ok := s.Get(name)
+	if ok {
+		return value, nil
+	}
+
+	if s.required(name) {
+		return "", fmt.Errorf("input variable %q is required but not set", name)
+	}
+
+	if _, defined := Get(name); defined {
+		// Defined but empty, which the package level functions also pass through
+		return value, nil
+	}
+
+	return "", fmt.Errorf("input variable %q not defined", name)
+}
+
+// required reports whether the named input is declared with required: true.
+func (s *Source) required(name string) bool {
+	return s.inputs[strings.ToLower(name)].Required
+}
+
+// warn logs a deprecation warning for the named input, if it hasn't already been warned about.
+func (s *Source) warn(name, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if s.warned[key] {
+		return
+	}
+
+	s.warned[key] = true
+	s.logger.Warning(fmt.Sprintf("Input '%s' has been deprecated with message: %s", name, message))
+}
@@ -0,0 +1,151 @@
+package input_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/input"
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/actions/metadata"
+	"go.followtheprocess.codes/test"
+)
+
+const actionYML = `name: Test
+description: Test action
+inputs:
+  token:
+    description: Required token
+    required: true
+  Retries:
+    description: Number of retries
+    default: "3"
+  paths:
+    description: Paths to check
+    default: |
+      src
+      docs
+  level:
+    description: Log level
+    default: info
+    deprecationMessage: Use verbosity instead
+  verbose:
+    description: Verbose output
+    required: true
+    default: "false"
+  optional:
+    description: Has no default
+runs:
+  using: node24
+  main: index.js
+`
+
+// newSource returns an input.Source for actionYML, logging to the returned buffer.
+func newSource(t *testing.T) (*input.Source, *bytes.Buffer) {
+	t.Helper()
+
+	action, err := metadata.Parse("action.yml", strings.NewReader(actionYML))
+	test.Ok(t, err)
+
+	buf := &bytes.Buffer{}
+
+	return input.NewSource(action, log.New(buf)), buf
+}
+
+func TestSourceDefaults(t *testing.T) {
+	t.Setenv("INPUT_TOKEN", "secret")
+	t.Setenv("INPUT_PATHS", "") // Declared by the runner but empty
+
+	source, buf := newSource(t)
+
+	token, ok := source.Get("token")
+	test.True(t, ok)
+	test.Equal(t, token, "secret")
+
+	retries, err := source.Int("retries")
+	test.Ok(t, err)
+	test.Equal(t, retries, 3)
+
+	paths, err := source.Lines("paths")
+	test.Ok(t, err)
+	test.EqualFunc(t, paths, []string{"src", "docs"}, slices.Equal)
+
+	verbose, err := source.Bool("verbose")
+	test.Ok(t, err)
+	test.False(t, verbose)
+
+	level, ok := source.Get("level")
+	test.True(t, ok)
+	test.Equal(t, level, "info")
+
+	_, ok = source.Get("optional")
+	test.False(t, ok)
+
+	_, err = source.List("optional")
+	test.Err(t, err)
+	test.Equal(t, err.Error(), `input variable "optional" not defined`)
+
+	test.Ok(t, source.Check())
+
+	// Nothing deprecated was supplied
+	test.Equal(t, buf.String(), "")
+}
+
+func TestSourceRequired(t *testing.T) {
+	source, _ := newSource(t)
+
+	_, err := source.Float("token")
+	test.Err(t, err)
+	test.Equal(t, err.Error(), `input variable "token" is required but not set`)
+
+	// verbose is also required but has a default so is fine
+	err = source.Check()
+	test.Err(t, err)
+	test.Equal(t, err.Error(), `input variable "token" is required but not set`)
+}
+
+func TestSourceDeprecated(t *testing.T) {
+	t.Setenv("INPUT_TOKEN", "secret")
+	t.Setenv("INPUT_LEVEL", "debug")
+
+	source, buf := newSource(t)
+
+	for range 3 {
+		level, ok := source.Get("level")
+		test.True(t, ok)
+		test.Equal(t, level, "debug")
+	}
+
+	// Only warned once
+	test.Equal(t, buf.String(), "::warning::Input 'level' has been deprecated with message: Use verbosity instead\n")
+}
+
+func TestSourceDecode(t *testing.T) {
+	t.Setenv("INPUT_PATHS", "a\nb")
+
+	source, _ := newSource(t)
+
+	var cfg struct {
+		Token   string   `input:"token"`
+		Level   string   `input:"level"`
+		Paths   []string `input:"paths,lines"`
+		Retries int      `input:"retries"`
+		Verbose bool     `input:"verbose"`
+	}
+
+	err := source.Decode(&cfg)
+	test.Err(t, err)
+	test.Equal(t, err.Error(), `input variable "token" is required but not set`)
+
+	t.Setenv("INPUT_TOKEN", "secret")
+
+	err = source.Decode(&cfg)
+	test.Ok(t, err)
+
+	test.Equal(t, cfg.Token, "secret")
+	test.Equal(t, cfg.Level, "info")
+	test.EqualFunc(t, cfg.Paths, []string{"a", "b"}, slices.Equal)
+	test.Equal(t, cfg.Retries, 3)
+	test.False(t, cfg.Verbose)
+}
@@ -0,0 +1,189 @@
+// Package blob uploads and downloads Azure block blobs through the pre-signed URLs handed
+// out by the v2 cache service and v4 artifacts.
+//
+// Only the small subset of the Blob service REST API needed for this is implemented, uploads
+// are staged as a sequence of blocks and then committed with a block list so that arbitrarily
+// large streams can be uploaded without knowing their size in advance.
+//
+// See https://learn.microsoft.com/en-us/rest/api/storageservices/blob-service-rest-api
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// version is the Blob service API version requested.
+const version = "2023-11-03"
+
+// Error is returned when the Blob service responds with an unsuccessful status code.
+type Error struct {
+	Operation  string // The operation that failed e.g. "upload block"
+	Message    string // The body of the response, which describes the error
+	StatusCode int    // The HTTP status code of the response
+}
+
+// Error implements the error interface for [Error].
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("blob %s failed with status %d", e.Operation, e.StatusCode)
+	}
+
+	return fmt.Sprintf("blob %s failed with status %d: %s", e.Operation, e.StatusCode, e.Message)
+}
+
+// Upload uploads everything read from r to the block blob at the pre-signed URL signed,
+// in blocks of at most blockSize bytes, returning the number of bytes uploaded.
+//
+// The blob is only created once every block has been uploaded and committed, so a failed
+// upload never leaves behind a partial blob.
+func Upload(ctx context.Context, client *http.Client, signed string, r io.Reader, blockSize int) (int64, error) {
+	if blockSize <= 0 {
+		return 0, fmt.Errorf("invalid block size %d", blockSize)
+	}
+
+	var (
+		ids   []string
+		total int64
+	)
+
+	buf := make([]byte, blockSize)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			id := base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "block-%08d", len(ids)))
+
+			target, urlErr := withQuery(signed, "comp", "block", "blockid", id)
+			if urlErr != nil {
+				return total, urlErr
+			}
+
+			if putErr := put(ctx, client, "upload block", target, "application/octet-stream", buf[:n]); putErr != nil {
+				return total, putErr
+			}
+
+			ids = append(ids, id)
+			total += int64(n)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			return total, fmt.Errorf("could not read upload: %w", err)
+		}
+	}
+
+	list := struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string `xml:"Latest"`
+	}{Latest: ids}
+
+	body, err := xml.Marshal(list)
+	if err != nil {
+		return total, fmt.Errorf("could not encode block list: %w", err)
+	}
+
+	target, err := withQuery(signed, "comp", "blocklist")
+	if err != nil {
+		return total, err
+	}
+
+	body = append([]byte(xml.Header), body...)
+
+	if err := put(ctx, client, "commit block list", target, "application/xml", body); err != nil {
+		return total, err
+	}
+
+	return total, nil
+}
+
+// Download copies the contents of the blob at the pre-signed URL signed to w, returning
+// the number of bytes copied.
+func Download(ctx context.Context, client *http.Client, signed string, w io.Writer) (int64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, signed, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not create download request: %w", err)
+	}
+
+	request.Header.Set("X-Ms-Version", version)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("could not download blob: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, newError("download", response)
+	}
+
+	n, err := io.Copy(w, response.Body)
+	if err != nil {
+		return n, fmt.Errorf("could not download blob: %w", err)
+	}
+
+	return n, nil
+}
+
+// put makes a PUT request with body to target, expecting 201 Created.
+func put(ctx context.Context, client *http.Client, operation, target, contentType string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create %s request: %w", operation, err)
+	}
+
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("X-Ms-Version", version)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not %s: %w", operation, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return newError(operation, response)
+	}
+
+	return nil
+}
+
+// newError builds an [*Error] from an unsuccessful response.
+func newError(operation string, response *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+	return &Error{
+		Operation:  operation,
+		Message:    strings.TrimSpace(string(body)),
+		StatusCode: response.StatusCode,
+	}
+}
+
+// withQuery returns signed with the additional query parameters in pairs set, leaving the
+// existing signature parameters alone.
+func withQuery(signed string, pairs ...string) (string, error) {
+	target, err := url.Parse(signed)
+	if err != nil {
+		return "", fmt.Errorf("invalid blob URL: %w", err)
+	}
+
+	query := target.Query()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		query.Set(pairs[i], pairs[i+1])
+	}
+
+	target.RawQuery = query.Encode()
+
+	return target.String(), nil
+}
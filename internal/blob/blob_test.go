@@ -0,0 +1,100 @@
+package blob_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.followtheprocess.codes/actions/internal/blob"
+	"go.followtheprocess.codes/test"
+)
+
+// fakeBlob is a single Azure block blob, accessible with the signature "sig=abc".
+type fakeBlob struct {
+	blocks map[string][]byte
+	data   []byte
+	mu     sync.Mutex
+}
+
+func (f *fakeBlob) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Query().Get("sig") != "abc" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("AuthenticationFailed"))
+
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		w.Write(f.data)
+	case r.URL.Query().Get("comp") == "block":
+		f.blocks[r.URL.Query().Get("blockid")], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case r.URL.Query().Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+
+		if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.data = nil
+		for _, id := range list.Latest {
+			f.data = append(f.data, f.blocks[id]...)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestUploadDownload(t *testing.T) {
+	fake := &fakeBlob{blocks: make(map[string][]byte)}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	contents := strings.Repeat("hello blob ", 100)
+
+	n, err := blob.Upload(t.Context(), server.Client(), server.URL+"/container/name?sig=abc", strings.NewReader(contents), 64)
+	test.Ok(t, err)
+	test.Equal(t, n, int64(len(contents)))
+	test.Equal(t, len(fake.blocks), 18) // 1100 bytes in blocks of 64
+
+	buf := &bytes.Buffer{}
+
+	n, err = blob.Download(t.Context(), server.Client(), server.URL+"/container/name?sig=abc", buf)
+	test.Ok(t, err)
+	test.Equal(t, n, int64(len(contents)))
+	test.Equal(t, buf.String(), contents)
+}
+
+func TestUploadError(t *testing.T) {
+	server := httptest.NewServer(&fakeBlob{blocks: make(map[string][]byte)})
+	defer server.Close()
+
+	_, err := blob.Upload(t.Context(), server.Client(), server.URL+"/container/name?sig=wrong", strings.NewReader("data"), 64)
+	test.Err(t, err)
+	test.Equal(t, err.Error(), "blob upload block failed with status 403: AuthenticationFailed")
+
+	var blobErr *blob.Error
+
+	test.True(t, errors.As(err, &blobErr))
+	test.Equal(t, blobErr.StatusCode, http.StatusForbidden)
+
+	_, err = blob.Download(t.Context(), server.Client(), server.URL+"/container/name?sig=wrong", io.Discard)
+	test.Err(t, err)
+	test.Equal(t, err.Error(), "blob download failed with status 403: AuthenticationFailed")
+}
@@ -0,0 +1,118 @@
+// Package filecmd implements the format of the runner's file commands, the files such as
+// $GITHUB_ENV, $GITHUB_OUTPUT and $GITHUB_STATE that an action appends key value pairs to
+// and the runner reads back once the step has finished.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#environment-files
+package filecmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// delimiterPrefix is the prefix of the random delimiters used for multi-line values,
+// the same as the actions toolkit.
+const delimiterPrefix = "ghadelimiter_"
+
+// Format returns the file command setting key to value, including the trailing newline.
+//
+// If the value contains newlines, the heredoc style "key<<delimiter" syntax is used with
+// a randomly generated delimiter, minimising the chance of collision with the contents.
+func Format(key, value string) string {
+	if !strings.Contains(value, "\n") {
+		return key + "=" + value + "\n"
+	}
+
+	delimiter := delimiterPrefix + rand.Text()
+
+	return key + "<<" + delimiter + "\n" + value + "\n" + delimiter + "\n"
+}
+
+// Parse parses the file commands in r the same way the runner does, returning the value
+// of each key. If a key is set more than once, the last value wins.
+//
+// Both the "key=value" and the heredoc style "key<<delimiter" syntax are supported,
+// and blank lines are ignored.
+func Parse(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	reader := bufio.NewReader(r)
+	number := 0
+
+	// next returns the next line without its line ending, and whether there was one
+	next := func() (string, bool, error) {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", false, err
+		}
+
+		if line == "" && errors.Is(err, io.EOF) {
+			return "", false, nil
+		}
+
+		number++
+
+		return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true, nil
+	}
+
+	for {
+		line, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return values, nil
+		}
+
+		if line == "" {
+			continue
+		}
+
+		equals := strings.Index(line, "=")
+		heredoc := strings.Index(line, "<<")
+
+		switch {
+		case equals >= 0 && (heredoc < 0 || equals < heredoc):
+			key := line[:equals]
+			if key == "" {
+				return nil, fmt.Errorf("line %d: invalid format %q, key must not be empty", number, line)
+			}
+
+			values[key] = line[equals+1:]
+		case heredoc >= 0:
+			start := number
+
+			key, delimiter := line[:heredoc], line[heredoc+2:]
+			if key == "" || delimiter == "" {
+				return nil, fmt.Errorf("line %d: invalid format %q, key and delimiter must not be empty", number, line)
+			}
+
+			var lines []string
+
+			for {
+				line, ok, err = next()
+				if err != nil {
+					return nil, err
+				}
+
+				if !ok {
+					return nil, fmt.Errorf("line %d: matching delimiter %q for %s not found", start, delimiter, key)
+				}
+
+				if line == delimiter {
+					break
+				}
+
+				lines = append(lines, line)
+			}
+
+			values[key] = strings.Join(lines, "\n")
+		default:
+			return nil, fmt.Errorf("line %d: invalid format %q", number, line)
+		}
+	}
+}
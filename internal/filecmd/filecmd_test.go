@@ -0,0 +1,115 @@
+package filecmd_test
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/internal/filecmd"
+	"go.followtheprocess.codes/test"
+)
+
+func TestFormat(t *testing.T) {
+	test.Equal(t, filecmd.Format("KEY", "value"), "KEY=value\n")
+
+	multi := filecmd.Format("KEY", "one\ntwo")
+	test.True(t, strings.HasPrefix(multi, "KEY<<ghadelimiter_"))
+	test.True(t, strings.HasSuffix(multi, "\n"))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		want  map[string]string
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  map[string]string{},
+		},
+		{
+			name:  "simple",
+			input: "ONE=1\nTWO=2=two\n\nEMPTY=\n",
+			want:  map[string]string{"ONE": "1", "TWO": "2=two", "EMPTY": ""},
+		},
+		{
+			name:  "heredoc",
+			input: "MULTI<<EOF\nline one\n\nline=three\nEOF\nAFTER=yes\n",
+			want:  map[string]string{"MULTI": "line one\n\nline=three", "AFTER": "yes"},
+		},
+		{
+			name:  "crlf",
+			input: "ONE=1\r\nMULTI<<EOF\r\na\r\nb\r\nEOF\r\n",
+			want:  map[string]string{"ONE": "1", "MULTI": "a\nb"},
+		},
+		{
+			name:  "no trailing newline",
+			input: "ONE=1",
+			want:  map[string]string{"ONE": "1"},
+		},
+		{
+			name:  "last wins",
+			input: "ONE=1\nONE=uno\n",
+			want:  map[string]string{"ONE": "uno"},
+		},
+		{
+			name:  "equals before heredoc",
+			input: "KEY=a<<b\n",
+			want:  map[string]string{"KEY": "a<<b"},
+		},
+		{
+			name:  "missing key",
+			input: "ONE=1\n=value\n",
+			err:   `line 2: invalid format "=value", key must not be empty`,
+		},
+		{
+			name:  "missing delimiter",
+			input: "KEY<<\n",
+			err:   `line 1: invalid format "KEY<<", key and delimiter must not be empty`,
+		},
+		{
+			name:  "unterminated",
+			input: "KEY<<EOF\nvalue\n",
+			err:   `line 1: matching delimiter "EOF" for KEY not found`,
+		},
+		{
+			name:  "invalid",
+			input: "nonsense\n",
+			err:   `line 1: invalid format "nonsense"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filecmd.Parse(strings.NewReader(tt.input))
+			if tt.err != "" {
+				test.Err(t, err)
+				test.Equal(t, err.Error(), tt.err)
+
+				return
+			}
+
+			test.Ok(t, err)
+			test.EqualFunc(t, got, tt.want, maps.Equal)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	values := map[string]string{
+		"SIMPLE": "value",
+		"MULTI":  "first\nsecond\n\nfourth",
+		"EQUALS": "a=b",
+	}
+
+	s := &strings.Builder{}
+	for key, value := range values {
+		s.WriteString(filecmd.Format(key, value))
+	}
+
+	got, err := filecmd.Parse(strings.NewReader(s.String()))
+	test.Ok(t, err)
+	test.EqualFunc(t, got, values, maps.Equal)
+}
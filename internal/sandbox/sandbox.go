@@ -0,0 +1,207 @@
+// Package sandbox creates a fake GitHub Actions runner environment on disk, the directories,
+// files and environment variables the runner provides to each step, so that actions can be
+// run and tested outside of GitHub.
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.followtheprocess.codes/actions/internal/filecmd"
+)
+
+const (
+	// dirPermissions is the permissions used when creating directories.
+	dirPermissions = 0o755
+
+	// filePermissions is the permissions used when creating files.
+	filePermissions = 0o644
+
+	// eventVar is the env var containing the path to the event payload.
+	eventVar = "GITHUB_EVENT_PATH"
+)
+
+// files are the names of the runner's files, keyed by the env var containing their path.
+//
+//nolint:gochecknoglobals // This is effectively a constant.
+var files = map[string]string{
+	"GITHUB_ENV":          "env",
+	"GITHUB_OUTPUT":       "output",
+	"GITHUB_STATE":        "state",
+	"GITHUB_PATH":         "path",
+	"GITHUB_STEP_SUMMARY": "step_summary",
+	eventVar:              "event.json",
+}
+
+// Sandbox is a fake runner environment on disk.
+type Sandbox struct {
+	Files     map[string]string // Paths to the runner's files, keyed by env var e.g. "GITHUB_OUTPUT"
+	Workspace string            // Path to $GITHUB_WORKSPACE
+	Temp      string            // Path to $RUNNER_TEMP
+	ToolCache string            // Path to $RUNNER_TOOL_CACHE
+}
+
+// New creates a sandbox in the directory root, writing event as the event payload.
+//
+// If workspace is empty, an empty workspace is created in root.
+func New(root, workspace string, event []byte) (Sandbox, error) {
+	if workspace == "" {
+		workspace = filepath.Join(root, "workspace")
+	}
+
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return Sandbox{}, fmt.Errorf("could not resolve workspace: %w", err)
+	}
+
+	sandbox := Sandbox{
+		Files:     make(map[string]string, len(files)),
+		Workspace: workspace,
+		Temp:      filepath.Join(root, "temp"),
+		ToolCache: filepath.Join(root, "tool-cache"),
+	}
+
+	commands := filepath.Join(sandbox.Temp, "_runner_file_commands")
+
+	for _, dir := range []string{sandbox.Workspace, sandbox.Temp, sandbox.ToolCache, commands} {
+		if err = os.MkdirAll(dir, dirPermissions); err != nil {
+			return Sandbox{}, fmt.Errorf("could not create %s: %w", dir, err)
+		}
+	}
+
+	for key, name := range files {
+		path := filepath.Join(commands, name)
+
+		contents := []byte{}
+		if key == eventVar {
+			contents = event
+		}
+
+		if err = os.WriteFile(path, contents, filePermissions); err != nil {
+			return Sandbox{}, fmt.Errorf("could not create %s: %w", path, err)
+		}
+
+		sandbox.Files[key] = path
+	}
+
+	return sandbox, nil
+}
+
+// Env returns the default environment variables the runner sets for a step triggered
+// by the named event, including the paths to the sandbox's files and directories.
+func (s Sandbox) Env(eventName string) map[string]string {
+	env := map[string]string{
+		"CI":                 "true",
+		"GITHUB_ACTIONS":     "true",
+		"GITHUB_ACTION":      "__run",
+		"GITHUB_ACTOR":       "octocat",
+		"GITHUB_ACTOR_ID":    "1",
+		"GITHUB_API_URL":     "https://api.github.com",
+		"GITHUB_EVENT_NAME":  eventName,
+		"GITHUB_GRAPHQL_URL": "https://api.github.com/graphql",
+		"GITHUB_JOB":         "test",
+		"GITHUB_REF":         "refs/heads/main",
+		"GITHUB_REF_NAME":    "main",
+		"GITHUB_REF_TYPE":    "branch",
+		"GITHUB_REPOSITORY":  "octo-org/octo-repo",
+		"GITHUB_RUN_ATTEMPT": "1",
+		"GITHUB_RUN_ID":      "1",
+		"GITHUB_RUN_NUMBER":  "1",
+		"GITHUB_SERVER_URL":  "https://github.com",
+		"GITHUB_SHA":         "ffac537e6cbbf934b08745a378932722df287a53",
+		"GITHUB_WORKFLOW":    "Test",
+		"GITHUB_WORKSPACE":   s.Workspace,
+		"RUNNER_ARCH":        runnerArch(),
+		"RUNNER_ENVIRONMENT": "self-hosted",
+		"RUNNER_NAME":        "sandbox",
+		"RUNNER_OS":          runnerOS(),
+		"RUNNER_TEMP":        s.Temp,
+		"RUNNER_TOOL_CACHE":  s.ToolCache,
+	}
+
+	for key, path := range s.Files {
+		env[key] = path
+	}
+
+	return env
+}
+
+// Parse returns the values written to the file command file in the env var key
+// e.g. "GITHUB_OUTPUT".
+func (s Sandbox) Parse(key string) (map[string]string, error) {
+	file, err := os.Open(s.Files[key])
+	if err != nil {
+		return nil, fmt.Errorf("could not open $%s: %w", key, err)
+	}
+	defer file.Close()
+
+	values, err := filecmd.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $%s: %w", key, err)
+	}
+
+	return values, nil
+}
+
+// Path returns the directories written to $GITHUB_PATH, in the order they were written.
+func (s Sandbox) Path() ([]string, error) {
+	contents, err := os.ReadFile(s.Files["GITHUB_PATH"])
+	if err != nil {
+		return nil, fmt.Errorf("could not read $GITHUB_PATH: %w", err)
+	}
+
+	var paths []string
+
+	for line := range strings.Lines(string(contents)) {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+
+	return paths, nil
+}
+
+// Summary returns the contents of $GITHUB_STEP_SUMMARY.
+func (s Sandbox) Summary() (string, error) {
+	contents, err := os.ReadFile(s.Files["GITHUB_STEP_SUMMARY"])
+	if err != nil {
+		return "", fmt.Errorf("could not read $GITHUB_STEP_SUMMARY: %w", err)
+	}
+
+	return string(contents), nil
+}
+
+// InputVar returns the name of the env var the runner uses to pass the input called name,
+// the inverse of what [go.followtheprocess.codes/actions/input.Get] does.
+func InputVar(name string) string {
+	return "INPUT_" + strings.ToUpper(strings.ReplaceAll(name, " ", "_"))
+}
+
+// runnerOS returns the value of $RUNNER_OS for the current platform.
+func runnerOS() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS"
+	case "windows":
+		return "Windows"
+	default:
+		return "Linux"
+	}
+}
+
+// runnerArch returns the value of $RUNNER_ARCH for the current platform.
+func runnerArch() string {
+	switch runtime.GOARCH {
+	case "arm64":
+		return "ARM64"
+	case "arm":
+		return "ARM"
+	case "386":
+		return "X86"
+	default:
+		return "X64"
+	}
+}
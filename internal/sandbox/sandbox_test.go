@@ -0,0 +1,65 @@
+package sandbox_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/actions/internal/sandbox"
+	"go.followtheprocess.codes/test"
+)
+
+func TestNew(t *testing.T) {
+	root := t.TempDir()
+
+	fake, err := sandbox.New(root, "", []byte(`{"ref": "main"}`))
+	test.Ok(t, err)
+
+	test.Equal(t, fake.Workspace, filepath.Join(root, "workspace"))
+
+	for _, dir := range []string{fake.Workspace, fake.Temp, fake.ToolCache} {
+		var info os.FileInfo
+
+		info, err = os.Stat(dir)
+		test.Ok(t, err)
+		test.True(t, info.IsDir())
+	}
+
+	event, err := os.ReadFile(fake.Files["GITHUB_EVENT_PATH"])
+	test.Ok(t, err)
+	test.Equal(t, string(event), `{"ref": "main"}`)
+
+	env := fake.Env("pull_request")
+	test.Equal(t, env["GITHUB_EVENT_NAME"], "pull_request")
+	test.Equal(t, env["GITHUB_WORKSPACE"], fake.Workspace)
+	test.Equal(t, env["GITHUB_OUTPUT"], fake.Files["GITHUB_OUTPUT"])
+}
+
+func TestResults(t *testing.T) {
+	fake, err := sandbox.New(t.TempDir(), t.TempDir(), nil)
+	test.Ok(t, err)
+
+	test.Ok(t, os.WriteFile(fake.Files["GITHUB_OUTPUT"], []byte("one=1\nbad\n"), 0o644))
+	test.Ok(t, os.WriteFile(fake.Files["GITHUB_PATH"], []byte("/a\n\n/b\n"), 0o644))
+	test.Ok(t, os.WriteFile(fake.Files["GITHUB_STEP_SUMMARY"], []byte("# Hi"), 0o644))
+
+	_, err = fake.Parse("GITHUB_OUTPUT")
+	test.Err(t, err)
+
+	env, err := fake.Parse("GITHUB_ENV")
+	test.Ok(t, err)
+	test.Equal(t, len(env), 0)
+
+	path, err := fake.Path()
+	test.Ok(t, err)
+	test.Equal(t, len(path), 2)
+	test.Equal(t, path[1], "/b")
+
+	summary, err := fake.Summary()
+	test.Ok(t, err)
+	test.Equal(t, summary, "# Hi")
+}
+
+func TestInputVar(t *testing.T) {
+	test.Equal(t, sandbox.InputVar("who to greet"), "INPUT_WHO_TO_GREET")
+}
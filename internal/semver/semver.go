@@ -0,0 +1,429 @@
+// Package semver implements parsing and comparison of semantic versions, along with
+// the version range syntax used by npm's node-semver which the actions toolkit
+// uses to resolve versions of tools e.g. "1.x", "^1.2.3" or ">=1.2 <2".
+//
+// See https://semver.org and https://github.com/npm/node-semver#ranges
+package semver
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version.
+type Version struct {
+	Prerelease string // Pre-release identifiers e.g. "rc.1", empty if not a pre-release
+	Build      string // Build metadata, ignored when comparing
+	Major      int    // Major version
+	Minor      int    // Minor version
+	Patch      int    // Patch version
+}
+
+// Parse parses a complete semantic version, tolerating a leading "v" or "=" and surrounding
+// whitespace e.g. "v1.2.3", "1.2.3-rc.1+build.5".
+func Parse(text string) (Version, error) {
+	text = clean(text)
+
+	version, parts, err := parsePartial(text)
+	if err != nil {
+		return Version{}, err
+	}
+
+	if parts != 3 {
+		return Version{}, fmt.Errorf("invalid semantic version %q: must have major, minor and patch", text)
+	}
+
+	return version, nil
+}
+
+// Clean returns the canonical string form of text if it is a valid semantic version,
+// or the empty string if not e.g. " v1.2.3 " becomes "1.2.3".
+func Clean(text string) string {
+	version, err := Parse(text)
+	if err != nil {
+		return ""
+	}
+
+	return version.String()
+}
+
+// String returns the canonical string form of the version, without a leading "v".
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+
+	return s
+}
+
+// Compare returns -1 if v < other, 1 if v > other and 0 if they have equal precedence.
+//
+// Build metadata does not affect precedence.
+func (v Version) Compare(other Version) int {
+	if c := cmp.Compare(v.Major, other.Major); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// Range is a parsed version range, a set of alternatives (separated by "||") each of
+// which is a set of comparators that must all be satisfied.
+type Range struct {
+	sets [][]comparator
+}
+
+// ParseRange parses a node-semver style version range.
+//
+// Supported syntax includes exact versions ("1.2.3"), comparators (">=1.2.3 <2"), X-ranges
+// ("1.x", "1.2", "*"), tilde ranges ("~1.2.3"), caret ranges ("^1.2.3"), hyphen ranges
+// ("1.2 - 2.3") and unions of any of these separated by "||".
+func ParseRange(text string) (Range, error) {
+	var r Range
+
+	for alternative := range strings.SplitSeq(text, "||") {
+		set, err := parseSet(alternative)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid version range %q: %w", text, err)
+		}
+
+		r.sets = append(r.sets, set)
+	}
+
+	return r, nil
+}
+
+// Contains reports whether version satisfies the range.
+//
+// As with node-semver, a pre-release version only satisfies the range if at least one
+// comparator in the matching set has a pre-release on the same major, minor and patch,
+// so "^1.2.0" does not match "1.3.0-rc.1" but ">=1.3.0-rc.0" does.
+func (r Range) Contains(version Version) bool {
+	for _, set := range r.sets {
+		if setContains(set, version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// comparator is a single comparison against a version e.g. ">=1.2.3".
+type comparator struct {
+	op      string  // One of "<", "<=", ">", ">=", "="
+	version Version // The version to compare against
+}
+
+// matches reports whether version satisfies the comparator.
+func (c comparator) matches(version Version) bool {
+	result := version.Compare(c.version)
+
+	switch c.op {
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	default:
+		return result == 0
+	}
+}
+
+// setContains reports whether version satisfies every comparator in set.
+func setContains(set []comparator, version Version) bool {
+	for _, c := range set {
+		if !c.matches(version) {
+			return false
+		}
+	}
+
+	if version.Prerelease == "" {
+		return true
+	}
+
+	// Pre-releases only match if explicitly opted in to on the same version tuple
+	for _, c := range set {
+		if c.version.Prerelease != "" && c.version.Major == version.Major &&
+			c.version.Minor == version.Minor && c.version.Patch == version.Patch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSet parses a whitespace separated set of range expressions that must all be satisfied.
+func parseSet(text string) ([]comparator, error) {
+	fields := strings.Fields(text)
+
+	// Hyphen range e.g. "1.2.3 - 2.3.4"
+	if len(fields) == 3 && fields[1] == "-" {
+		lower, _, err := parsePartialOrX(fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		upper, upperParts, err := parsePartialOrX(fields[2])
+		if err != nil {
+			return nil, err
+		}
+
+		set := []comparator{{op: ">=", version: lower}}
+		if upperParts == 3 {
+			return append(set, comparator{op: "<=", version: upper}), nil
+		}
+
+		if upperParts == 0 {
+			return set, nil
+		}
+
+		return append(set, comparator{op: "<", version: bump(upper, upperParts)}), nil
+	}
+
+	if len(fields) == 0 {
+		// Empty means any version
+		return []comparator{{op: ">=", version: Version{}}}, nil
+	}
+
+	var set []comparator
+
+	for _, field := range fields {
+		comparators, err := parseExpression(field)
+		if err != nil {
+			return nil, err
+		}
+
+		set = append(set, comparators...)
+	}
+
+	return set, nil
+}
+
+// parseExpression parses a single range expression like "^1.2", ">=1.0.0" or "1.x" into
+// the comparators it is equivalent to.
+func parseExpression(text string) ([]comparator, error) {
+	var op string
+
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~>", "~"} {
+		if rest, ok := strings.CutPrefix(text, prefix); ok {
+			op = prefix
+			text = rest
+
+			break
+		}
+	}
+
+	version, parts, err := parsePartialOrX(text)
+	if err != nil {
+		return nil, err
+	}
+
+	anyVersion := []comparator{{op: ">=", version: Version{}}}
+
+	switch op {
+	case "^":
+		if parts == 0 {
+			return anyVersion, nil
+		}
+
+		lower := comparator{op: ">=", version: version}
+
+		var upper Version
+
+		switch {
+		case version.Major != 0 || parts == 1:
+			upper = Version{Major: version.Major + 1}
+		case version.Minor != 0 || parts == 2:
+			upper = Version{Minor: version.Minor + 1}
+		default:
+			upper = Version{Minor: version.Minor, Patch: version.Patch + 1}
+		}
+
+		return []comparator{lower, {op: "<", version: upper}}, nil
+
+	case "~", "~>":
+		if parts == 0 {
+			return anyVersion, nil
+		}
+
+		lower := comparator{op: ">=", version: version}
+		if parts == 1 {
+			return []comparator{lower, {op: "<", version: Version{Major: version.Major + 1}}}, nil
+		}
+
+		return []comparator{lower, {op: "<", version: Version{Major: version.Major, Minor: version.Minor + 1}}}, nil
+
+	case ">", ">=", "<", "<=":
+		if parts == 0 {
+			if op == "<" || op == ">" {
+				// Nothing is less or greater than every version
+				return []comparator{{op: "<", version: Version{}}}, nil
+			}
+
+			return anyVersion, nil
+		}
+
+		if parts == 3 {
+			return []comparator{{op: op, version: version}}, nil
+		}
+
+		// Partial versions compare against the whole range they cover
+		// e.g. ">1.2" is ">=1.3.0" and "<=1.2" is "<1.3.0"
+		switch op {
+		case ">":
+			return []comparator{{op: ">=", version: bump(version, parts)}}, nil
+		case "<=":
+			return []comparator{{op: "<", version: bump(version, parts)}}, nil
+		default:
+			return []comparator{{op: op, version: version}}, nil
+		}
+
+	default:
+		// "=" or no operator, an exact version or X-range
+		switch parts {
+		case 0:
+			return anyVersion, nil
+		case 3:
+			return []comparator{{op: "=", version: version}}, nil
+		default:
+			return []comparator{{op: ">=", version: version}, {op: "<", version: bump(version, parts)}}, nil
+		}
+	}
+}
+
+// bump returns the lowest version above every version matched by the partial version with
+// the given number of parts e.g. bump(1.2, 2) is 1.3.0 and bump(1, 1) is 2.0.0.
+func bump(version Version, parts int) Version {
+	if parts == 1 {
+		return Version{Major: version.Major + 1}
+	}
+
+	return Version{Major: version.Major, Minor: version.Minor + 1}
+}
+
+// parsePartialOrX is like parsePartial but also accepts "x", "X" or "*" in place of any
+// component, treating it (and everything after it) as unspecified.
+func parsePartialOrX(text string) (Version, int, error) {
+	text = clean(text)
+
+	if text == "" {
+		return Version{}, 0, nil
+	}
+
+	components := strings.SplitN(text, ".", 3)
+	for i, component := range components {
+		if component == "x" || component == "X" || component == "*" {
+			if i == 0 {
+				return Version{}, 0, nil
+			}
+
+			return parsePartial(strings.Join(components[:i], "."))
+		}
+	}
+
+	return parsePartial(text)
+}
+
+// parsePartial parses a possibly incomplete version e.g. "1", "1.2" or "1.2.3-rc.1", returning
+// the version and how many of the major, minor and patch components were present.
+func parsePartial(text string) (Version, int, error) {
+	if text == "" {
+		return Version{}, 0, errors.New("empty version")
+	}
+
+	var version Version
+
+	text, version.Build, _ = strings.Cut(text, "+")
+	text, version.Prerelease, _ = strings.Cut(text, "-")
+
+	components := strings.Split(text, ".")
+	if len(components) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q: too many components", text)
+	}
+
+	if len(components) < 3 && (version.Prerelease != "" || version.Build != "") {
+		return Version{}, 0, fmt.Errorf("invalid version %q: pre-release requires major, minor and patch", text)
+	}
+
+	targets := []*int{&version.Major, &version.Minor, &version.Patch}
+
+	for i, component := range components {
+		n, err := strconv.Atoi(component)
+		if err != nil || n < 0 || component == "" || (len(component) > 1 && component[0] == '0') {
+			return Version{}, 0, fmt.Errorf("invalid version %q: bad component %q", text, component)
+		}
+
+		*targets[i] = n
+	}
+
+	return version, len(components), nil
+}
+
+// clean strips surrounding whitespace and a leading "v" or "=" from text.
+func clean(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "=")
+	text = strings.TrimPrefix(text, "v")
+
+	return strings.TrimSpace(text)
+}
+
+// comparePrerelease compares pre-release strings according to semver precedence rules.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		// A version without a pre-release is greater than one with
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		var c int
+
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			// Numeric identifiers have lower precedence than alphanumeric
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(as), len(bs))
+}
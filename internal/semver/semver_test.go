@@ -0,0 +1,147 @@
+package semver_test
+
+import (
+	"testing"
+
+	"go.followtheprocess.codes/actions/internal/semver"
+	"go.followtheprocess.codes/test"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string         // Name of the test case
+		text    string         // Text to parse
+		want    semver.Version // Expected version
+		wantErr bool           // Whether we want an error
+	}{
+		{name: "simple", text: "1.2.3", want: semver.Version{Major: 1, Minor: 2, Patch: 3}},
+		{name: "leading v", text: " v10.0.1 ", want: semver.Version{Major: 10, Patch: 1}},
+		{name: "leading equals", text: "=0.1.0", want: semver.Version{Minor: 1}},
+		{
+			name: "prerelease and build",
+			text: "1.2.3-rc.1+build.5",
+			want: semver.Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "build.5"},
+		},
+		{name: "empty", text: "", wantErr: true},
+		{name: "partial", text: "1.2", wantErr: true},
+		{name: "too many", text: "1.2.3.4", wantErr: true},
+		{name: "leading zero", text: "01.2.3", wantErr: true},
+		{name: "not a number", text: "1.two.3", wantErr: true},
+		{name: "negative", text: "1.-2.3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := semver.Parse(tt.text)
+			test.WantErr(t, err, tt.wantErr)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestClean(t *testing.T) {
+	test.Equal(t, semver.Clean(" v1.2.3 "), "1.2.3")
+	test.Equal(t, semver.Clean("1.2.3-beta+exp"), "1.2.3-beta+exp")
+	test.Equal(t, semver.Clean("1.2"), "")
+}
+
+func TestCompare(t *testing.T) {
+	// Each version is strictly greater than the one before it
+	ordered := []string{
+		"0.0.1",
+		"0.1.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i := range len(ordered) - 1 {
+		lower, err := semver.Parse(ordered[i])
+		test.Ok(t, err)
+
+		higher, err := semver.Parse(ordered[i+1])
+		test.Ok(t, err)
+
+		test.Equal(t, lower.Compare(higher), -1, test.Context("%s < %s", lower, higher))
+		test.Equal(t, higher.Compare(lower), 1, test.Context("%s > %s", higher, lower))
+		test.Equal(t, lower.Compare(lower), 0)
+	}
+
+	// Build metadata is ignored
+	a, err := semver.Parse("1.0.0+a")
+	test.Ok(t, err)
+
+	b, err := semver.Parse("1.0.0+b")
+	test.Ok(t, err)
+
+	test.Equal(t, a.Compare(b), 0)
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		spec    string   // The version range
+		match   []string // Versions that should satisfy the range
+		noMatch []string // Versions that should not satisfy the range
+	}{
+		{spec: "*", match: []string{"0.0.0", "1.2.3", "99.0.0"}, noMatch: []string{"1.0.0-rc.1"}},
+		{spec: "", match: []string{"0.0.0", "1.2.3"}},
+		{spec: "x", match: []string{"3.2.1"}},
+		{spec: "1.2.3", match: []string{"1.2.3", "v1.2.3"}, noMatch: []string{"1.2.4", "1.2.2"}},
+		{spec: "1", match: []string{"1.0.0", "1.99.99"}, noMatch: []string{"0.9.9", "2.0.0"}},
+		{spec: "1.x", match: []string{"1.0.0", "1.5.0"}, noMatch: []string{"2.0.0"}},
+		{spec: "1.2.x", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0", "1.1.9"}},
+		{spec: "1.2", match: []string{"1.2.0", "1.2.9"}, noMatch: []string{"1.3.0"}},
+		{spec: "^1.2.3", match: []string{"1.2.3", "1.9.0"}, noMatch: []string{"1.2.2", "2.0.0", "2.0.0-rc.1"}},
+		{spec: "^0.2.3", match: []string{"0.2.3", "0.2.9"}, noMatch: []string{"0.3.0"}},
+		{spec: "^0.0.3", match: []string{"0.0.3"}, noMatch: []string{"0.0.4"}},
+		{spec: "^1.2", match: []string{"1.2.0", "1.9.9"}, noMatch: []string{"2.0.0"}},
+		{spec: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, noMatch: []string{"1.3.0"}},
+		{spec: "~1", match: []string{"1.0.0", "1.9.0"}, noMatch: []string{"2.0.0"}},
+		{spec: ">=1.2.3 <2", match: []string{"1.2.3", "1.9.9"}, noMatch: []string{"1.2.2", "2.0.0"}},
+		{spec: ">1.2", match: []string{"1.3.0"}, noMatch: []string{"1.2.9"}},
+		{spec: "<=1.2", match: []string{"1.2.9", "0.1.0"}, noMatch: []string{"1.3.0"}},
+		{spec: "<1.2", match: []string{"1.1.9"}, noMatch: []string{"1.2.0"}},
+		{spec: "1.2 - 2.3.4", match: []string{"1.2.0", "2.3.4"}, noMatch: []string{"1.1.9", "2.3.5"}},
+		{spec: "1.2.3 - 2", match: []string{"2.9.9"}, noMatch: []string{"3.0.0"}},
+		{spec: "1.x || >=3.1", match: []string{"1.5.0", "3.1.0"}, noMatch: []string{"2.0.0", "3.0.9"}},
+		{
+			spec:    ">=1.3.0-rc.0",
+			match:   []string{"1.3.0-rc.1", "1.3.0", "2.0.0"},
+			noMatch: []string{"1.4.0-rc.1", "1.2.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			r, err := semver.ParseRange(tt.spec)
+			test.Ok(t, err)
+
+			for _, text := range tt.match {
+				version, err := semver.Parse(text)
+				test.Ok(t, err)
+				test.True(t, r.Contains(version), test.Context("%q should match %s", tt.spec, text))
+			}
+
+			for _, text := range tt.noMatch {
+				version, err := semver.Parse(text)
+				test.Ok(t, err)
+				test.True(t, !r.Contains(version), test.Context("%q should not match %s", tt.spec, text))
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, spec := range []string{"abc", ">=1.a", "1.2.3.4", "1.2 - nope"} {
+			_, err := semver.ParseRange(spec)
+			test.Err(t, err, test.Context("%q should be invalid", spec))
+		}
+	})
+}
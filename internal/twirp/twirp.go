@@ -0,0 +1,157 @@
+// Package twirp implements a minimal JSON client for the twirp based services the actions
+// runner exposes at $ACTIONS_RESULTS_URL, which back both the v2 cache service and v4
+// artifacts.
+//
+// Only the JSON encoding is supported, with field names as they appear in the protobuf
+// definitions e.g. "signed_upload_url", which is what the actions toolkit sends.
+//
+// See https://twitchtv.github.io/twirp/docs/spec_v7.html
+package twirp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client makes twirp calls to a single server.
+//
+// A Client is safe for concurrent use by multiple goroutines.
+type Client struct {
+	HTTP     *http.Client  // The HTTP client to use, nil means http.DefaultClient
+	BaseURL  string        // Base URL of the server e.g. $ACTIONS_RESULTS_URL
+	Token    string        // Bearer token to authenticate with e.g. $ACTIONS_RUNTIME_TOKEN
+	Attempts int           // Maximum number of attempts for each call, 0 means 5
+	Delay    time.Duration // Delay before the first retry, increased by half each time, 0 means 3s
+}
+
+// Error is a twirp error response.
+type Error struct {
+	Meta       map[string]string `json:"meta,omitempty"` // Additional error metadata
+	Code       string            `json:"code"`           // The twirp error code e.g. "not_found"
+	Message    string            `json:"msg"`            // Description of the error
+	StatusCode int               `json:"-"`              // The HTTP status code of the response
+}
+
+// Error implements the error interface for [Error].
+func (e *Error) Error() string {
+	return fmt.Sprintf("twirp error %s (status %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// Call calls method on service, encoding in as the request and decoding the response into out.
+//
+// Network errors and responses indicating the server is temporarily unavailable are retried,
+// any other unsuccessful response is returned as an [*Error].
+func (c *Client) Call(ctx context.Context, service, method string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("could not encode %s request: %w", method, err)
+	}
+
+	url := strings.TrimSuffix(c.BaseURL, "/") + "/twirp/" + service + "/" + method
+
+	attempts := c.Attempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+
+	delay := c.Delay
+	if delay <= 0 {
+		delay = 3 * time.Second
+	}
+
+	for attempt := range attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+
+			delay += delay / 2
+		}
+
+		var retryable bool
+
+		retryable, err = c.call(ctx, url, body, out)
+		if err == nil || !retryable {
+			break
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	return nil
+}
+
+// call makes a single attempt at a call, reporting whether any error is worth retrying.
+func (c *Client) call(ctx context.Context, url string, body []byte, out any) (retryable bool, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("could not create request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("could not read response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		twirpErr := &Error{StatusCode: response.StatusCode}
+		if json.Unmarshal(data, twirpErr) != nil || twirpErr.Code == "" {
+			twirpErr.Code = "unknown"
+			twirpErr.Message = strings.TrimSpace(string(data))
+		}
+
+		return temporary(response.StatusCode), twirpErr
+	}
+
+	if out == nil {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return false, nil
+}
+
+// temporary reports whether a response with the given status code should be retried.
+func temporary(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
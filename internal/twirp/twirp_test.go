@@ -0,0 +1,111 @@
+package twirp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/internal/twirp"
+	"go.followtheprocess.codes/test"
+)
+
+type request struct {
+	Name string `json:"name"`
+}
+
+type response struct {
+	Greeting string `json:"greeting"`
+}
+
+func TestCall(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Method, http.MethodPost)
+			test.Equal(t, r.URL.Path, "/twirp/example.v1.Greeter/Greet")
+			test.Equal(t, r.Header.Get("Authorization"), "Bearer token")
+			test.Equal(t, r.Header.Get("Content-Type"), "application/json")
+
+			var req request
+			test.Ok(t, json.NewDecoder(r.Body).Decode(&req))
+
+			json.NewEncoder(w).Encode(response{Greeting: "hello " + req.Name})
+		}))
+		defer server.Close()
+
+		client := &twirp.Client{BaseURL: server.URL + "/", Token: "token"}
+
+		var resp response
+
+		err := client.Call(t.Context(), "example.v1.Greeter", "Greet", request{Name: "octocat"}, &resp)
+		test.Ok(t, err)
+		test.Equal(t, resp.Greeting, "hello octocat")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": "not_found", "msg": "no such artifact"}`))
+		}))
+		defer server.Close()
+
+		client := &twirp.Client{BaseURL: server.URL, Delay: time.Millisecond}
+
+		err := client.Call(t.Context(), "example.v1.Greeter", "Greet", request{}, nil)
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "Greet: twirp error not_found (status 404): no such artifact")
+		test.Equal(t, calls.Load(), 1) // Not retried
+
+		var twirpErr *twirp.Error
+
+		test.True(t, errors.As(err, &twirpErr))
+		test.Equal(t, twirpErr.Code, "not_found")
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte("upstream unavailable"))
+
+				return
+			}
+
+			json.NewEncoder(w).Encode(response{Greeting: "finally"})
+		}))
+		defer server.Close()
+
+		client := &twirp.Client{BaseURL: server.URL, Delay: time.Millisecond}
+
+		var resp response
+
+		test.Ok(t, client.Call(t.Context(), "example.v1.Greeter", "Greet", request{}, &resp))
+		test.Equal(t, resp.Greeting, "finally")
+		test.Equal(t, calls.Load(), 3)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := &twirp.Client{BaseURL: server.URL, Attempts: 2, Delay: time.Millisecond}
+
+		err := client.Call(t.Context(), "example.v1.Greeter", "Greet", request{}, nil)
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "Greet: twirp error unknown (status 502): ")
+		test.Equal(t, calls.Load(), 2)
+	})
+}
@@ -0,0 +1,462 @@
+// Package io provides platform independent filesystem operations for use in GitHub Actions,
+// mirroring the @actions/io package of the actions toolkit.
+//
+// The functions here behave like their familiar command line counterparts (cp, mv, rm -rf,
+// mkdir -p and which) on every platform the runner supports:
+//
+//	if err := io.Cp("dist", "out", io.Recursive(true)); err != nil {
+//		// Handle error
+//	}
+//
+//	git, err := io.Which("git", true)
+//	if err != nil {
+//		// Handle error
+//	}
+//
+// Note that this package shares a name with the standard library [io] package, so will need
+// to be imported under an alias if both are used in the same file.
+//
+// See https://github.com/actions/toolkit/tree/main/packages/io
+package io // import "go.followtheprocess.codes/actions/io"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+const (
+	// pathVar is the env var containing the directories searched for executables.
+	pathVar = "PATH"
+
+	// pathExtVar is the env var containing the extensions of executable files on Windows.
+	pathExtVar = "PATHEXT"
+
+	// defaultPathExt is used when $PATHEXT is not set on Windows.
+	defaultPathExt = ".COM;.EXE;.BAT;.CMD"
+
+	// dirPermissions is the permissions used when creating directories.
+	dirPermissions = 0o755
+
+	// errorNotSameDevice is the Windows error returned when renaming across volumes, the
+	// equivalent of EXDEV elsewhere.
+	errorNotSameDevice = 17
+
+	// invalidWindowsChars are the characters not allowed in a path passed to [RmRF] on Windows.
+	invalidWindowsChars = `*"<>|`
+)
+
+// NotFoundError is returned from [Which] when check is true and the tool could not be found.
+type NotFoundError struct {
+	Tool string // The tool that was searched for
+}
+
+// Error implements the error interface for [NotFoundError].
+func (n *NotFoundError) Error() string {
+	return fmt.Sprintf(
+		"could not find executable %s, check the path exists or the file is in a directory on $PATH",
+		n.Tool,
+	)
+}
+
+// config holds the configuration for a single call to [Cp] or [Mv].
+type config struct {
+	recursive           bool // Whether directories are copied recursively
+	force               bool // Whether existing destinations are overwritten
+	copySourceDirectory bool // Whether a directory is copied into an existing destination directory
+}
+
+// Option is a configuration option for [Cp] and [Mv].
+type Option interface {
+	// Apply the option to the config.
+	apply(cfg *config)
+}
+
+// option is a function that implements the Option interface.
+type option func(cfg *config)
+
+// apply applies the option, implementing the Option interface.
+func (o option) apply(cfg *config) {
+	o(cfg)
+}
+
+// Recursive sets whether [Cp] copies directories recursively, by default false in which
+// case copying a directory is an error.
+func Recursive(recursive bool) Option {
+	f := func(cfg *config) {
+		cfg.recursive = recursive
+	}
+
+	return option(f)
+}
+
+// Force sets whether [Cp] and [Mv] overwrite anything already at the destination, by
+// default true.
+//
+// When false, [Cp] leaves existing files untouched and [Mv] returns an error.
+func Force(force bool) Option {
+	f := func(cfg *config) {
+		cfg.force = force
+	}
+
+	return option(f)
+}
+
+// CopySourceDirectory sets whether [Cp] copies a directory into an existing destination
+// directory, like cp -r, by default true.
+//
+// When false, the contents of the source directory are copied into the destination
+// directory instead.
+func CopySourceDirectory(copySource bool) Option {
+	f := func(cfg *config) {
+		cfg.copySourceDirectory = copySource
+	}
+
+	return option(f)
+}
+
+// Cp copies the file or directory src to dst.
+//
+// If dst is an existing directory, src is copied into it. Directories are only copied if
+// [Recursive] is passed, in which case file modes are preserved and symlinks are recreated
+// rather than followed.
+func Cp(src, dst string, options ...Option) error {
+	cfg := newConfig(options)
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("could not copy %s: %w", src, err)
+	}
+
+	dstInfo, err := os.Stat(dst)
+	if err == nil && dstInfo.IsDir() && (cfg.copySourceDirectory || !srcInfo.IsDir()) {
+		dst = filepath.Join(dst, filepath.Base(src))
+	}
+
+	if srcInfo.IsDir() {
+		if !cfg.recursive {
+			return fmt.Errorf("could not copy %s: is a directory, but Recursive was not set", src)
+		}
+
+		if err = copyDir(src, dst, cfg.force); err != nil {
+			return fmt.Errorf("could not copy %s to %s: %w", src, dst, err)
+		}
+
+		return nil
+	}
+
+	if dstInfo, err = os.Stat(dst); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return fmt.Errorf("could not copy %s: %s is the same file", src, dst)
+	}
+
+	if err = copyFile(src, dst, cfg.force); err != nil {
+		return fmt.Errorf("could not copy %s to %s: %w", src, dst, err)
+	}
+
+	return nil
+}
+
+// Mv moves the file or directory src to dst.
+//
+// If dst is an existing directory, src is moved into it. Anything already at the destination
+// is replaced unless Force(false) is passed, in which case it's an error. Moves between
+// devices, which cannot be renamed, fall back to a copy followed by removing src.
+func Mv(src, dst string, options ...Option) error {
+	cfg := newConfig(options)
+
+	if info, err := os.Stat(dst); err == nil {
+		exists := true
+
+		if info.IsDir() {
+			dst = filepath.Join(dst, filepath.Base(src))
+			_, err = os.Lstat(dst)
+			exists = err == nil
+		}
+
+		if exists {
+			if !cfg.force {
+				return fmt.Errorf("could not move %s: destination %s already exists", src, dst)
+			}
+
+			if err = RmRF(dst); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := MkdirP(filepath.Dir(dst)); err != nil {
+		return err
+	}
+
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	if !crossDevice(err) {
+		return fmt.Errorf("could not move %s to %s: %w", src, dst, err)
+	}
+
+	if err = Cp(src, dst, Recursive(true)); err != nil {
+		return err
+	}
+
+	return RmRF(src)
+}
+
+// RmRF removes path and anything below it, like rm -rf.
+//
+// Read-only files and directories are removed too, and a path that doesn't exist is
+// not an error.
+func RmRF(path string) error {
+	if windows() && strings.ContainsAny(path, invalidWindowsChars) {
+		return fmt.Errorf("could not remove %s: path must not contain any of %s on Windows", path, invalidWindowsChars)
+	}
+
+	if err := os.RemoveAll(path); err == nil {
+		return nil
+	}
+
+	// Most likely something is read-only, make everything writable and try again. Errors are
+	// ignored here as the second attempt reports anything that's actually wrong
+	_ = filepath.WalkDir(path, func(item string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type()&fs.ModeSymlink == 0 {
+			makeWritable(item, entry)
+		}
+
+		return nil
+	})
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("could not remove %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// MkdirP creates the directory path along with any missing parents, like mkdir -p.
+//
+// A directory that already exists is not an error.
+func MkdirP(path string) error {
+	if path == "" {
+		return errors.New("cannot create a directory with an empty path")
+	}
+
+	if err := os.MkdirAll(path, dirPermissions); err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Which returns the path to the executable tool, searching the directories in $PATH in order.
+//
+// As [go.followtheprocess.codes/actions.AddPath] updates $PATH as well as $GITHUB_PATH,
+// directories added with it are searched too. A tool containing a separator is only
+// checked directly, not searched for, and on Windows the extensions in $PATHEXT are tried.
+//
+// If the tool is not found and check is true a [*NotFoundError] is returned, otherwise the
+// path is empty and the error nil.
+func Which(tool string, check bool) (string, error) {
+	if tool == "" {
+		return "", errors.New("cannot search for a tool with an empty name")
+	}
+
+	path := find(tool)
+	if path == "" && check {
+		return "", &NotFoundError{Tool: tool}
+	}
+
+	return path, nil
+}
+
+// find returns the path to the executable tool, or "" if it can't be found.
+func find(tool string) string {
+	var extensions []string
+
+	if windows() {
+		pathExt := os.Getenv(pathExtVar)
+		if pathExt == "" {
+			pathExt = defaultPathExt
+		}
+
+		for ext := range strings.SplitSeq(pathExt, string(os.PathListSeparator)) {
+			if ext != "" {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+
+	if filepath.IsAbs(tool) || strings.ContainsRune(tool, '/') || strings.ContainsRune(tool, filepath.Separator) {
+		// Paths aren't searched for, relative ones are relative to the working directory
+		return executable(tool, extensions)
+	}
+
+	for dir := range strings.SplitSeq(os.Getenv(pathVar), string(os.PathListSeparator)) {
+		if dir == "" {
+			continue
+		}
+
+		if path := executable(filepath.Join(dir, tool), extensions); path != "" {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// executable returns path if it's an executable file, trying each of extensions in turn
+// on Windows, or "" if none are.
+func executable(path string, extensions []string) string {
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		if windows() {
+			ext := filepath.Ext(path)
+			if slices.ContainsFunc(extensions, func(e string) bool { return strings.EqualFold(e, ext) }) {
+				return path
+			}
+		} else if info.Mode().Perm()&0o111 != 0 {
+			return path
+		}
+	}
+
+	for _, ext := range extensions {
+		if info, err := os.Stat(path + ext); err == nil && info.Mode().IsRegular() {
+			return path + ext
+		}
+	}
+
+	return ""
+}
+
+// makeWritable makes the file or directory at path writable by its owner, ignoring errors.
+func makeWritable(path string, entry fs.DirEntry) {
+	info, err := entry.Info()
+	if err != nil {
+		return
+	}
+
+	mode := info.Mode().Perm() | 0o200
+	if entry.IsDir() {
+		mode |= 0o700
+	}
+
+	_ = os.Chmod(path, mode)
+}
+
+// copyDir recursively copies the contents of the directory src into the directory dst,
+// creating it if necessary.
+func copyDir(src, dst string, force bool) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dst, dirPermissions); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		from := filepath.Join(src, entry.Name())
+		to := filepath.Join(dst, entry.Name())
+
+		if entry.IsDir() {
+			err = copyDir(from, to, force)
+		} else {
+			err = copyFile(from, to, force)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// copyFile copies the file src to dst, recreating it if it's a symlink and leaving an
+// existing dst alone unless force is true.
+func copyFile(src, dst string, force bool) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if _, err = os.Lstat(dst); err == nil {
+		if !force {
+			return nil
+		}
+
+		// Replace rather than write through it, it may be read-only or a symlink
+		if err = os.Remove(dst); err != nil {
+			return err
+		}
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		var link string
+
+		link, err = os.Readlink(src)
+		if err != nil {
+			return err
+		}
+
+		return os.Symlink(link, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err = out.ReadFrom(in); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// crossDevice reports whether err is from attempting to rename across devices.
+func crossDevice(err error) bool {
+	if windows() {
+		return errors.Is(err, syscall.Errno(errorNotSameDevice))
+	}
+
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// newConfig builds the config from the defaults and options.
+func newConfig(options []Option) config {
+	cfg := config{
+		force:               true,
+		copySourceDirectory: true,
+	}
+
+	for _, option := range options {
+		option.apply(&cfg)
+	}
+
+	return cfg
+}
+
+// windows reports whether we're running on Windows.
+func windows() bool {
+	return runtime.GOOS == "windows"
+}
@@ -0,0 +1,282 @@
+package io_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"go.followtheprocess.codes/actions"
+	"go.followtheprocess.codes/actions/io"
+	"go.followtheprocess.codes/test"
+)
+
+// makeTree creates files (and their parent directories) under a temporary directory,
+// returning its path. Each file's contents is its path.
+func makeTree(t *testing.T, files ...string) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+
+		test.Ok(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		test.Ok(t, os.WriteFile(path, []byte(file), 0o644))
+	}
+
+	return root
+}
+
+// contents returns the contents of the file at path.
+func contents(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	test.Ok(t, err)
+
+	return string(data)
+}
+
+// exists reports whether anything is at path, without following symlinks.
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestCp(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		root := makeTree(t, "a.txt")
+
+		test.Ok(t, io.Cp(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")))
+		test.Equal(t, contents(t, filepath.Join(root, "b.txt")), "a.txt")
+	})
+
+	t.Run("file into directory", func(t *testing.T) {
+		root := makeTree(t, "a.txt", "dir/other.txt")
+
+		test.Ok(t, io.Cp(filepath.Join(root, "a.txt"), filepath.Join(root, "dir")))
+		test.Equal(t, contents(t, filepath.Join(root, "dir", "a.txt")), "a.txt")
+	})
+
+	t.Run("no force", func(t *testing.T) {
+		root := makeTree(t, "a.txt", "b.txt")
+
+		test.Ok(t, io.Cp(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt"), io.Force(false)))
+		test.Equal(t, contents(t, filepath.Join(root, "b.txt")), "b.txt")
+	})
+
+	t.Run("overwrites read-only", func(t *testing.T) {
+		root := makeTree(t, "a.txt", "b.txt")
+		test.Ok(t, os.Chmod(filepath.Join(root, "b.txt"), 0o444))
+
+		test.Ok(t, io.Cp(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")))
+		test.Equal(t, contents(t, filepath.Join(root, "b.txt")), "a.txt")
+	})
+
+	t.Run("same file", func(t *testing.T) {
+		root := makeTree(t, "a.txt")
+
+		err := io.Cp(filepath.Join(root, "a.txt"), root)
+		test.Err(t, err)
+		test.Equal(t, contents(t, filepath.Join(root, "a.txt")), "a.txt")
+	})
+
+	t.Run("directory without recursive", func(t *testing.T) {
+		root := makeTree(t, "src/a.txt")
+
+		err := io.Cp(filepath.Join(root, "src"), filepath.Join(root, "dst"))
+		test.Err(t, err)
+		test.False(t, exists(filepath.Join(root, "dst")))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		root := makeTree(t, "src/a.txt", "src/nested/b.txt")
+
+		test.Ok(t, io.Cp(filepath.Join(root, "src"), filepath.Join(root, "dst"), io.Recursive(true)))
+		test.Equal(t, contents(t, filepath.Join(root, "dst", "a.txt")), "src/a.txt")
+		test.Equal(t, contents(t, filepath.Join(root, "dst", "nested", "b.txt")), "src/nested/b.txt")
+	})
+
+	t.Run("directory into directory", func(t *testing.T) {
+		root := makeTree(t, "src/a.txt", "dst/existing.txt")
+
+		test.Ok(t, io.Cp(filepath.Join(root, "src"), filepath.Join(root, "dst"), io.Recursive(true)))
+		test.Equal(t, contents(t, filepath.Join(root, "dst", "src", "a.txt")), "src/a.txt")
+	})
+
+	t.Run("directory contents into directory", func(t *testing.T) {
+		root := makeTree(t, "src/a.txt", "dst/existing.txt")
+
+		err := io.Cp(
+			filepath.Join(root, "src"),
+			filepath.Join(root, "dst"),
+			io.Recursive(true),
+			io.CopySourceDirectory(false),
+		)
+		test.Ok(t, err)
+		test.Equal(t, contents(t, filepath.Join(root, "dst", "a.txt")), "src/a.txt")
+		test.Equal(t, contents(t, filepath.Join(root, "dst", "existing.txt")), "dst/existing.txt")
+	})
+
+	t.Run("symlinks", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks require elevated privileges on windows")
+		}
+
+		root := makeTree(t, "src/a.txt")
+		test.Ok(t, os.Symlink("a.txt", filepath.Join(root, "src", "link")))
+
+		test.Ok(t, io.Cp(filepath.Join(root, "src"), filepath.Join(root, "dst"), io.Recursive(true)))
+
+		link, err := os.Readlink(filepath.Join(root, "dst", "link"))
+		test.Ok(t, err)
+		test.Equal(t, link, "a.txt")
+	})
+}
+
+func TestMv(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		root := makeTree(t, "a.txt")
+
+		test.Ok(t, io.Mv(filepath.Join(root, "a.txt"), filepath.Join(root, "new", "b.txt")))
+		test.False(t, exists(filepath.Join(root, "a.txt")))
+		test.Equal(t, contents(t, filepath.Join(root, "new", "b.txt")), "a.txt")
+	})
+
+	t.Run("into directory", func(t *testing.T) {
+		root := makeTree(t, "src/a.txt", "dst/b.txt")
+
+		test.Ok(t, io.Mv(filepath.Join(root, "src"), filepath.Join(root, "dst")))
+		test.False(t, exists(filepath.Join(root, "src")))
+		test.Equal(t, contents(t, filepath.Join(root, "dst", "src", "a.txt")), "src/a.txt")
+	})
+
+	t.Run("replaces", func(t *testing.T) {
+		root := makeTree(t, "a.txt", "b.txt")
+
+		test.Ok(t, io.Mv(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")))
+		test.Equal(t, contents(t, filepath.Join(root, "b.txt")), "a.txt")
+	})
+
+	t.Run("no force", func(t *testing.T) {
+		root := makeTree(t, "a.txt", "b.txt")
+
+		err := io.Mv(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt"), io.Force(false))
+		test.Err(t, err)
+		test.Equal(t, contents(t, filepath.Join(root, "a.txt")), "a.txt")
+		test.Equal(t, contents(t, filepath.Join(root, "b.txt")), "b.txt")
+	})
+}
+
+func TestRmRF(t *testing.T) {
+	t.Run("read-only", func(t *testing.T) {
+		root := makeTree(t, "dir/a.txt", "dir/nested/b.txt")
+
+		test.Ok(t, os.Chmod(filepath.Join(root, "dir", "a.txt"), 0o444))
+		test.Ok(t, os.Chmod(filepath.Join(root, "dir", "nested"), 0o555))
+
+		test.Ok(t, io.RmRF(filepath.Join(root, "dir")))
+		test.False(t, exists(filepath.Join(root, "dir")))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		test.Ok(t, io.RmRF(filepath.Join(t.TempDir(), "missing")))
+	})
+}
+
+func TestMkdirP(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b", "c")
+
+	test.Ok(t, io.MkdirP(dir))
+	test.Ok(t, io.MkdirP(dir)) // Already exists is fine
+
+	info, err := os.Stat(dir)
+	test.Ok(t, err)
+	test.True(t, info.IsDir())
+
+	test.Err(t, io.MkdirP(""))
+}
+
+func TestWhich(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executables are identified by $PATHEXT on windows")
+	}
+
+	first := makeTree(t, "tool", "data.txt")
+	second := makeTree(t, "tool", "other")
+
+	test.Ok(t, os.Chmod(filepath.Join(first, "tool"), 0o755))
+	test.Ok(t, os.Chmod(filepath.Join(second, "tool"), 0o755))
+	test.Ok(t, os.Chmod(filepath.Join(second, "other"), 0o755))
+
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	t.Run("first on path", func(t *testing.T) {
+		got, err := io.Which("tool", true)
+		test.Ok(t, err)
+		test.Equal(t, got, filepath.Join(first, "tool"))
+	})
+
+	t.Run("later on path", func(t *testing.T) {
+		got, err := io.Which("other", true)
+		test.Ok(t, err)
+		test.Equal(t, got, filepath.Join(second, "other"))
+	})
+
+	t.Run("not executable", func(t *testing.T) {
+		got, err := io.Which("data.txt", false)
+		test.Ok(t, err)
+		test.Equal(t, got, "")
+	})
+
+	t.Run("absolute", func(t *testing.T) {
+		got, err := io.Which(filepath.Join(second, "other"), true)
+		test.Ok(t, err)
+		test.Equal(t, got, filepath.Join(second, "other"))
+	})
+
+	t.Run("relative", func(t *testing.T) {
+		t.Chdir(filepath.Dir(second))
+
+		tool := filepath.Join(".", filepath.Base(second), "other")
+
+		got, err := io.Which(tool, true)
+		test.Ok(t, err)
+		test.Equal(t, got, tool)
+
+		_, err = io.Which(filepath.Join(".", filepath.Base(second), "missing"), true)
+		test.Err(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		got, err := io.Which("missing", false)
+		test.Ok(t, err)
+		test.Equal(t, got, "")
+
+		_, err = io.Which("missing", true)
+		test.Err(t, err)
+
+		var notFound *io.NotFoundError
+
+		test.True(t, errors.As(err, &notFound))
+		test.Equal(t, notFound.Tool, "missing")
+	})
+
+	t.Run("added path", func(t *testing.T) {
+		added := makeTree(t, "installed")
+		test.Ok(t, os.Chmod(filepath.Join(added, "installed"), 0o755))
+
+		githubPath := filepath.Join(t.TempDir(), "path.txt")
+		test.Ok(t, os.WriteFile(githubPath, nil, 0o644))
+		t.Setenv("GITHUB_PATH", githubPath)
+
+		test.Ok(t, actions.AddPath(added))
+
+		got, err := io.Which("installed", true)
+		test.Ok(t, err)
+		test.Equal(t, got, filepath.Join(added, "installed"))
+	})
+}
@@ -0,0 +1,232 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//nolint:gochecknoglobals // These are built once and reused.
+var (
+	// messageUnescaper reverses messageEscaper.
+	messageUnescaper = strings.NewReplacer(
+		"%0D", "\r",
+		"%0A", "\n",
+		"%25", "%",
+	)
+
+	// propertyUnescaper reverses propertyEscaper.
+	propertyUnescaper = strings.NewReplacer(
+		"%0D", "\r",
+		"%0A", "\n",
+		"%3A", ":",
+		"%2C", ",",
+		"%25", "%",
+	)
+
+	// annotationProperties are the properties of annotation commands, in the order
+	// they are written by the [Logger].
+	annotationProperties = []string{"title", "file", "line", "endLine", "col", "endColumn"}
+)
+
+// Command is a single workflow command, the decoded form of a "::name key=value::message" line
+// in the workflow log.
+type Command struct {
+	Properties map[string]string // The properties of the command e.g. "file" or "line", nil if none
+	Name       string            // The name of the command e.g. "error" or "group"
+	Message    string            // The message of the command
+}
+
+// ParseCommand decodes a single line of the workflow log into a [Command], reporting
+// whether the line was a workflow command at all.
+//
+// The message and property values are unescaped, exactly reversing the escaping done
+// by the [Logger].
+func ParseCommand(line string) (Command, bool) {
+	line = strings.TrimRight(line, "\r\n")
+
+	rest, ok := strings.CutPrefix(line, "::")
+	if !ok {
+		return Command{}, false
+	}
+
+	head, message, ok := strings.Cut(rest, "::")
+	if !ok {
+		return Command{}, false
+	}
+
+	name, properties, _ := strings.Cut(head, " ")
+	if name == "" {
+		return Command{}, false
+	}
+
+	command := Command{Name: name, Message: messageUnescaper.Replace(message)}
+
+	for property := range strings.SplitSeq(properties, ",") {
+		key, value, ok := strings.Cut(property, "=")
+		if !ok || key == "" {
+			continue
+		}
+
+		if command.Properties == nil {
+			command.Properties = make(map[string]string)
+		}
+
+		command.Properties[key] = propertyUnescaper.Replace(value)
+	}
+
+	return command, true
+}
+
+// Commands returns an iterator over the workflow commands in the log read from r, line
+// by line, skipping any lines that aren't commands.
+//
+// Like the runner, commands are not processed between a "stop-commands" command and
+// the line resuming them, so nothing in between is yielded. If reading from r fails
+// the error is yielded and iteration stops.
+func Commands(r io.Reader) iter.Seq2[Command, error] {
+	return func(yield func(Command, error) bool) {
+		reader := bufio.NewReader(r)
+		resume := "" // The line that resumes commands, empty if they aren't stopped
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				yield(Command{}, err)
+				return
+			}
+
+			if line != "" {
+				line = strings.TrimRight(line, "\r\n")
+
+				switch {
+				case resume != "":
+					if line == resume {
+						resume = ""
+					}
+				default:
+					command, ok := ParseCommand(line)
+					if !ok {
+						break
+					}
+
+					if command.Name == "stop-commands" {
+						resume = "::" + command.Message + "::"
+					}
+
+					if !yield(command, nil) {
+						return
+					}
+				}
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// String renders the command as a workflow command line, without a trailing newline,
+// escaping the message and properties the same way as the [Logger].
+//
+// Annotation properties are written in the same order as the [Logger] writes them, any
+// others follow sorted by key.
+func (c Command) String() string {
+	s := &strings.Builder{}
+	s.WriteString("::")
+	s.WriteString(c.Name)
+
+	keys := make([]string, 0, len(c.Properties))
+	for _, key := range annotationProperties {
+		if _, ok := c.Properties[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var others []string
+
+	for key := range c.Properties {
+		if !slices.Contains(annotationProperties, key) {
+			others = append(others, key)
+		}
+	}
+
+	slices.Sort(others)
+	keys = append(keys, others...)
+
+	for i, key := range keys {
+		if i == 0 {
+			s.WriteByte(' ')
+		} else {
+			s.WriteByte(',')
+		}
+
+		s.WriteString(key)
+		s.WriteByte('=')
+		s.WriteString(propertyEscaper.Replace(c.Properties[key]))
+	}
+
+	s.WriteString("::")
+	s.WriteString(messageEscaper.Replace(c.Message))
+
+	return s.String()
+}
+
+// Annotations returns the annotations described by the command's properties, so that an
+// annotation command read from elsewhere, e.g. the output of another tool, can be
+// written with a [Logger]:
+//
+//	for command, err := range log.Commands(output) {
+//		// ...
+//		if command.Name == "error" {
+//			logger.Error(command.Message, command.Annotations()...)
+//		}
+//	}
+//
+// Properties that aren't annotations, or whose values are invalid, are ignored.
+func (c Command) Annotations() []Annotation {
+	var annotations []Annotation
+
+	if title, ok := c.Properties["title"]; ok {
+		annotations = append(annotations, Title(title))
+	}
+
+	if file, ok := c.Properties["file"]; ok {
+		annotations = append(annotations, File(file))
+	}
+
+	if line := c.uint("line"); line != 0 {
+		end := c.uint("endLine")
+		if end == 0 {
+			end = line
+		}
+
+		annotations = append(annotations, Lines(line, end))
+	}
+
+	if col := c.uint("col"); col != 0 {
+		end := c.uint("endColumn")
+		if end == 0 {
+			end = col
+		}
+
+		annotations = append(annotations, Span(col, end))
+	}
+
+	return annotations
+}
+
+// uint returns the unsigned integer value of the property key, or 0 if it's missing or invalid.
+func (c Command) uint(key string) uint {
+	n, err := strconv.ParseUint(c.Properties[key], 10, 0)
+	if err != nil {
+		return 0
+	}
+
+	return uint(n)
+}
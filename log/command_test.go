@@ -0,0 +1,175 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		want log.Command // Expected command
+		name string      // Name of the test case
+		line string      // Line to parse
+		ok   bool        // Expected ok
+	}{
+		{
+			name: "empty",
+			line: "",
+			ok:   false,
+		},
+		{
+			name: "not a command",
+			line: "just some output",
+			ok:   false,
+		},
+		{
+			name: "unterminated",
+			line: "::error message",
+			ok:   false,
+		},
+		{
+			name: "no name",
+			line: ":: key=value::message",
+			ok:   false,
+		},
+		{
+			name: "no message",
+			line: "::endgroup::",
+			want: log.Command{Name: "endgroup"},
+			ok:   true,
+		},
+		{
+			name: "message",
+			line: "::notice::hello there\n",
+			want: log.Command{Name: "notice", Message: "hello there"},
+			ok:   true,
+		},
+		{
+			name: "escaped message",
+			line: "::debug::100%25 %0D%0Adone:: really\r\n",
+			want: log.Command{Name: "debug", Message: "100% \r\ndone:: really"},
+			ok:   true,
+		},
+		{
+			name: "properties",
+			line: "::error file=main.go,line=3,title=Percent %25 colon %3A comma %2C,bogus::oh no",
+			want: log.Command{
+				Name:       "error",
+				Message:    "oh no",
+				Properties: map[string]string{"file": "main.go", "line": "3", "title": "Percent % colon : comma ,"},
+			},
+			ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := log.ParseCommand(tt.line)
+			test.Equal(t, ok, tt.ok)
+			test.Equal(t, got.Name, tt.want.Name)
+			test.Equal(t, got.Message, tt.want.Message)
+			test.EqualFunc(t, got.Properties, tt.want.Properties, maps.Equal)
+		})
+	}
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New(buf)
+
+	message := "tricky % message\r\nwith :: and , in it"
+
+	logger.Error(
+		message,
+		log.Title("A title: with, everything %\n"),
+		log.File("dir/main.go"),
+		log.Lines(4, 4),
+		log.Span(2, 10),
+	)
+
+	line := strings.TrimSuffix(buf.String(), "\n")
+
+	command, ok := log.ParseCommand(line)
+	test.True(t, ok)
+	test.Equal(t, command.Name, "error")
+	test.Equal(t, command.Message, message)
+	test.Equal(t, command.Properties["title"], "A title: with, everything %\n")
+
+	// Rendering it again gives exactly the same line
+	test.Equal(t, command.String(), line)
+
+	// As do its annotations
+	relayed := &bytes.Buffer{}
+	log.New(relayed).Error(command.Message, command.Annotations()...)
+	test.Equal(t, relayed.String(), buf.String())
+}
+
+func TestCommandString(t *testing.T) {
+	command := log.Command{
+		Name:       "custom",
+		Message:    "50%",
+		Properties: map[string]string{"zebra": "z", "alpha": "a,b", "file": "x.go"},
+	}
+
+	test.Equal(t, command.String(), "::custom file=x.go,alpha=a%2Cb,zebra=z::50%25")
+	test.Equal(t, log.Command{Name: "endgroup"}.String(), "::endgroup::")
+}
+
+func TestCommands(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New(buf)
+
+	logger.StartGroup("Build")
+	logger.Write([]byte("plain output\n"))
+	logger.StopCommands(func() {
+		logger.Warning("ignored while stopped")
+	})
+	logger.Notice("after resuming")
+	logger.EndGroup()
+	logger.Write([]byte("::debug::no trailing newline"))
+
+	var got []log.Command
+
+	for command, err := range log.Commands(buf) {
+		test.Ok(t, err)
+
+		got = append(got, command)
+	}
+
+	names := make([]string, 0, len(got))
+	for _, command := range got {
+		names = append(names, command.Name)
+	}
+
+	test.Equal(t, strings.Join(names, ","), "group,stop-commands,notice,endgroup,debug")
+	test.Equal(t, got[2].Message, "after resuming")
+	test.Equal(t, got[4].Message, "no trailing newline")
+}
+
+func TestCommandsError(t *testing.T) {
+	boom := errors.New("boom")
+
+	var errs []error
+
+	for _, err := range log.Commands(failingReader{err: boom}) {
+		errs = append(errs, err)
+	}
+
+	test.Equal(t, len(errs), 1)
+	test.True(t, errors.Is(errs[0], boom))
+}
+
+// failingReader is an io.Reader that always fails.
+type failingReader struct {
+	err error
+}
+
+func (f failingReader) Read([]byte) (int, error) {
+	return 0, f.err
+}
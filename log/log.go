@@ -5,10 +5,12 @@
 package log // import "go.followtheprocess.codes/actions/log"
 
 import (
+	"crypto/rand"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
 )
 
 // messageEscaper escapes disallowed characters in workflow log messages.
@@ -21,9 +23,39 @@ var messageEscaper = strings.NewReplacer(
 )
 
 // Logger is the actions logger, it maintains no state other than an [io.Writer]
-// which is where the logs will be printed.
+// which is where the logs will be printed, and whether the last thing written to it
+// ended a line.
 type Logger struct {
-	out io.Writer
+	out *writer
+}
+
+// writer is the [io.Writer] a [Logger] writes to, remembering the last byte written
+// so a command can always be started on a new line. It is shared by copies of the Logger.
+type writer struct {
+	out  io.Writer  // Where the logs are printed
+	mu   sync.Mutex // Protects last
+	last byte       // The last byte written, 0 if nothing has been
+}
+
+// Write implements [io.Writer] for writer.
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.out.Write(p)
+	if n > 0 {
+		w.last = p[n-1]
+	}
+
+	return n, err
+}
+
+// midLine reports whether the last thing written didn't end a line.
+func (w *writer) midLine() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.last != 0 && w.last != '\n'
 }
 
 // New returns a new [Logger] configured to write to out.
@@ -33,7 +65,7 @@ type Logger struct {
 //
 //	logger := log.New(os.Stdout)
 func New(out io.Writer) Logger {
-	return Logger{out: out}
+	return Logger{out: &writer{out: out}}
 }
 
 // IsDebug reports whether the actions runner is running in
@@ -177,6 +209,15 @@ func (l Logger) WithGroup(title string, fn func()) {
 	fn()
 }
 
+// Write writes p verbatim to the workflow log, allowing a [Logger] to be used
+// as an [io.Writer] e.g. to stream the output of an external command into the log.
+//
+// No escaping is performed, so anything in p that looks like a workflow command will
+// be interpreted as such by the runner.
+func (l Logger) Write(p []byte) (n int, err error) {
+	return l.out.Write(p)
+}
+
 // Mask redacts a string or environment variable, preventing it from being printed in the workflow logs.
 //
 // When masked, the string or variable is replaced by `*` characters in subsequent logs. If str is
@@ -196,6 +237,86 @@ func (l Logger) Mask(str string) {
 	fmt.Fprintf(l.out, "::add-mask::%s\n", str)
 }
 
+// Echo enables or disables echoing of workflow commands to the log.
+//
+// By default workflow commands are not echoed to the log, but enabling this shows
+// each command as it is processed which can be helpful when debugging. Commands are
+// always echoed when debug logging is enabled, regardless of this setting.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#echoing-command-outputs
+func (l Logger) Echo(on bool) {
+	if on {
+		fmt.Fprintln(l.out, "::echo::on")
+	} else {
+		fmt.Fprintln(l.out, "::echo::off")
+	}
+}
+
+// StopCommands executes the provided closure fn with processing of workflow commands
+// disabled, so that nothing printed by fn will be interpreted as a workflow command.
+//
+// This should be used whenever printing untrusted output, e.g. from an external tool
+// or user supplied file, that could otherwise inject commands into the workflow.
+//
+// A random, unguessable token is generated to stop commands, which is then used to resume
+// command processing once fn returns, so the untrusted output cannot resume it early.
+//
+// Note that this also means workflow commands written by this logger inside fn will
+// not be processed either.
+//
+// The command resuming processing must be on a line of its own, so if the last thing fn
+// writes to the Logger doesn't end in a newline, one is added first. Output written by fn
+// somewhere other than the Logger, e.g. straight to [os.Stdout], can't be seen by it and so
+// must end in a newline itself.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#stopping-and-starting-workflow-commands
+func (l Logger) StopCommands(fn func()) {
+	token := rand.Text()
+
+	fmt.Fprintf(l.out, "::stop-commands::%s\n", token)
+
+	defer func() {
+		if l.out.midLine() {
+			fmt.Fprintln(l.out)
+		}
+
+		fmt.Fprintf(l.out, "::%s::\n", token)
+	}()
+
+	fn()
+}
+
+// AddMatcher registers the problem matcher(s) defined in the JSON file at path with the runner.
+//
+// Once registered, any output in the workflow log matching the problem matcher will be
+// surfaced as an annotation. The path must be accessible to the runner, i.e. on the runner's
+// filesystem rather than only inside a container. If path is the empty string "", nothing
+// will be logged.
+//
+// See https://github.com/actions/toolkit/blob/main/docs/problem-matchers.md
+func (l Logger) AddMatcher(path string) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return
+	}
+
+	fmt.Fprintf(l.out, "::add-matcher::%s\n", messageEscaper.Replace(path))
+}
+
+// RemoveMatcher unregisters a previously added problem matcher by its owner.
+//
+// If owner is the empty string "", nothing will be logged.
+//
+// See https://github.com/actions/toolkit/blob/main/docs/problem-matchers.md
+func (l Logger) RemoveMatcher(owner string) {
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return
+	}
+
+	fmt.Fprintf(l.out, "::remove-matcher owner=%s::\n", propertyEscaper.Replace(owner))
+}
+
 // log renders an annotated message (cmd = notice | warning | error).
 //
 // It's behaviour is common to all annotations.
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"go.followtheprocess.codes/actions/log"
@@ -396,6 +397,18 @@ func TestWithGroup(t *testing.T) {
 	test.Diff(t, got, want)
 }
 
+func TestWrite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New(buf)
+
+	fmt.Fprintf(logger, "raw output 100%%\n")
+
+	got := buf.String()
+	want := "raw output 100%\n" // Not escaped
+
+	test.Equal(t, got, want)
+}
+
 func TestMask(t *testing.T) {
 	tests := []struct {
 		name string // Name of the test case
@@ -442,6 +455,145 @@ func TestMask(t *testing.T) {
 	}
 }
 
+func TestEcho(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New(buf)
+
+	logger.Echo(true)
+	logger.Echo(false)
+
+	got := buf.String()
+	want := "::echo::on\n::echo::off\n"
+
+	test.Equal(t, got, want)
+}
+
+func TestStopCommands(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New(buf)
+
+	logger.StopCommands(func() {
+		fmt.Fprintln(buf, "::error::injected by untrusted output")
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	test.Equal(t, len(lines), 3)
+
+	token, ok := strings.CutPrefix(lines[0], "::stop-commands::")
+	test.True(t, ok, test.Context("first line was %q", lines[0]))
+	test.True(t, len(token) >= 16, test.Context("token %q is too short to be unguessable", token))
+
+	test.Equal(t, lines[1], "::error::injected by untrusted output")
+	test.Equal(t, lines[2], "::"+token+"::")
+
+	// Each call should get a different token
+	buf.Reset()
+	logger.StopCommands(func() {})
+
+	other, _ := strings.CutPrefix(strings.Split(buf.String(), "\n")[0], "::stop-commands::")
+	test.True(t, other != token)
+}
+
+func TestStopCommandsNoTrailingNewline(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.New(buf)
+
+	logger.StopCommands(func() {
+		fmt.Fprint(logger, "partial line")
+	})
+
+	lines := strings.Split(buf.String(), "\n")
+	test.Equal(t, len(lines), 4) // Including the empty string after the final newline
+
+	token, ok := strings.CutPrefix(lines[0], "::stop-commands::")
+	test.True(t, ok, test.Context("first line was %q", lines[0]))
+
+	// The resume command must be on its own line or the runner never sees it
+	test.Equal(t, lines[1], "partial line")
+	test.Equal(t, lines[2], "::"+token+"::")
+	test.Equal(t, lines[3], "")
+
+	// Already ending in a newline, nothing extra is added
+	buf.Reset()
+	logger.StopCommands(func() {
+		fmt.Fprintln(logger, "whole line")
+	})
+
+	test.Equal(t, strings.Count(buf.String(), "\n"), 3)
+}
+
+func TestAddMatcher(t *testing.T) {
+	tests := []struct {
+		name string // Name of the test case
+		path string // Path to the matcher file
+		want string // Expected output
+	}{
+		{
+			name: "empty",
+			path: "",
+			want: "",
+		},
+		{
+			name: "valid",
+			path: "/home/runner/work/_temp/matcher.json",
+			want: "::add-matcher::/home/runner/work/_temp/matcher.json\n",
+		},
+		{
+			name: "trimmed",
+			path: "  matcher.json\n",
+			want: "::add-matcher::matcher.json\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := log.New(buf)
+
+			logger.AddMatcher(tt.path)
+
+			got := buf.String()
+			test.Diff(t, got, tt.want)
+		})
+	}
+}
+
+func TestRemoveMatcher(t *testing.T) {
+	tests := []struct {
+		name  string // Name of the test case
+		owner string // Owner of the matcher
+		want  string // Expected output
+	}{
+		{
+			name:  "empty",
+			owner: "",
+			want:  "",
+		},
+		{
+			name:  "valid",
+			owner: "golangci-lint",
+			want:  "::remove-matcher owner=golangci-lint::\n",
+		},
+		{
+			name:  "escaped",
+			owner: "owner:with,chars",
+			want:  "::remove-matcher owner=owner%3Awith%2Cchars::\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := log.New(buf)
+
+			logger.RemoveMatcher(tt.owner)
+
+			got := buf.String()
+			test.Diff(t, got, tt.want)
+		})
+	}
+}
+
 func BenchmarkLog(b *testing.B) {
 	logger := log.New(io.Discard)
 
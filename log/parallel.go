@@ -0,0 +1,137 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Task is a unit of work run concurrently with other tasks by [Logger.Parallel].
+type Task struct {
+	Run   func(ctx context.Context, logger Logger) error // The work to do, logging to logger
+	Title string                                         // Title of the task's group in the log
+}
+
+// Parallel runs tasks concurrently, at most limit at a time or all at once if limit < 1, and
+// waits for them to finish.
+//
+// The runner doesn't support interleaved groups, so rather than writing straight to the log,
+// each task logs to its own [Logger] which is buffered until the task completes. The buffered
+// output is then written as a single group, titled with whether the task passed or failed and
+// how long it took:
+//
+//	::group::lint (passed in 1.2s)
+//	...
+//	::endgroup::
+//
+// Groups are written in the order the tasks complete. As groups can't be nested, tasks should
+// not start groups of their own.
+//
+// If a task fails its error is logged at the end of its group and returned, along with those of
+// any other failed tasks, prefixed with its title. A task that panics fails with the panic and
+// its stack trace as the error. Failed tasks don't stop the others, but once ctx is cancelled
+// no more tasks are started and the context's error is returned too.
+func (l Logger) Parallel(ctx context.Context, limit int, tasks ...Task) error {
+	if limit < 1 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex // Protects writes to l and errs
+		errs    []error
+		started int
+	)
+
+	slots := make(chan struct{}, limit)
+
+start:
+	for i, task := range tasks {
+		select {
+		case <-ctx.Done():
+			break start
+		case slots <- struct{}{}:
+			// Both may have been ready, don't start anything after cancellation
+			if ctx.Err() != nil {
+				<-slots
+				break start
+			}
+		}
+
+		started++
+
+		wg.Go(func() {
+			defer func() { <-slots }()
+
+			buf := &bytes.Buffer{}
+			begin := time.Now()
+			err := task.run(ctx, New(buf))
+			elapsed := time.Since(begin)
+
+			title := task.Title
+			if title == "" {
+				title = fmt.Sprintf("Task %d", i+1)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			l.flush(title, buf.Bytes(), elapsed, err)
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", title, err))
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if started < len(tasks) {
+		skipped := len(tasks) - started
+		errs = append(errs, fmt.Errorf("%d of %d tasks not started: %w", skipped, len(tasks), context.Cause(ctx)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// run calls the task's Run function, recovering a panic as the task's error so that it fails
+// like any other task rather than crashing the process and losing the buffered groups.
+func (t Task) run(ctx context.Context, logger Logger) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			if cause, ok := value.(error); ok {
+				err = fmt.Errorf("panic: %w\n\n%s", cause, debug.Stack())
+			} else {
+				err = fmt.Errorf("panic: %v\n\n%s", value, debug.Stack())
+			}
+		}
+	}()
+
+	return t.Run(ctx, logger)
+}
+
+// flush writes the buffered output of a completed task to the log as a single group.
+func (l Logger) flush(title string, output []byte, elapsed time.Duration, err error) {
+	status := "passed"
+	if err != nil {
+		status = "failed"
+	}
+
+	l.StartGroup(fmt.Sprintf("%s (%s in %s)", title, status, elapsed.Round(time.Millisecond)))
+	defer l.EndGroup()
+
+	fmt.Fprintf(l.out, "%s", output)
+
+	// The group must be closed on a line of its own
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		fmt.Fprintln(l.out)
+	}
+
+	if err != nil {
+		l.Error(err.Error())
+	}
+}
@@ -0,0 +1,249 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+func TestParallel(t *testing.T) {
+	t.Run("contiguous groups", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		// Every task waits for all the others to start, so their output is interleaved
+		const n = 5
+
+		var (
+			started atomic.Int32
+			all     = make(chan struct{})
+		)
+
+		tasks := make([]log.Task, 0, n)
+		for i := range n {
+			name := fmt.Sprintf("task%d", i)
+			tasks = append(tasks, log.Task{
+				Title: name,
+				Run: func(ctx context.Context, logger log.Logger) error {
+					if started.Add(1) == n {
+						close(all)
+					}
+
+					for line := range 3 {
+						<-all
+						fmt.Fprintf(logger, "%s line %d\n", name, line)
+					}
+
+					logger.Notice(name + " done")
+
+					return nil
+				},
+			})
+		}
+
+		err := logger.Parallel(t.Context(), 0, tasks...)
+		test.Ok(t, err)
+
+		title := regexp.MustCompile(`^::group::(task\d) \(passed in \S+\)$`)
+
+		group := "" // The task whose group we're in
+		groups := 0
+
+		for line := range strings.Lines(buf.String()) {
+			line = strings.TrimSuffix(line, "\n")
+
+			switch {
+			case line == "::endgroup::":
+				test.True(t, group != "", test.Context("endgroup outside a group"))
+				group = ""
+			case strings.HasPrefix(line, "::group::"):
+				test.Equal(t, group, "", test.Context("nested group %q", line))
+
+				match := title.FindStringSubmatch(line)
+				test.True(t, match != nil, test.Context("unexpected group title %q", line))
+
+				group = match[1]
+				groups++
+			default:
+				// Everything else belongs to the group it's in
+				test.True(t, group != "", test.Context("%q outside a group", line))
+				test.True(t, strings.Contains(line, group), test.Context("%q in group %s", line, group))
+			}
+		}
+
+		test.Equal(t, group, "")
+		test.Equal(t, groups, n)
+	})
+
+	t.Run("completion order", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		second := make(chan struct{})
+
+		err := logger.Parallel(t.Context(), 2,
+			log.Task{
+				Title: "slow",
+				Run: func(ctx context.Context, logger log.Logger) error {
+					<-second
+					return nil
+				},
+			},
+			log.Task{
+				Title: "fast",
+				Run: func(ctx context.Context, logger log.Logger) error {
+					defer close(second)
+					fmt.Fprint(logger, "no trailing newline")
+
+					return nil
+				},
+			},
+		)
+		test.Ok(t, err)
+
+		got := regexp.MustCompile(`in \S+\)`).ReplaceAllString(buf.String(), "in <duration>)")
+		want := "::group::fast (passed in <duration>)\n" +
+			"no trailing newline\n" +
+			"::endgroup::\n" +
+			"::group::slow (passed in <duration>)\n" +
+			"::endgroup::\n"
+
+		test.Equal(t, got, want)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		const limit = 3
+
+		var running, peak atomic.Int32
+
+		tasks := make([]log.Task, 20)
+		for i := range tasks {
+			tasks[i].Run = func(ctx context.Context, logger log.Logger) error {
+				now := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					old := peak.Load()
+					if now <= old || peak.CompareAndSwap(old, now) {
+						break
+					}
+				}
+
+				logger.Notice("working")
+
+				return nil
+			}
+		}
+
+		err := log.New(&bytes.Buffer{}).Parallel(t.Context(), limit, tasks...)
+		test.Ok(t, err)
+
+		test.True(t, peak.Load() <= limit, test.Context("peak concurrency %d > limit %d", peak.Load(), limit))
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		sentinel := errors.New("tests failed")
+
+		err := logger.Parallel(t.Context(), 1,
+			log.Task{
+				Title: "test",
+				Run: func(ctx context.Context, logger log.Logger) error {
+					fmt.Fprintln(logger, "running tests")
+					return sentinel
+				},
+			},
+			log.Task{
+				Run: func(ctx context.Context, logger log.Logger) error {
+					return nil
+				},
+			},
+		)
+		test.Err(t, err)
+		test.True(t, errors.Is(err, sentinel))
+		test.Equal(t, err.Error(), "test: tests failed")
+
+		got := regexp.MustCompile(`in \S+\)`).ReplaceAllString(buf.String(), "in <duration>)")
+		want := "::group::test (failed in <duration>)\n" +
+			"running tests\n" +
+			"::error::tests failed\n" +
+			"::endgroup::\n" +
+			"::group::Task 2 (passed in <duration>)\n" +
+			"::endgroup::\n"
+
+		test.Equal(t, got, want)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		err := logger.Parallel(t.Context(), 1,
+			log.Task{
+				Title: "explode",
+				Run: func(ctx context.Context, logger log.Logger) error {
+					fmt.Fprintln(logger, "about to panic")
+					panic("boom")
+				},
+			},
+			log.Task{
+				Title: "fine",
+				Run: func(ctx context.Context, logger log.Logger) error {
+					return nil
+				},
+			},
+		)
+		test.Err(t, err)
+		test.True(t, strings.HasPrefix(err.Error(), "explode: panic: boom\n\ngoroutine "), test.Context("error: %v", err))
+		test.True(t, strings.Contains(err.Error(), "parallel_test.go"), test.Context("no stack trace: %v", err))
+
+		got := buf.String()
+		for _, want := range []string{
+			"::group::explode (failed in ",
+			"about to panic\n::error::panic: boom%0A%0Agoroutine ",
+			"::group::fine (passed in ",
+		} {
+			test.True(t, strings.Contains(got, want), test.Context("log missing %q:\n%s", want, got))
+		}
+
+		test.Equal(t, strings.Count(got, "::endgroup::"), 2)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		var ran atomic.Int32
+
+		task := log.Task{
+			Run: func(ctx context.Context, logger log.Logger) error {
+				ran.Add(1)
+				cancel()
+
+				return nil
+			},
+		}
+
+		err := log.New(&bytes.Buffer{}).Parallel(ctx, 1, task, task, task)
+		test.Err(t, err)
+		test.True(t, errors.Is(err, context.Canceled))
+		test.Equal(t, err.Error(), "2 of 3 tasks not started: context canceled")
+		test.Equal(t, ran.Load(), 1)
+	})
+
+	t.Run("no tasks", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		test.Ok(t, log.New(buf).Parallel(t.Context(), 4))
+		test.Equal(t, buf.String(), "")
+	})
+}
@@ -0,0 +1,402 @@
+// Package matcher implements GitHub Actions problem matchers.
+//
+// Problem matchers scan the output of a workflow step for lines matching a regular
+// expression, and surface the matches as annotations e.g. compiler errors or linter
+// warnings. This package provides Go types describing problem matcher JSON, a way of
+// registering matchers with the runner, and an in-process evaluator that applies a
+// matcher to text exactly as the runner would, so matchers can be unit tested.
+//
+// See https://github.com/actions/toolkit/blob/main/docs/problem-matchers.md
+package matcher // import "go.followtheprocess.codes/actions/matcher"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.followtheprocess.codes/actions/log"
+)
+
+// filePermissions is the permissions used when writing the matcher file.
+const filePermissions = 0o644
+
+// File is the top level structure of a problem matcher JSON file.
+type File struct {
+	ProblemMatcher []Matcher `json:"problemMatcher"` // The problem matchers defined in the file
+}
+
+// Matcher is a single problem matcher.
+type Matcher struct {
+	Owner    string    `json:"owner"`              // Unique identifier of the matcher, used to remove it
+	Severity string    `json:"severity,omitempty"` // Default severity of problems, "error" if unset
+	Pattern  []Pattern `json:"pattern"`            // The patterns to match, in order
+}
+
+// Pattern is a single regular expression within a [Matcher], along with the indices of
+// the capture groups containing each piece of information about a problem.
+//
+// An index of 0 means that piece of information is not captured by this pattern. Matchers
+// with multiple patterns match consecutive lines of output, each pattern capturing some
+// of the information that makes up the problem.
+//
+// The runner evaluates regular expressions using .NET semantics whereas [Matcher.Match]
+// uses Go's [regexp] package, so patterns should stick to the common subset of both.
+type Pattern struct {
+	Regexp    string `json:"regexp"`              // The regular expression to match
+	File      int    `json:"file,omitempty"`      // Group containing the file path
+	FromPath  int    `json:"fromPath,omitempty"`  // Group containing a path the file is relative to
+	Line      int    `json:"line,omitempty"`      // Group containing the line number
+	EndLine   int    `json:"endLine,omitempty"`   // Group containing the end line number
+	Column    int    `json:"column,omitempty"`    // Group containing the column number
+	EndColumn int    `json:"endColumn,omitempty"` // Group containing the end column number
+	Severity  int    `json:"severity,omitempty"`  // Group containing the severity e.g. "warning"
+	Code      int    `json:"code,omitempty"`      // Group containing the error code
+	Message   int    `json:"message,omitempty"`   // Group containing the message
+	Loop      bool   `json:"loop,omitempty"`      // Whether the last pattern may match repeatedly
+}
+
+// Problem is a single problem found by applying a [Matcher] to some text.
+type Problem struct {
+	File      string // Path of the file the problem is in
+	FromPath  string // Path the file is relative to, if any
+	Severity  string // Severity of the problem, one of "error", "warning" or "notice"
+	Code      string // The error code, if any
+	Message   string // The problem description
+	Line      int    // Line number of the problem, 0 if unknown
+	EndLine   int    // End line number of the problem, 0 if unknown
+	Column    int    // Column number of the problem, 0 if unknown
+	EndColumn int    // End column number of the problem, 0 if unknown
+}
+
+// Log writes the problem to the workflow log as an annotation, as the runner
+// would when the matcher is registered.
+//
+// The severity of the problem determines whether it is logged with [log.Logger.Error],
+// [log.Logger.Warning] or [log.Logger.Notice] and the code, if present, is used
+// as the annotation title.
+func (p Problem) Log(logger log.Logger) {
+	var annotations []log.Annotation
+
+	if p.Code != "" {
+		annotations = append(annotations, log.Title(p.Code))
+	}
+
+	if p.File != "" {
+		annotations = append(annotations, log.File(p.File))
+
+		if p.Line > 0 {
+			annotations = append(annotations, log.Lines(uint(p.Line), uint(max(p.EndLine, p.Line))))
+
+			if p.Column > 0 {
+				annotations = append(annotations, log.Span(uint(p.Column), uint(max(p.EndColumn, p.Column))))
+			}
+		}
+	}
+
+	switch p.Severity {
+	case "warning":
+		logger.Warning(p.Message, annotations...)
+	case "notice":
+		logger.Notice(p.Message, annotations...)
+	default:
+		logger.Error(p.Message, annotations...)
+	}
+}
+
+// Parse reads problem matcher JSON from r, validating each matcher.
+func Parse(r io.Reader) ([]Matcher, error) {
+	var file File
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("could not decode problem matcher: %w", err)
+	}
+
+	for _, matcher := range file.ProblemMatcher {
+		if err := matcher.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return file.ProblemMatcher, nil
+}
+
+// Register writes matchers to a temporary JSON file and registers them with the
+// runner using logger, returning a function that unregisters them again.
+//
+// The file is written to $RUNNER_TEMP if set, or the system temporary directory otherwise.
+//
+//	remove, err := matcher.Register(logger, golangciLint)
+//	if err != nil {
+//		// Handle error
+//	}
+//	defer remove()
+func Register(logger log.Logger, matchers ...Matcher) (remove func(), err error) {
+	if len(matchers) == 0 {
+		return nil, errors.New("no problem matchers to register")
+	}
+
+	for _, matcher := range matchers {
+		if err = matcher.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	contents, err := json.MarshalIndent(File{ProblemMatcher: matchers}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode problem matchers: %w", err)
+	}
+
+	dir := os.Getenv("RUNNER_TEMP")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	file, err := os.CreateTemp(dir, "problem-matcher-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("could not create problem matcher file: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Chmod(filePermissions); err != nil {
+		return nil, fmt.Errorf("could not set permissions on problem matcher file: %w", err)
+	}
+
+	if _, err := file.Write(contents); err != nil {
+		return nil, fmt.Errorf("could not write problem matcher file: %w", err)
+	}
+
+	logger.AddMatcher(file.Name())
+
+	remove = func() {
+		for _, matcher := range matchers {
+			logger.RemoveMatcher(matcher.Owner)
+		}
+
+		os.Remove(file.Name())
+	}
+
+	return remove, nil
+}
+
+// Validate checks the matcher is well formed according to the rules the runner applies.
+func (m Matcher) Validate() error {
+	_, err := m.compile()
+	return err
+}
+
+// Match applies the matcher to text, returning every problem found.
+//
+// Text is processed line by line exactly as the runner would process the output of a
+// workflow step, so this can be used to unit test a matcher without needing a runner.
+func (m Matcher) Match(text string) ([]Problem, error) {
+	compiled, err := m.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		problems []Problem
+		partial  Problem // The problem being built up across multiple lines
+		base     Problem // The problem as it was before the last (possibly looping) pattern
+		state    int     // Index of the pattern the next line should match
+	)
+
+	last := len(m.Pattern) - 1
+
+	for line := range strings.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+
+		if state > 0 {
+			if match := compiled[state].FindStringSubmatch(line); match != nil {
+				if state == last {
+					problem := base
+					m.Pattern[state].apply(&problem, match)
+					problems = m.emit(problems, problem)
+
+					if !m.Pattern[state].Loop {
+						state = 0
+					}
+				} else {
+					m.Pattern[state].apply(&partial, match)
+
+					state++
+					if state == last {
+						base = partial
+					}
+				}
+
+				continue
+			}
+
+			// The sequence was broken, start again from the first pattern with this line
+			state = 0
+		}
+
+		match := compiled[0].FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		partial = Problem{}
+		m.Pattern[0].apply(&partial, match)
+
+		if last == 0 {
+			problems = m.emit(problems, partial)
+			continue
+		}
+
+		state = 1
+		if state == last {
+			base = partial
+		}
+	}
+
+	return problems, nil
+}
+
+// compile validates the matcher and compiles each of its patterns.
+func (m Matcher) compile() ([]*regexp.Regexp, error) {
+	if strings.TrimSpace(m.Owner) == "" {
+		return nil, errors.New("problem matcher owner cannot be empty")
+	}
+
+	if len(m.Pattern) == 0 {
+		return nil, fmt.Errorf("problem matcher %q has no patterns", m.Owner)
+	}
+
+	switch strings.ToLower(m.Severity) {
+	case "", "error", "warning", "notice":
+	default:
+		return nil, fmt.Errorf("problem matcher %q has invalid severity: %q", m.Owner, m.Severity)
+	}
+
+	compiled := make([]*regexp.Regexp, 0, len(m.Pattern))
+	hasMessage := false
+
+	for i, pattern := range m.Pattern {
+		re, err := regexp.Compile(pattern.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("problem matcher %q pattern %d has invalid regexp: %w", m.Owner, i, err)
+		}
+
+		for _, index := range pattern.indices() {
+			if index < 0 || index > re.NumSubexp() {
+				return nil, fmt.Errorf(
+					"problem matcher %q pattern %d refers to group %d but regexp only has %d",
+					m.Owner,
+					i,
+					index,
+					re.NumSubexp(),
+				)
+			}
+		}
+
+		if pattern.Loop {
+			if len(m.Pattern) == 1 || i != len(m.Pattern)-1 {
+				return nil, fmt.Errorf(
+					"problem matcher %q pattern %d: only the last of multiple patterns may loop",
+					m.Owner,
+					i,
+				)
+			}
+
+			if pattern.Message == 0 {
+				return nil, fmt.Errorf("problem matcher %q pattern %d: a looping pattern must capture message", m.Owner, i)
+			}
+		}
+
+		if pattern.Message != 0 {
+			hasMessage = true
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	if !hasMessage {
+		return nil, fmt.Errorf("problem matcher %q does not capture a message in any pattern", m.Owner)
+	}
+
+	return compiled, nil
+}
+
+// emit appends problem to problems if it is complete, filling in the default severity.
+func (m Matcher) emit(problems []Problem, problem Problem) []Problem {
+	// The runner discards problems with no message
+	if problem.Message == "" {
+		return problems
+	}
+
+	severity := problem.Severity
+	if severity == "" {
+		severity = m.Severity
+	}
+
+	switch strings.ToLower(severity) {
+	case "warning":
+		problem.Severity = "warning"
+	case "notice":
+		problem.Severity = "notice"
+	default:
+		problem.Severity = "error"
+	}
+
+	return append(problems, problem)
+}
+
+// indices returns all the capture group indices referred to by the pattern.
+func (p Pattern) indices() []int {
+	return []int{p.File, p.FromPath, p.Line, p.EndLine, p.Column, p.EndColumn, p.Severity, p.Code, p.Message}
+}
+
+// apply sets the fields of problem captured by this pattern from match.
+func (p Pattern) apply(problem *Problem, match []string) {
+	if p.File != 0 {
+		problem.File = match[p.File]
+	}
+
+	if p.FromPath != 0 {
+		problem.FromPath = match[p.FromPath]
+	}
+
+	if p.Line != 0 {
+		problem.Line = atoi(match[p.Line])
+	}
+
+	if p.EndLine != 0 {
+		problem.EndLine = atoi(match[p.EndLine])
+	}
+
+	if p.Column != 0 {
+		problem.Column = atoi(match[p.Column])
+	}
+
+	if p.EndColumn != 0 {
+		problem.EndColumn = atoi(match[p.EndColumn])
+	}
+
+	if p.Severity != 0 {
+		problem.Severity = match[p.Severity]
+	}
+
+	if p.Code != 0 {
+		problem.Code = match[p.Code]
+	}
+
+	if p.Message != 0 {
+		problem.Message = match[p.Message]
+	}
+}
+
+// atoi parses s as an integer, returning 0 if it is not a valid one.
+func atoi(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
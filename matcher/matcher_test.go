@@ -0,0 +1,344 @@
+package matcher_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/actions/matcher"
+	"go.followtheprocess.codes/test"
+)
+
+// goVet is a single line matcher for `go vet` style output.
+var goVet = matcher.Matcher{
+	Owner: "go-vet",
+	Pattern: []matcher.Pattern{
+		{
+			Regexp:  `^(.+\.go):(\d+):(\d+): (.+)$`,
+			File:    1,
+			Line:    2,
+			Column:  3,
+			Message: 4,
+		},
+	},
+}
+
+// eslintStylish is a multi line, looping matcher for eslint's stylish output.
+var eslintStylish = matcher.Matcher{
+	Owner: "eslint-stylish",
+	Pattern: []matcher.Pattern{
+		{
+			Regexp: `^([^\s].*)$`,
+			File:   1,
+		},
+		{
+			Regexp:   `^\s+(\d+):(\d+)\s+(error|warning|info)\s+(.*)\s\s+(.*)$`,
+			Line:     1,
+			Column:   2,
+			Severity: 3,
+			Message:  4,
+			Code:     5,
+			Loop:     true,
+		},
+	},
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string            // Name of the test case
+		text    string            // Text to match against
+		want    []matcher.Problem // Expected problems
+		matcher matcher.Matcher   // The matcher under test
+	}{
+		{
+			name:    "no matches",
+			matcher: goVet,
+			text:    "ok  \tgo.followtheprocess.codes/actions\t0.01s\n",
+			want:    nil,
+		},
+		{
+			name:    "single line",
+			matcher: goVet,
+			text:    "# some/pkg\nmain.go:12:5: unreachable code\nother noise\nlib/a.go:1:1: missing return\n",
+			want: []matcher.Problem{
+				{File: "main.go", Line: 12, Column: 5, Message: "unreachable code", Severity: "error"},
+				{File: "lib/a.go", Line: 1, Column: 1, Message: "missing return", Severity: "error"},
+			},
+		},
+		{
+			name: "default severity",
+			matcher: matcher.Matcher{
+				Owner:    "todo",
+				Severity: "warning",
+				Pattern:  []matcher.Pattern{{Regexp: `TODO: (.+)`, Message: 1}},
+			},
+			text: "TODO: fix this\r\n",
+			want: []matcher.Problem{
+				{Message: "fix this", Severity: "warning"},
+			},
+		},
+		{
+			name:    "multi line loop",
+			matcher: eslintStylish,
+			text: strings.Join([]string{
+				"test.js",
+				"  1:0   error  Missing \"use strict\" statement                 strict",
+				"  5:10  warning  'addOne' is defined but never used           no-unused-vars",
+				"other.js",
+				"  2:3   error  Unexpected var                                no-var",
+				"",
+			}, "\n"),
+			want: []matcher.Problem{
+				{
+					File:     "test.js",
+					Line:     1,
+					Column:   0,
+					Severity: "error",
+					Message:  `Missing "use strict" statement               `,
+					Code:     "strict",
+				},
+				{
+					File:     "test.js",
+					Line:     5,
+					Column:   10,
+					Severity: "warning",
+					Message:  "'addOne' is defined but never used         ",
+					Code:     "no-unused-vars",
+				},
+				{
+					File:     "other.js",
+					Line:     2,
+					Column:   3,
+					Severity: "error",
+					Message:  "Unexpected var                              ",
+					Code:     "no-var",
+				},
+			},
+		},
+		{
+			name: "multi line broken sequence",
+			matcher: matcher.Matcher{
+				Owner: "two-line",
+				Pattern: []matcher.Pattern{
+					{Regexp: `^FILE (.+)$`, File: 1},
+					{Regexp: `^MSG (.+)$`, Message: 1},
+				},
+			},
+			text: "FILE a.go\nnoise\nMSG orphaned\nFILE b.go\nMSG real\nMSG not looping\n",
+			want: []matcher.Problem{
+				{File: "b.go", Message: "real", Severity: "error"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.matcher.Match(tt.text)
+			test.Ok(t, err)
+			test.EqualFunc(t, got, tt.want, slices.Equal, test.Context("got %#v", got))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string          // Name of the test case
+		errMsg  string          // Expected error message
+		matcher matcher.Matcher // The matcher under test
+	}{
+		{
+			name:    "no owner",
+			matcher: matcher.Matcher{Pattern: goVet.Pattern},
+			errMsg:  "problem matcher owner cannot be empty",
+		},
+		{
+			name:    "no patterns",
+			matcher: matcher.Matcher{Owner: "empty"},
+			errMsg:  `problem matcher "empty" has no patterns`,
+		},
+		{
+			name:    "bad severity",
+			matcher: matcher.Matcher{Owner: "bad", Severity: "fatal", Pattern: goVet.Pattern},
+			errMsg:  `problem matcher "bad" has invalid severity: "fatal"`,
+		},
+		{
+			name: "bad regexp",
+			matcher: matcher.Matcher{
+				Owner:   "bad",
+				Pattern: []matcher.Pattern{{Regexp: `(unclosed`, Message: 1}},
+			},
+			errMsg: "problem matcher \"bad\" pattern 0 has invalid regexp: error parsing regexp: missing closing ): `(unclosed`",
+		},
+		{
+			name: "group out of range",
+			matcher: matcher.Matcher{
+				Owner:   "bad",
+				Pattern: []matcher.Pattern{{Regexp: `(a)`, Message: 2}},
+			},
+			errMsg: `problem matcher "bad" pattern 0 refers to group 2 but regexp only has 1`,
+		},
+		{
+			name: "single pattern loop",
+			matcher: matcher.Matcher{
+				Owner:   "bad",
+				Pattern: []matcher.Pattern{{Regexp: `(a)`, Message: 1, Loop: true}},
+			},
+			errMsg: `problem matcher "bad" pattern 0: only the last of multiple patterns may loop`,
+		},
+		{
+			name: "loop without message",
+			matcher: matcher.Matcher{
+				Owner: "bad",
+				Pattern: []matcher.Pattern{
+					{Regexp: `(a)`, Message: 1},
+					{Regexp: `(b)`, File: 1, Loop: true},
+				},
+			},
+			errMsg: `problem matcher "bad" pattern 1: a looping pattern must capture message`,
+		},
+		{
+			name: "no message",
+			matcher: matcher.Matcher{
+				Owner:   "bad",
+				Pattern: []matcher.Pattern{{Regexp: `(a)`, File: 1}},
+			},
+			errMsg: `problem matcher "bad" does not capture a message in any pattern`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.matcher.Validate()
+			test.Err(t, err)
+			test.Equal(t, err.Error(), tt.errMsg)
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		test.Ok(t, goVet.Validate())
+		test.Ok(t, eslintStylish.Validate())
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		raw := `{
+			"problemMatcher": [
+				{
+					"owner": "go-vet",
+					"pattern": [{"regexp": "^(.+\\.go):(\\d+):(\\d+): (.+)$", "file": 1, "line": 2, "column": 3, "message": 4}]
+				}
+			]
+		}`
+
+		matchers, err := matcher.Parse(strings.NewReader(raw))
+		test.Ok(t, err)
+		test.Equal(t, len(matchers), 1)
+		test.Equal(t, matchers[0].Owner, "go-vet")
+		test.Equal(t, matchers[0].Pattern[0], goVet.Pattern[0])
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := matcher.Parse(strings.NewReader(`{"problemMatcher": [`))
+		test.Err(t, err)
+	})
+
+	t.Run("invalid matcher", func(t *testing.T) {
+		_, err := matcher.Parse(strings.NewReader(`{"problemMatcher": [{"owner": "x", "pattern": []}]}`))
+		test.Err(t, err)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		_, err := matcher.Register(log.New(&bytes.Buffer{}))
+		test.Err(t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := matcher.Register(log.New(&bytes.Buffer{}), matcher.Matcher{})
+		test.Err(t, err)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		tmp := t.TempDir()
+		t.Setenv("RUNNER_TEMP", tmp)
+
+		buf := &bytes.Buffer{}
+		logger := log.New(buf)
+
+		remove, err := matcher.Register(logger, goVet, eslintStylish)
+		test.Ok(t, err)
+
+		entries, err := os.ReadDir(tmp)
+		test.Ok(t, err)
+		test.Equal(t, len(entries), 1)
+
+		path := filepath.Join(tmp, entries[0].Name())
+		test.Equal(t, buf.String(), "::add-matcher::"+path+"\n")
+
+		// The written file should round trip
+		file, err := os.Open(path)
+		test.Ok(t, err)
+
+		defer file.Close()
+
+		matchers, err := matcher.Parse(file)
+		test.Ok(t, err)
+		test.Equal(t, len(matchers), 2)
+		test.Equal(t, matchers[1].Owner, "eslint-stylish")
+
+		buf.Reset()
+		remove()
+
+		want := "::remove-matcher owner=go-vet::\n::remove-matcher owner=eslint-stylish::\n"
+		test.Equal(t, buf.String(), want)
+
+		_, err = os.Stat(path)
+		test.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestProblemLog(t *testing.T) {
+	tests := []struct {
+		name    string          // Name of the test case
+		want    string          // Expected log output
+		problem matcher.Problem // The problem to log
+	}{
+		{
+			name:    "message only",
+			problem: matcher.Problem{Message: "bad things", Severity: "error"},
+			want:    "::error::bad things\n",
+		},
+		{
+			name: "full",
+			problem: matcher.Problem{
+				File:     "main.go",
+				Line:     3,
+				Column:   4,
+				Code:     "SA1000",
+				Message:  "invalid regexp",
+				Severity: "warning",
+			},
+			want: "::warning title=SA1000,file=main.go,line=3,endLine=3,col=4,endColumn=4::invalid regexp\n",
+		},
+		{
+			name:    "notice",
+			problem: matcher.Problem{File: "a.go", Line: 1, EndLine: 5, Message: "fyi", Severity: "notice"},
+			want:    "::notice file=a.go,line=1,endLine=5::fyi\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tt.problem.Log(log.New(buf))
+
+			test.Diff(t, buf.String(), tt.want)
+		})
+	}
+}
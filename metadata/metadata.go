@@ -0,0 +1,616 @@
+// Package metadata parses and validates GitHub Action metadata files (action.yml).
+//
+// Every action has a metadata file named either action.yml or action.yaml in its root
+// declaring its name, inputs, outputs and how it runs. This package parses that file into
+// typed structs so the Go side of an action can stay in sync with what it declares, and
+// validates it against the same rules GitHub applies, reporting problems with the line
+// number in the file they came from:
+//
+//	action, err := metadata.Load(os.Getenv("GITHUB_ACTION_PATH"))
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	for name, input := range action.Inputs {
+//		fmt.Println(name, input.Default)
+//	}
+//
+// See https://docs.github.com/en/actions/reference/workflows-and-actions/metadata-syntax
+package metadata // import "go.followtheprocess.codes/actions/metadata"
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Supported values of [Runs.Using].
+const (
+	UsingNode12    = "node12"
+	UsingNode16    = "node16"
+	UsingNode20    = "node20"
+	UsingNode24    = "node24"
+	UsingDocker    = "docker"
+	UsingComposite = "composite"
+)
+
+// idPattern matches valid input and output ids.
+var idPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// colors are the allowed values of [Branding.Color].
+//
+//nolint:gochecknoglobals // This is effectively a constant.
+var colors = []string{"white", "black", "yellow", "blue", "green", "orange", "red", "purple", "gray-dark"}
+
+// Action is a parsed action metadata file.
+type Action struct {
+	Inputs      map[string]Input  // The action's inputs keyed by id
+	Outputs     map[string]Output // The action's outputs keyed by id
+	Branding    Branding          // How the action appears on the marketplace
+	Name        string            // The name of the action
+	Author      string            // The name of the action's author
+	Description string            // A short description of the action
+	Runs        Runs              // How the action is run
+}
+
+// Input is an input parameter declared by an action.
+type Input struct {
+	Description        string // A description of the input
+	Default            string // The value used if the input is not supplied
+	DeprecationMessage string // If non-empty, the input is deprecated and this tells users what to use instead
+	Line               int    // The line in the metadata file on which the input is declared
+	Required           bool   // Whether the input must be supplied
+}
+
+// Deprecated reports whether the input has been deprecated.
+func (i Input) Deprecated() bool {
+	return i.DeprecationMessage != ""
+}
+
+// Output is an output declared by an action.
+type Output struct {
+	Description string // A description of the output
+	Value       string // The expression the output is mapped to, composite actions only
+	Line        int    // The line in the metadata file on which the output is declared
+}
+
+// Branding controls how the action appears on the GitHub marketplace.
+type Branding struct {
+	Icon  string // The name of a Feather icon
+	Color string // The background color of the badge
+}
+
+// Runs configures how the action is run.
+//
+// Which fields are populated depends on [Runs.Using], Main, Pre and Post for JavaScript
+// actions, Image, Entrypoint, PreEntrypoint, PostEntrypoint, Args and Env for docker
+// actions and Steps for composite actions.
+type Runs struct {
+	Env            map[string]string // Environment variables for the container
+	Using          string            // The runtime used to run the action e.g. node20, docker or composite
+	Main           string            // The file containing the action's code
+	Pre            string            // A script run at the start of the job
+	Post           string            // A script run at the end of the job
+	PreIf          string            // Condition for running Pre or PreEntrypoint
+	PostIf         string            // Condition for running Post or PostEntrypoint
+	Image          string            // The docker image or Dockerfile to use
+	Entrypoint     string            // Overrides the Dockerfile ENTRYPOINT
+	PreEntrypoint  string            // Entrypoint run at the start of the job
+	PostEntrypoint string            // Entrypoint run at the end of the job
+	Args           []string          // Arguments passed to the container
+	Steps          []Step            // The steps of a composite action
+}
+
+// Node reports whether the action is a JavaScript action, run by one of the node runtimes.
+func (r Runs) Node() bool {
+	return strings.HasPrefix(r.Using, "node")
+}
+
+// Step is a single step in a composite action.
+type Step struct {
+	With             map[string]string // Inputs passed to the action in Uses
+	Env              map[string]string // Environment variables for the step
+	ID               string            // Unique identifier for the step
+	If               string            // Condition for running the step
+	Name             string            // Name of the step
+	Run              string            // The command to run
+	Shell            string            // The shell used to run Run
+	Uses             string            // The action to run
+	WorkingDirectory string            // The working directory for Run
+	ContinueOnError  string            // Whether a failing step should fail the action, may be an expression
+	Line             int               // The line in the metadata file on which the step is declared
+}
+
+// Error is a problem with an action metadata file.
+type Error struct {
+	File    string // The name of the metadata file
+	Message string // Description of the problem
+	Line    int    // The line on which the problem occurs, 0 if unknown
+	Column  int    // The column on which the problem occurs, 0 if unknown
+}
+
+// Error implements the error interface for [Error].
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Load reads and validates the metadata file in dir, which may be named either action.yml
+// or action.yaml.
+func Load(dir string) (Action, error) {
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		action, err := Read(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		return action, err
+	}
+
+	return Action{}, fmt.Errorf("no action.yml or action.yaml in %s: %w", dir, os.ErrNotExist)
+}
+
+// Read reads and validates the metadata file at path.
+func Read(path string) (Action, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Action{}, fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return Parse(path, file)
+}
+
+// Parse parses and validates the action metadata read from r, name is the name of the
+// file used in error messages.
+//
+// Parse does not stop at the first problem, the returned error wraps an [*Error] for every
+// problem found, each of which carries the line number it occurred on.
+func Parse(name string, r io.Reader) (Action, error) {
+	var doc yaml.Node
+
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Action{}, &Error{File: name, Message: "file is empty"}
+		}
+
+		return Action{}, &Error{File: name, Message: err.Error()}
+	}
+
+	if len(doc.Content) == 0 {
+		return Action{}, &Error{File: name, Message: "file is empty"}
+	}
+
+	p := &parser{file: name}
+
+	action := p.action(doc.Content[0])
+	if len(p.errs) != 0 {
+		// Some checks range over maps, so sort to report problems in file order
+		slices.SortStableFunc(p.errs, func(a, b *Error) int {
+			return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+		})
+
+		errs := make([]error, 0, len(p.errs))
+		for _, err := range p.errs {
+			errs = append(errs, err)
+		}
+
+		return Action{}, errors.Join(errs...)
+	}
+
+	return action, nil
+}
+
+// parser walks the YAML node tree of a metadata file, collecting errors as it goes.
+type parser struct {
+	file   string       // Name of the file being parsed
+	errs   []*Error     // Errors collected so far
+	values []*yaml.Node // Output value keys, only valid in composite actions
+}
+
+// errorf records an error at the position of node.
+func (p *parser) errorf(node *yaml.Node, format string, args ...any) {
+	p.errs = append(p.errs, &Error{
+		File:    p.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// action parses the top level of the metadata file.
+func (p *parser) action(node *yaml.Node) Action {
+	var (
+		action Action
+		seen   []string
+	)
+
+	for key, value := range p.mapping(node) {
+		seen = append(seen, key.Value)
+
+		switch key.Value {
+		case "name":
+			action.Name = p.str(value)
+		case "author":
+			action.Author = p.str(value)
+		case "description":
+			action.Description = p.str(value)
+		case "inputs":
+			action.Inputs = p.inputs(value)
+		case "outputs":
+			action.Outputs = p.outputs(value)
+		case "runs":
+			action.Runs = p.runs(value)
+		case "branding":
+			action.Branding = p.branding(value)
+		default:
+			p.errorf(key, "unexpected key %q", key.Value)
+		}
+	}
+
+	p.require(node, seen, "name", "description", "runs")
+
+	if action.Runs.Using != UsingComposite {
+		for _, value := range p.values {
+			p.errorf(value, "output values are only supported in composite actions")
+		}
+	}
+
+	return action
+}
+
+// inputs parses the inputs section.
+func (p *parser) inputs(node *yaml.Node) map[string]Input {
+	inputs := make(map[string]Input)
+	lowered := make(map[string]bool)
+
+	for key, value := range p.mapping(node) {
+		id := key.Value
+		p.id(key, "input")
+
+		// Inputs are case insensitive as they're exposed as upper cased env vars
+		if lowered[strings.ToLower(id)] {
+			p.errorf(key, "input %q is declared more than once (ids are case insensitive)", id)
+		}
+
+		lowered[strings.ToLower(id)] = true
+
+		input := Input{Line: key.Line}
+
+		var seen []string
+
+		for k, v := range p.mapping(value) {
+			seen = append(seen, k.Value)
+
+			switch k.Value {
+			case "description":
+				input.Description = p.str(v)
+			case "required":
+				input.Required = p.boolean(v)
+			case "default":
+				input.Default = p.str(v)
+			case "deprecationMessage":
+				input.DeprecationMessage = p.str(v)
+			default:
+				p.errorf(k, "unexpected key %q in input %q", k.Value, id)
+			}
+		}
+
+		p.require(value, seen, "description")
+
+		inputs[id] = input
+	}
+
+	return inputs
+}
+
+// outputs parses the outputs section.
+func (p *parser) outputs(node *yaml.Node) map[string]Output {
+	outputs := make(map[string]Output)
+
+	for key, value := range p.mapping(node) {
+		id := key.Value
+		p.id(key, "output")
+
+		output := Output{Line: key.Line}
+
+		var seen []string
+
+		for k, v := range p.mapping(value) {
+			seen = append(seen, k.Value)
+
+			switch k.Value {
+			case "description":
+				output.Description = p.str(v)
+			case "value":
+				output.Value = p.str(v)
+				p.values = append(p.values, k)
+			default:
+				p.errorf(k, "unexpected key %q in output %q", k.Value, id)
+			}
+		}
+
+		p.require(value, seen, "description")
+
+		outputs[id] = output
+	}
+
+	return outputs
+}
+
+// branding parses the branding section.
+func (p *parser) branding(node *yaml.Node) Branding {
+	var branding Branding
+
+	for key, value := range p.mapping(node) {
+		switch key.Value {
+		case "icon":
+			branding.Icon = p.str(value)
+		case "color":
+			branding.Color = p.str(value)
+			if !slices.Contains(colors, branding.Color) {
+				p.errorf(value, "invalid branding color %q, must be one of %s", branding.Color, strings.Join(colors, ", "))
+			}
+		default:
+			p.errorf(key, "unexpected key %q in branding", key.Value)
+		}
+	}
+
+	return branding
+}
+
+// runs parses and validates the runs section according to the runtime in using.
+func (p *parser) runs(node *yaml.Node) Runs {
+	var (
+		runs Runs
+		keys = make(map[string]*yaml.Node)
+	)
+
+	for key, value := range p.mapping(node) {
+		keys[key.Value] = key
+
+		switch key.Value {
+		case "using":
+			runs.Using = p.str(value)
+		case "main":
+			runs.Main = p.str(value)
+		case "pre":
+			runs.Pre = p.str(value)
+		case "post":
+			runs.Post = p.str(value)
+		case "pre-if":
+			runs.PreIf = p.str(value)
+		case "post-if":
+			runs.PostIf = p.str(value)
+		case "image":
+			runs.Image = p.str(value)
+		case "entrypoint":
+			runs.Entrypoint = p.str(value)
+		case "pre-entrypoint":
+			runs.PreEntrypoint = p.str(value)
+		case "post-entrypoint":
+			runs.PostEntrypoint = p.str(value)
+		case "args":
+			runs.Args = p.strings(value)
+		case "env":
+			runs.Env = p.stringMap(value)
+		case "steps":
+			runs.Steps = p.steps(value)
+		default:
+			p.errorf(key, "unexpected key %q in runs", key.Value)
+		}
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return runs
+	}
+
+	// Keys allowed for each runtime, in addition to using
+	var allowed, required []string
+
+	switch runs.Using {
+	case "":
+		p.errorf(node, "missing required key %q", "using")
+		return runs
+	case UsingNode12, UsingNode16, UsingNode20, UsingNode24:
+		allowed = []string{"main", "pre", "post", "pre-if", "post-if"}
+		required = []string{"main"}
+	case UsingDocker:
+		allowed = []string{"image", "entrypoint", "pre-entrypoint", "post-entrypoint", "pre-if", "post-if", "args", "env"}
+		required = []string{"image"}
+	case UsingComposite:
+		allowed = []string{"steps"}
+		required = []string{"steps"}
+	default:
+		p.errorf(keys["using"], "unsupported runtime %q", runs.Using)
+		return runs
+	}
+
+	for name, key := range keys {
+		if name != "using" && !slices.Contains(allowed, name) {
+			p.errorf(key, "key %q is not supported by %s actions", name, runs.Using)
+		}
+	}
+
+	p.require(node, slices.Collect(maps.Keys(keys)), required...)
+
+	return runs
+}
+
+// steps parses the steps of a composite action.
+func (p *parser) steps(node *yaml.Node) []Step {
+	node = resolve(node)
+
+	if node.Kind != yaml.SequenceNode {
+		p.errorf(node, "expected a list of steps")
+		return nil
+	}
+
+	steps := make([]Step, 0, len(node.Content))
+
+	for _, item := range node.Content {
+		step := Step{Line: item.Line}
+
+		for key, value := range p.mapping(item) {
+			switch key.Value {
+			case "id":
+				step.ID = p.str(value)
+			case "if":
+				step.If = p.str(value)
+			case "name":
+				step.Name = p.str(value)
+			case "run":
+				step.Run = p.str(value)
+			case "shell":
+				step.Shell = p.str(value)
+			case "uses":
+				step.Uses = p.str(value)
+			case "with":
+				step.With = p.stringMap(value)
+			case "env":
+				step.Env = p.stringMap(value)
+			case "working-directory":
+				step.WorkingDirectory = p.str(value)
+			case "continue-on-error":
+				step.ContinueOnError = p.str(value)
+			default:
+				p.errorf(key, "unexpected key %q in step", key.Value)
+			}
+		}
+
+		switch {
+		case step.Run == "" && step.Uses == "":
+			p.errorf(item, "step must have one of %q or %q", "run", "uses")
+		case step.Run != "" && step.Uses != "":
+			p.errorf(item, "step cannot have both %q and %q", "run", "uses")
+		case step.Run != "" && step.Shell == "":
+			p.errorf(item, "step with %q must also set %q", "run", "shell")
+		case step.Uses != "" && (step.Shell != "" || step.WorkingDirectory != ""):
+			p.errorf(item, "step with %q cannot set %q or %q", "uses", "shell", "working-directory")
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+// id validates the input or output id in node.
+func (p *parser) id(node *yaml.Node, kind string) {
+	if !idPattern.MatchString(node.Value) {
+		p.errorf(
+			node,
+			"invalid %s id %q, must start with a letter or _ and contain only alphanumeric characters, - or _",
+			kind,
+			node.Value,
+		)
+	}
+}
+
+// require records an error at node for each key in required that's not in seen.
+func (p *parser) require(node *yaml.Node, seen []string, required ...string) {
+	node = resolve(node)
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for _, key := range required {
+		if !slices.Contains(seen, key) {
+			p.errorf(node, "missing required key %q", key)
+		}
+	}
+}
+
+// mapping yields the key value pairs of the mapping node, recording an error if node
+// is not a mapping.
+func (p *parser) mapping(node *yaml.Node) iter.Seq2[*yaml.Node, *yaml.Node] {
+	return func(yield func(*yaml.Node, *yaml.Node) bool) {
+		node = resolve(node)
+
+		if node.Kind != yaml.MappingNode {
+			p.errorf(node, "expected a mapping")
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !yield(node.Content[i], resolve(node.Content[i+1])) {
+				return
+			}
+		}
+	}
+}
+
+// str returns the value of the scalar node, recording an error if it's not a scalar.
+func (p *parser) str(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected a string")
+		return ""
+	}
+
+	if node.Tag == "!!null" {
+		return ""
+	}
+
+	return node.Value
+}
+
+// boolean returns the value of a boolean scalar node, which may also be the strings
+// "true" or "false".
+func (p *parser) boolean(node *yaml.Node) bool {
+	switch p.str(node) {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE", "":
+		return false
+	default:
+		p.errorf(node, "expected a boolean, got %q", node.Value)
+		return false
+	}
+}
+
+// strings returns the values of a sequence of scalars.
+func (p *parser) strings(node *yaml.Node) []string {
+	if node.Kind != yaml.SequenceNode {
+		p.errorf(node, "expected a list")
+		return nil
+	}
+
+	values := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		values = append(values, p.str(resolve(item)))
+	}
+
+	return values
+}
+
+// stringMap returns the values of a mapping of scalars.
+func (p *parser) stringMap(node *yaml.Node) map[string]string {
+	values := make(map[string]string)
+	for key, value := range p.mapping(node) {
+		values[key.Value] = p.str(value)
+	}
+
+	return values
+}
+
+// resolve follows YAML aliases to the node they refer to.
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
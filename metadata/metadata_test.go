@@ -0,0 +1,303 @@
+package metadata_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/metadata"
+	"go.followtheprocess.codes/test"
+)
+
+const nodeAction = `name: My Action
+author: Someone
+description: Does a thing
+branding:
+  icon: zap
+  color: purple
+inputs:
+  token:
+    description: The GitHub token
+    required: true
+  level:
+    description: Log level
+    required: 'false'
+    default: info
+  old-flag:
+    description: Don't use this
+    deprecationMessage: Use level instead
+outputs:
+  result:
+    description: The result
+runs:
+  using: node24
+  main: dist/index.js
+  post: dist/cleanup.js
+  post-if: success()
+`
+
+func TestParse(t *testing.T) {
+	t.Run("node", func(t *testing.T) {
+		action, err := metadata.Parse("action.yml", strings.NewReader(nodeAction))
+		test.Ok(t, err)
+
+		test.Equal(t, action.Name, "My Action")
+		test.Equal(t, action.Author, "Someone")
+		test.Equal(t, action.Description, "Does a thing")
+		test.Equal(t, action.Branding, metadata.Branding{Icon: "zap", Color: "purple"})
+
+		test.Equal(t, len(action.Inputs), 3)
+		test.Equal(t, action.Inputs["token"], metadata.Input{Description: "The GitHub token", Required: true, Line: 8})
+		test.Equal(t, action.Inputs["level"], metadata.Input{Description: "Log level", Default: "info", Line: 11})
+		test.True(t, action.Inputs["old-flag"].Deprecated())
+		test.False(t, action.Inputs["token"].Deprecated())
+
+		test.Equal(t, action.Outputs["result"], metadata.Output{Description: "The result", Line: 19})
+
+		test.Equal(t, action.Runs.Using, metadata.UsingNode24)
+		test.True(t, action.Runs.Node())
+		test.Equal(t, action.Runs.Main, "dist/index.js")
+		test.Equal(t, action.Runs.Post, "dist/cleanup.js")
+		test.Equal(t, action.Runs.PostIf, "success()")
+	})
+
+	t.Run("docker", func(t *testing.T) {
+		yml := `name: Docker
+description: Runs in a container
+runs:
+  using: docker
+  image: Dockerfile
+  entrypoint: /entrypoint.sh
+  args:
+    - --verbose
+    - ${{ inputs.thing }}
+  env:
+    FOO: bar
+`
+		action, err := metadata.Parse("action.yml", strings.NewReader(yml))
+		test.Ok(t, err)
+
+		test.Equal(t, action.Runs.Using, metadata.UsingDocker)
+		test.False(t, action.Runs.Node())
+		test.Equal(t, action.Runs.Image, "Dockerfile")
+		test.Equal(t, action.Runs.Entrypoint, "/entrypoint.sh")
+		test.EqualFunc(t, action.Runs.Args, []string{"--verbose", "${{ inputs.thing }}"}, slices.Equal)
+		test.Equal(t, action.Runs.Env["FOO"], "bar")
+	})
+
+	t.Run("composite", func(t *testing.T) {
+		yml := `name: Composite
+description: Runs some steps
+outputs:
+  sum:
+    description: The sum
+    value: ${{ steps.add.outputs.sum }}
+runs:
+  using: composite
+  steps:
+    - uses: actions/checkout@v4
+      with:
+        fetch-depth: 0
+    - id: add
+      run: echo "sum=3" >> "$GITHUB_OUTPUT"
+      shell: bash
+      continue-on-error: true
+`
+		action, err := metadata.Parse("action.yml", strings.NewReader(yml))
+		test.Ok(t, err)
+
+		test.Equal(t, action.Outputs["sum"].Value, "${{ steps.add.outputs.sum }}")
+		test.Equal(t, len(action.Runs.Steps), 2)
+
+		checkout := action.Runs.Steps[0]
+		test.Equal(t, checkout.Uses, "actions/checkout@v4")
+		test.Equal(t, checkout.With["fetch-depth"], "0")
+		test.Equal(t, checkout.Line, 10)
+
+		add := action.Runs.Steps[1]
+		test.Equal(t, add.ID, "add")
+		test.Equal(t, add.Shell, "bash")
+		test.Equal(t, add.ContinueOnError, "true")
+		test.Equal(t, add.Line, 13)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string   // Name of the test case
+		yml  string   // The action.yml contents
+		want []string // Expected error messages
+	}{
+		{
+			name: "empty",
+			yml:  "",
+			want: []string{"action.yml: file is empty"},
+		},
+		{
+			name: "invalid yaml",
+			yml:  "name: [oops",
+			want: []string{"action.yml: yaml: line 1: did not find expected ',' or ']'"},
+		},
+		{
+			name: "not a mapping",
+			yml:  "- a list",
+			want: []string{"action.yml:1:1: expected a mapping"},
+		},
+		{
+			name: "missing required",
+			yml:  "author: me\n",
+			want: []string{
+				`action.yml:1:1: missing required key "name"`,
+				`action.yml:1:1: missing required key "description"`,
+				`action.yml:1:1: missing required key "runs"`,
+			},
+		},
+		{
+			name: "unknown keys",
+			yml: `name: Test
+description: Test
+colour: red
+inputs:
+  thing:
+    description: A thing
+    requried: true
+runs:
+  using: node20
+  main: index.js
+  image: Dockerfile
+`,
+			want: []string{
+				`action.yml:3:1: unexpected key "colour"`,
+				`action.yml:7:5: unexpected key "requried" in input "thing"`,
+				`action.yml:11:3: key "image" is not supported by node20 actions`,
+			},
+		},
+		{
+			name: "bad inputs",
+			yml: `name: Test
+description: Test
+inputs:
+  1st:
+    description: Bad id
+  Token:
+    description: Upper
+  token:
+    required: yes please
+runs:
+  using: node20
+  main: index.js
+`,
+			want: []string{
+				`action.yml:4:3: invalid input id "1st", must start with a letter or _ and contain only alphanumeric characters, - or _`,
+				`action.yml:8:3: input "token" is declared more than once (ids are case insensitive)`,
+				`action.yml:9:5: missing required key "description"`,
+				`action.yml:9:15: expected a boolean, got "yes please"`,
+			},
+		},
+		{
+			name: "output value outside composite",
+			yml: `name: Test
+description: Test
+outputs:
+  out:
+    description: An output
+    value: nope
+runs:
+  using: docker
+`,
+			want: []string{
+				`action.yml:6:5: output values are only supported in composite actions`,
+				`action.yml:8:3: missing required key "image"`,
+			},
+		},
+		{
+			name: "unsupported runtime",
+			yml: `name: Test
+description: Test
+branding:
+  color: pink
+runs:
+  using: node8
+  main: index.js
+`,
+			want: []string{
+				`action.yml:4:10: invalid branding color "pink", must be one of white, black, yellow, blue, green, orange, red, purple, gray-dark`,
+				`action.yml:6:3: unsupported runtime "node8"`,
+			},
+		},
+		{
+			name: "missing using",
+			yml: `name: Test
+description: Test
+runs:
+  main: index.js
+`,
+			want: []string{`action.yml:4:3: missing required key "using"`},
+		},
+		{
+			name: "bad steps",
+			yml: `name: Test
+description: Test
+runs:
+  using: composite
+  steps:
+    - name: nothing
+    - run: echo hi
+    - run: echo hi
+      uses: actions/checkout@v4
+    - uses: actions/checkout@v4
+      shell: bash
+`,
+			want: []string{
+				`action.yml:6:7: step must have one of "run" or "uses"`,
+				`action.yml:7:7: step with "run" must also set "shell"`,
+				`action.yml:8:7: step cannot have both "run" and "uses"`,
+				`action.yml:10:7: step with "uses" cannot set "shell" or "working-directory"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := metadata.Parse("action.yml", strings.NewReader(tt.yml))
+			test.Err(t, err)
+			test.Equal(t, err.Error(), strings.Join(tt.want, "\n"))
+
+			var metaErr *metadata.Error
+
+			test.True(t, errors.As(err, &metaErr))
+			test.Equal(t, metaErr.File, "action.yml")
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("action.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "action.yaml"), []byte(nodeAction), 0o644)
+		test.Ok(t, err)
+
+		action, err := metadata.Load(dir)
+		test.Ok(t, err)
+		test.Equal(t, action.Name, "My Action")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "action.yml"), []byte("name: Test\n"), 0o644)
+		test.Ok(t, err)
+
+		_, err = metadata.Load(dir)
+		test.Err(t, err)
+		test.True(t, strings.HasPrefix(err.Error(), filepath.Join(dir, "action.yml")+":1:1:"))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := metadata.Load(t.TempDir())
+		test.Err(t, err)
+		test.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
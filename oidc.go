@@ -0,0 +1,283 @@
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.followtheprocess.codes/actions/log"
+)
+
+const (
+	// idTokenURLVar is the env var containing the URL from which to request an OIDC token.
+	idTokenURLVar = "ACTIONS_ID_TOKEN_REQUEST_URL"
+
+	// idTokenTokenVar is the env var containing the bearer token used to request an OIDC token.
+	idTokenTokenVar = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+)
+
+// ErrNoIDToken is returned from [IDToken] when the runner has not made an OIDC token
+// available, which happens unless the workflow or job is granted the id-token: write permission.
+var ErrNoIDToken = errors.New(
+	"OIDC ID token is not available, the workflow or job needs the `id-token: write` permission",
+)
+
+// IDTokenClaims are the claims in a GitHub Actions OIDC token.
+//
+// GitHub encodes every custom claim as a string, numeric and boolean claims are parsed
+// into the appropriate types here.
+//
+// See https://docs.github.com/en/actions/reference/security/oidc#oidc-token-claims
+type IDTokenClaims struct {
+	IssuedAt             time.Time  // iat, when the token was issued
+	ExpiresAt            time.Time  // exp, when the token expires
+	NotBefore            time.Time  // nbf, the token is not valid before this time
+	Repository           Repository // repository, the repository the workflow is running in
+	Issuer               string     // iss, e.g. https://token.actions.githubusercontent.com
+	Subject              string     // sub, e.g. repo:octo-org/octo-repo:environment:prod
+	JWTID                string     // jti, unique identifier of the token
+	Actor                string     // actor, the user that initiated the workflow run
+	BaseRef              string     // base_ref, the target branch of a pull request
+	Environment          string     // environment, the name of the environment used by the job
+	EventName            string     // event_name, the event that triggered the workflow run
+	HeadRef              string     // head_ref, the source branch of a pull request
+	JobWorkflowRef       string     // job_workflow_ref, the ref path to the (possibly reusable) workflow running the job
+	JobWorkflowSHA       string     // job_workflow_sha, the commit SHA of the workflow running the job
+	Ref                  string     // ref, the git ref that triggered the workflow run
+	RepositoryVisibility string     // repository_visibility, public, private or internal
+	RunnerEnvironment    string     // runner_environment, github-hosted or self-hosted
+	SHA                  string     // sha, the commit SHA that triggered the workflow run
+	Workflow             string     // workflow, the name of the workflow
+	WorkflowRef          string     // workflow_ref, the ref path to the workflow
+	WorkflowSHA          string     // workflow_sha, the commit SHA of the workflow file
+	Audience             []string   // aud, the intended audiences of the token
+	ActorID              int64      // actor_id, the account ID of the actor
+	RepositoryID         int64      // repository_id, the ID of the repository
+	RepositoryOwnerID    int64      // repository_owner_id, the account ID of the repository owner
+	RunID                int64      // run_id, the ID of the workflow run
+	RunNumber            int64      // run_number, the number of the workflow run
+	RunAttempt           int        // run_attempt, the attempt number of the workflow run
+	RefType              RefType    // ref_type, the type of ref that triggered the workflow run
+	RefProtected         bool       // ref_protected, whether the ref has branch protections
+}
+
+// IDToken requests an OIDC ID token (a JWT) from the runner for the given audience, which
+// can be exchanged with a cloud provider for short lived credentials.
+//
+// If audience is empty, the default audience (the URL of the repository owner) is used.
+// The returned token is automatically masked in the logs by writing the mask to logger.
+//
+// If the workflow or job does not have the id-token: write permission, either because the
+// runner hasn't made a token available or the token endpoint rejects the request, an error
+// wrapping [ErrNoIDToken] is returned.
+//
+// See https://docs.github.com/en/actions/concepts/security/openid-connect
+func IDToken(ctx context.Context, logger log.Logger, audience string) (string, error) {
+	requestURL := os.Getenv(idTokenURLVar)
+	requestToken := os.Getenv(idTokenTokenVar)
+
+	if requestURL == "" || requestToken == "" {
+		return "", fmt.Errorf("$%s or $%s is not set: %w", idTokenURLVar, idTokenTokenVar, ErrNoIDToken)
+	}
+
+	target, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid $%s: %w", idTokenURLVar, err)
+	}
+
+	if audience != "" {
+		query := target.Query()
+		query.Set("audience", audience)
+		target.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create OIDC token request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not request OIDC token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = fmt.Errorf("OIDC token request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			err = fmt.Errorf("%w: %w", err, ErrNoIDToken)
+		}
+
+		return "", err
+	}
+
+	var payload struct {
+		Value string `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("could not decode OIDC token response: %w", err)
+	}
+
+	if payload.Value == "" {
+		return "", errors.New("OIDC token response did not contain a token")
+	}
+
+	logger.Mask(payload.Value)
+
+	return payload.Value, nil
+}
+
+// ParseIDToken parses the claims from an OIDC token returned by [IDToken].
+//
+// The signature of the token is NOT verified, the claims are for inspection (e.g. logging
+// which ref a deployment came from) only. The party the token is presented to is
+// responsible for verifying it against GitHub's published keys.
+func ParseIDToken(token string) (IDTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return IDTokenClaims{}, errors.New("malformed OIDC token: expected 3 dot separated parts")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return IDTokenClaims{}, fmt.Errorf("malformed OIDC token payload: %w", err)
+	}
+
+	var raw struct {
+		Issuer               string          `json:"iss"`
+		Subject              string          `json:"sub"`
+		JWTID                string          `json:"jti"`
+		Actor                string          `json:"actor"`
+		ActorID              string          `json:"actor_id"`
+		BaseRef              string          `json:"base_ref"`
+		Environment          string          `json:"environment"`
+		EventName            string          `json:"event_name"`
+		HeadRef              string          `json:"head_ref"`
+		JobWorkflowRef       string          `json:"job_workflow_ref"`
+		JobWorkflowSHA       string          `json:"job_workflow_sha"`
+		Ref                  string          `json:"ref"`
+		RefProtected         string          `json:"ref_protected"`
+		RefType              string          `json:"ref_type"`
+		Repository           string          `json:"repository"`
+		RepositoryID         string          `json:"repository_id"`
+		RepositoryOwnerID    string          `json:"repository_owner_id"`
+		RepositoryVisibility string          `json:"repository_visibility"`
+		RunAttempt           string          `json:"run_attempt"`
+		RunID                string          `json:"run_id"`
+		RunNumber            string          `json:"run_number"`
+		RunnerEnvironment    string          `json:"runner_environment"`
+		SHA                  string          `json:"sha"`
+		Workflow             string          `json:"workflow"`
+		WorkflowRef          string          `json:"workflow_ref"`
+		WorkflowSHA          string          `json:"workflow_sha"`
+		Audience             json.RawMessage `json:"aud"`
+		IssuedAt             int64           `json:"iat"`
+		ExpiresAt            int64           `json:"exp"`
+		NotBefore            int64           `json:"nbf"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return IDTokenClaims{}, fmt.Errorf("malformed OIDC token claims: %w", err)
+	}
+
+	claims := IDTokenClaims{
+		IssuedAt:             time.Unix(raw.IssuedAt, 0),
+		ExpiresAt:            time.Unix(raw.ExpiresAt, 0),
+		NotBefore:            time.Unix(raw.NotBefore, 0),
+		Issuer:               raw.Issuer,
+		Subject:              raw.Subject,
+		JWTID:                raw.JWTID,
+		Actor:                raw.Actor,
+		BaseRef:              raw.BaseRef,
+		Environment:          raw.Environment,
+		EventName:            raw.EventName,
+		HeadRef:              raw.HeadRef,
+		JobWorkflowRef:       raw.JobWorkflowRef,
+		JobWorkflowSHA:       raw.JobWorkflowSHA,
+		Ref:                  raw.Ref,
+		RepositoryVisibility: raw.RepositoryVisibility,
+		RunnerEnvironment:    raw.RunnerEnvironment,
+		SHA:                  raw.SHA,
+		Workflow:             raw.Workflow,
+		WorkflowRef:          raw.WorkflowRef,
+		WorkflowSHA:          raw.WorkflowSHA,
+		RefProtected:         raw.RefProtected == "true",
+	}
+
+	if owner, name, ok := strings.Cut(raw.Repository, "/"); ok {
+		claims.Repository = Repository{Owner: owner, Name: name}
+	}
+
+	switch raw.RefType {
+	case "branch":
+		claims.RefType = RefTypeBranch
+	case "tag":
+		claims.RefType = RefTypeTag
+	}
+
+	// aud may be a single string or an array of them
+	if len(raw.Audience) != 0 {
+		var single string
+		if err := json.Unmarshal(raw.Audience, &single); err == nil {
+			claims.Audience = []string{single}
+		} else if err := json.Unmarshal(raw.Audience, &claims.Audience); err != nil {
+			return IDTokenClaims{}, fmt.Errorf("malformed OIDC token aud claim: %w", err)
+		}
+	}
+
+	var errs []error
+
+	for _, field := range []struct {
+		dst   *int64
+		name  string
+		value string
+	}{
+		{dst: &claims.ActorID, name: "actor_id", value: raw.ActorID},
+		{dst: &claims.RepositoryID, name: "repository_id", value: raw.RepositoryID},
+		{dst: &claims.RepositoryOwnerID, name: "repository_owner_id", value: raw.RepositoryOwnerID},
+		{dst: &claims.RunID, name: "run_id", value: raw.RunID},
+		{dst: &claims.RunNumber, name: "run_number", value: raw.RunNumber},
+	} {
+		if field.value == "" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(field.value, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s claim %q", field.name, field.value))
+			continue
+		}
+
+		*field.dst = n
+	}
+
+	if raw.RunAttempt != "" {
+		attempt, err := strconv.Atoi(raw.RunAttempt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid run_attempt claim %q", raw.RunAttempt))
+		}
+
+		claims.RunAttempt = attempt
+	}
+
+	if len(errs) != 0 {
+		return IDTokenClaims{}, errors.Join(errs...)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,167 @@
+package actions //nolint: testpackage // See actions_test.go
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/log"
+	"go.followtheprocess.codes/test"
+)
+
+// fakeJWT builds an unsigned JWT carrying claims.
+func fakeJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+
+	payload, err := json.Marshal(claims)
+	test.Ok(t, err)
+
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func TestIDToken(t *testing.T) {
+	t.Run("missing permission", func(t *testing.T) {
+		t.Setenv(idTokenURLVar, "")
+		t.Setenv(idTokenTokenVar, "")
+
+		_, err := IDToken(t.Context(), log.New(io.Discard), "sts.amazonaws.com")
+		test.Err(t, err)
+		test.True(t, errors.Is(err, ErrNoIDToken))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		jwt := fakeJWT(t, map[string]any{"sub": "repo:octo-org/octo-repo:ref:refs/heads/main"})
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Header.Get("Authorization"), "Bearer request-token")
+			test.Equal(t, r.URL.Query().Get("api-version"), "2.0")
+			test.Equal(t, r.URL.Query().Get("audience"), "sts.amazonaws.com")
+
+			json.NewEncoder(w).Encode(map[string]string{"value": jwt})
+		}))
+		defer server.Close()
+
+		t.Setenv(idTokenURLVar, server.URL+"/token?api-version=2.0")
+		t.Setenv(idTokenTokenVar, "request-token")
+
+		buf := &bytes.Buffer{}
+
+		got, err := IDToken(t.Context(), log.New(buf), "sts.amazonaws.com")
+		test.Ok(t, err)
+		test.Equal(t, got, jwt)
+		test.Equal(t, buf.String(), "::add-mask::"+jwt+"\n")
+	})
+
+	t.Run("request failed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("bad token\n"))
+		}))
+		defer server.Close()
+
+		t.Setenv(idTokenURLVar, server.URL)
+		t.Setenv(idTokenTokenVar, "request-token")
+
+		_, err := IDToken(t.Context(), log.New(io.Discard), "")
+		test.Err(t, err)
+		test.True(t, errors.Is(err, ErrNoIDToken))
+		test.True(t, strings.HasPrefix(err.Error(), "OIDC token request failed with status 401: bad token: "))
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("oops\n"))
+		}))
+		defer server.Close()
+
+		t.Setenv(idTokenURLVar, server.URL)
+		t.Setenv(idTokenTokenVar, "request-token")
+
+		_, err := IDToken(t.Context(), log.New(io.Discard), "")
+		test.Err(t, err)
+		test.False(t, errors.Is(err, ErrNoIDToken))
+		test.Equal(t, err.Error(), "OIDC token request failed with status 500: oops")
+	})
+}
+
+func TestParseIDToken(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		jwt := fakeJWT(t, map[string]any{
+			"iss":                   "https://token.actions.githubusercontent.com",
+			"sub":                   "repo:octo-org/octo-repo:environment:prod",
+			"aud":                   "https://github.com/octo-org",
+			"jti":                   "example-id",
+			"iat":                   1632492967,
+			"nbf":                   1632492667,
+			"exp":                   1632493867,
+			"actor":                 "octocat",
+			"actor_id":              "12",
+			"environment":           "prod",
+			"event_name":            "workflow_dispatch",
+			"job_workflow_ref":      "octo-org/octo-automation/.github/workflows/oidc.yml@refs/heads/main",
+			"ref":                   "refs/heads/main",
+			"ref_protected":         "true",
+			"ref_type":              "branch",
+			"repository":            "octo-org/octo-repo",
+			"repository_id":         "74",
+			"repository_owner_id":   "65",
+			"repository_visibility": "private",
+			"run_attempt":           "2",
+			"run_id":                "5862834612",
+			"run_number":            "10",
+			"runner_environment":    "github-hosted",
+			"sha":                   "example-sha",
+			"workflow":              "example-workflow",
+		})
+
+		claims, err := ParseIDToken(jwt)
+		test.Ok(t, err)
+
+		test.Equal(t, claims.Issuer, "https://token.actions.githubusercontent.com")
+		test.Equal(t, claims.Subject, "repo:octo-org/octo-repo:environment:prod")
+		test.EqualFunc(t, claims.Audience, []string{"https://github.com/octo-org"}, slices.Equal)
+		test.Equal(t, claims.IssuedAt, time.Unix(1632492967, 0))
+		test.Equal(t, claims.ExpiresAt, time.Unix(1632493867, 0))
+		test.Equal(t, claims.Repository, Repository{Owner: "octo-org", Name: "octo-repo"})
+		test.Equal(t, claims.Environment, "prod")
+		test.Equal(t, claims.JobWorkflowRef, "octo-org/octo-automation/.github/workflows/oidc.yml@refs/heads/main")
+		test.Equal(t, claims.RefType, RefTypeBranch)
+		test.True(t, claims.RefProtected)
+		test.Equal(t, claims.ActorID, 12)
+		test.Equal(t, claims.RepositoryID, 74)
+		test.Equal(t, claims.RepositoryOwnerID, 65)
+		test.Equal(t, claims.RunID, 5862834612)
+		test.Equal(t, claims.RunNumber, 10)
+		test.Equal(t, claims.RunAttempt, 2)
+		test.Equal(t, claims.RunnerEnvironment, "github-hosted")
+	})
+
+	t.Run("audience array", func(t *testing.T) {
+		claims, err := ParseIDToken(fakeJWT(t, map[string]any{"aud": []string{"a", "b"}}))
+		test.Ok(t, err)
+		test.EqualFunc(t, claims.Audience, []string{"a", "b"}, slices.Equal)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := ParseIDToken("not.a-jwt")
+		test.Err(t, err)
+
+		_, err = ParseIDToken("a.!!!.c")
+		test.Err(t, err)
+
+		_, err = ParseIDToken(fakeJWT(t, map[string]any{"run_id": "nope", "run_attempt": "x"}))
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "invalid run_id claim \"nope\"\ninvalid run_attempt claim \"x\"")
+	})
+}
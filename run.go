@@ -0,0 +1,341 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// phaseKey is the state variable recording the last phase of the action to run, used
+	// by [Run] to detect the current phase.
+	phaseKey = "actions_phase"
+
+	// cleanupKey is the state variable holding the names of the cleanup tasks registered
+	// with [State.AddCleanup], as a JSON array.
+	cleanupKey = "actions_cleanup"
+
+	// phaseFlag is the prefix of the command line argument naming the phase for [Run] to run.
+	phaseFlag = "--actions-phase="
+)
+
+// Phase is a phase of an action's lifecycle.
+//
+// See https://docs.github.com/en/actions/reference/workflows-and-actions/metadata-syntax#runspre
+type Phase int
+
+const (
+	PhasePre  Phase = iota // The pre phase, run at the start of the job
+	PhaseMain              // The main phase, run as the step itself
+	PhasePost              // The post phase, run at the end of the job
+)
+
+// String implements [fmt.Stringer] for [Phase], returning the name of the phase as
+// passed to [Run] in the --actions-phase argument.
+func (p Phase) String() string {
+	switch p {
+	case PhasePre:
+		return "pre"
+	case PhaseMain:
+		return "main"
+	case PhasePost:
+		return "post"
+	default:
+		return fmt.Sprintf("Phase(%d)", int(p))
+	}
+}
+
+// PhaseFunc is a function run by [Run] in a phase of an action, or as a cleanup task.
+type PhaseFunc func(ctx context.Context, state *State) error
+
+// Action is an action whose pre, main and post phases share a single binary, see [Run].
+type Action struct {
+	Cleanup map[string]PhaseFunc // Cleanup tasks that may be registered with [State.AddCleanup], keyed by name
+	Pre     PhaseFunc            // The pre phase, may be nil if the action has no pre entrypoint
+	Main    PhaseFunc            // The main phase, required
+	Post    PhaseFunc            // The post phase, may be nil if only cleanup tasks are needed
+}
+
+// Run runs the current phase of action, allowing the pre, main and post entrypoints of an
+// action to be the same binary:
+//
+//	func main() {
+//		action := actions.Action{
+//			Main:    start,
+//			Cleanup: map[string]actions.PhaseFunc{"stop-server": stop},
+//		}
+//
+//		if err := actions.Run(context.Background(), action); err != nil {
+//			// Handle error
+//		}
+//	}
+//
+// The phase is taken from the first command line argument if it's --actions-phase=<phase>,
+// where phase is "pre", "main" or "post", and the argument is removed from [os.Args] so the
+// action's own flag parsing doesn't see it. Otherwise it's detected from a marker Run saves
+// in the action's state: pre and main each record that they ran before calling the phase
+// function, and as the runner passes state on to the later phases, a run after main is
+// post. Nothing runs before pre, so an action with a pre phase must pass
+// --actions-phase=pre as the first argument to its pre entrypoint.
+//
+// A flag is used rather than a bare word as a Docker action's args are shared by all of
+// its entrypoints, so may well start with "main" or similar.
+//
+// In post, the Post function is called followed by each cleanup task registered during
+// pre or main, in the reverse order of registration, like a deferred call. Every task is
+// run even if Post or an earlier task fails, and all the errors are returned together.
+//
+// See https://docs.github.com/en/actions/reference/workflows-and-actions/metadata-syntax#runspost
+func Run(ctx context.Context, action Action) error {
+	args := os.Args[1:]
+	if len(args) > 0 && strings.HasPrefix(args[0], phaseFlag) {
+		os.Args = slices.Delete(slices.Clone(os.Args), 1, 2)
+	}
+
+	return run(ctx, action, args)
+}
+
+// run implements [Run], taking the command line arguments so they may be set in tests.
+func run(ctx context.Context, action Action, args []string) error {
+	phase, err := detectPhase(args)
+	if err != nil {
+		return err
+	}
+
+	state := &State{
+		cleanup: action.Cleanup,
+		values:  make(map[string]string),
+		phase:   phase,
+	}
+
+	var fn PhaseFunc
+
+	switch state.phase {
+	case PhasePre:
+		fn = action.Pre
+	case PhaseMain:
+		fn = action.Main
+	default:
+		return runPost(ctx, action, state)
+	}
+
+	if fn == nil {
+		return fmt.Errorf("action has no %s phase", state.phase)
+	}
+
+	// Marking the phase before running it means post is still detected if it fails
+	state.mu.Lock()
+	err = state.set(phaseKey, state.phase.String())
+	state.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("could not save %s phase marker: %w", state.phase, err)
+	}
+
+	return fn(ctx, state)
+}
+
+// runPost runs the post phase of action, followed by the registered cleanup tasks.
+func runPost(ctx context.Context, action Action, state *State) error {
+	var errs []error
+
+	if action.Post != nil {
+		if err := action.Post(ctx, state); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	state.mu.Lock()
+	names, err := state.cleanups()
+	state.mu.Unlock()
+
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	for _, name := range slices.Backward(names) {
+		task, ok := action.Cleanup[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("no cleanup task named %q", name))
+			continue
+		}
+
+		if err = task(ctx, state); err != nil {
+			errs = append(errs, fmt.Errorf("cleanup task %q failed: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// detectPhase returns the current phase, from the first of args if it's the phase flag, or
+// from the phase marker in the state otherwise.
+func detectPhase(args []string) (Phase, error) {
+	if len(args) > 0 {
+		if name, ok := strings.CutPrefix(args[0], phaseFlag); ok {
+			for _, phase := range []Phase{PhasePre, PhaseMain, PhasePost} {
+				if name == phase.String() {
+					return phase, nil
+				}
+			}
+
+			return PhaseMain, fmt.Errorf("unknown phase %q in %s, must be pre, main or post", name, args[0])
+		}
+	}
+
+	if last, _ := GetState(phaseKey); last == PhaseMain.String() {
+		return PhasePost, nil
+	}
+
+	return PhaseMain, nil
+}
+
+// State is the state shared between the phases of an action run with [Run].
+//
+// Values are saved with [SetState] as soon as they're set, so every later phase sees them
+// even if the phase setting them goes on to fail. Values set during a phase can also be
+// read back within it, which isn't the case for [GetState].
+//
+// It is safe for concurrent use.
+type State struct {
+	cleanup map[string]PhaseFunc // The cleanup tasks of the action, keyed by name
+	values  map[string]string    // Values set during this phase
+	mu      sync.Mutex           // Protects values
+	phase   Phase                // The current phase
+}
+
+// Phase returns the phase that's currently running.
+func (s *State) Phase() Phase {
+	return s.phase
+}
+
+// Get returns the value of the state variable key, set either during this phase or an
+// earlier one, and whether it was set at all.
+func (s *State) Get(key string) (value string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(key)
+}
+
+// Set sets the state variable key to value, making it available to later phases.
+//
+// Like [SetState], neither the key nor the value may be empty and both are trimmed of
+// surrounding whitespace.
+func (s *State) Set(key, value string) error {
+	if key = strings.TrimSpace(key); key == phaseKey || key == cleanupKey {
+		return fmt.Errorf("state variable %s is reserved", key)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.set(key, value)
+}
+
+// Save sets the state variable key to value encoded as JSON, to be decoded in a later
+// phase with [State.Load].
+func (s *State) Save(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("could not encode state variable %s: %w", key, err)
+	}
+
+	return s.Set(key, string(data))
+}
+
+// Load decodes the JSON encoded state variable key, as set by [State.Save], into the value
+// pointed to by value.
+//
+// If the variable isn't set, value is left untouched and the boolean is false.
+func (s *State) Load(key string, value any) (ok bool, err error) {
+	data, ok := s.Get(key)
+	if !ok {
+		return false, nil
+	}
+
+	if err = json.Unmarshal([]byte(data), value); err != nil {
+		return true, fmt.Errorf("could not decode state variable %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// AddCleanup registers the cleanup task called name, from [Action.Cleanup], to be run
+// automatically in the post phase.
+//
+// Registering the same task more than once has no effect. Tasks can only be registered
+// during pre and main.
+func (s *State) AddCleanup(name string) error {
+	if s.phase == PhasePost {
+		return fmt.Errorf("cannot add cleanup task %q during the post phase", name)
+	}
+
+	if _, ok := s.cleanup[name]; !ok {
+		return fmt.Errorf("no cleanup task named %q in Action.Cleanup", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.cleanups()
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(names, name) {
+		return nil
+	}
+
+	data, err := json.Marshal(append(names, name))
+	if err != nil {
+		return fmt.Errorf("could not encode cleanup tasks: %w", err)
+	}
+
+	if err = s.set(cleanupKey, string(data)); err != nil {
+		return fmt.Errorf("could not add cleanup task %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// cleanups returns the names of the registered cleanup tasks, in the order they were
+// registered. The caller must hold s.mu.
+func (s *State) cleanups() ([]string, error) {
+	data, ok := s.get(cleanupKey)
+	if !ok {
+		return nil, nil
+	}
+
+	var names []string
+	if err := json.Unmarshal([]byte(data), &names); err != nil {
+		return nil, fmt.Errorf("could not decode cleanup tasks: %w", err)
+	}
+
+	return names, nil
+}
+
+// get implements [State.Get], the caller must hold s.mu.
+func (s *State) get(key string) (string, bool) {
+	if value, ok := s.values[key]; ok {
+		return value, true
+	}
+
+	return GetState(key)
+}
+
+// set implements [State.Set] without checking for reserved keys, the caller must hold s.mu.
+func (s *State) set(key, value string) error {
+	if err := SetState(key, value); err != nil {
+		return err
+	}
+
+	s.values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+
+	return nil
+}
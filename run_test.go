@@ -0,0 +1,343 @@
+package actions //nolint: testpackage // See actions_test.go
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.followtheprocess.codes/actions/internal/filecmd"
+	"go.followtheprocess.codes/test"
+)
+
+// runnerState points $TEST_GITHUB_STATE at an empty file and returns a function that passes
+// the state written to it on to the next phase, like the runner does.
+func runnerState(t *testing.T) (next func()) {
+	t.Helper()
+
+	old := stateFile
+	stateFile = testStateName
+
+	t.Cleanup(func() { stateFile = old })
+
+	path := filepath.Join(t.TempDir(), "state")
+	test.Ok(t, os.WriteFile(path, nil, filePermissions))
+
+	t.Setenv(stateFile, path)
+
+	return func() {
+		t.Helper()
+
+		file, err := os.Open(path)
+		test.Ok(t, err)
+
+		state, err := filecmd.Parse(file)
+		file.Close()
+		test.Ok(t, err)
+
+		for key, value := range state {
+			t.Setenv("STATE_"+key, value)
+		}
+
+		// Each phase gets a fresh file
+		test.Ok(t, os.WriteFile(path, nil, filePermissions))
+	}
+}
+
+func TestDetectPhase(t *testing.T) {
+	tests := []struct {
+		name    string // Name of the test case
+		marker  string // The phase marker in the state, if any
+		args    []string
+		want    Phase
+		wantErr bool // Whether detectPhase should return an error
+	}{
+		{name: "first run", want: PhaseMain},
+		{name: "after pre", marker: "pre", want: PhaseMain},
+		{name: "after main", marker: "main", want: PhasePost},
+		{name: "explicit pre", args: []string{"--actions-phase=pre"}, want: PhasePre},
+		{name: "explicit main", marker: "main", args: []string{"--actions-phase=main"}, want: PhaseMain},
+		{name: "explicit post", args: []string{"--actions-phase=post"}, want: PhasePost},
+		{name: "other argument", args: []string{"--verbose"}, want: PhaseMain},
+		{name: "other argument after main", marker: "main", args: []string{"--verbose"}, want: PhasePost},
+		{name: "bare phase name", marker: "main", args: []string{"main"}, want: PhasePost},
+		{name: "flag not first", args: []string{"--verbose", "--actions-phase=post"}, want: PhaseMain},
+		{name: "unknown phase", args: []string{"--actions-phase=during"}, want: PhaseMain, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STATE_"+phaseKey, tt.marker)
+
+			if tt.marker == "" {
+				os.Unsetenv("STATE_" + phaseKey)
+			}
+
+			got, err := detectPhase(tt.args)
+			test.WantErr(t, err, tt.wantErr)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestPhaseString(t *testing.T) {
+	test.Equal(t, PhasePre.String(), "pre")
+	test.Equal(t, PhaseMain.String(), "main")
+	test.Equal(t, PhasePost.String(), "post")
+	test.Equal(t, Phase(42).String(), "Phase(42)")
+}
+
+func TestRun(t *testing.T) {
+	t.Run("lifecycle", func(t *testing.T) {
+		next := runnerState(t)
+
+		var calls []string
+
+		record := func(name string) PhaseFunc {
+			return func(ctx context.Context, state *State) error {
+				calls = append(calls, name)
+				return nil
+			}
+		}
+
+		type server struct {
+			Addr string `json:"addr"`
+			PID  int    `json:"pid"`
+		}
+
+		action := Action{
+			Pre: func(ctx context.Context, state *State) error {
+				calls = append(calls, "pre")
+
+				test.Equal(t, state.Phase(), PhasePre)
+				test.Ok(t, state.Set("from_pre", "hello"))
+
+				return state.AddCleanup("remove-cache")
+			},
+			Main: func(ctx context.Context, state *State) error {
+				calls = append(calls, "main")
+
+				test.Equal(t, state.Phase(), PhaseMain)
+
+				value, ok := state.Get("from_pre")
+				test.True(t, ok)
+				test.Equal(t, value, "hello")
+
+				test.Ok(t, state.Save("server", server{Addr: "localhost:8080", PID: 1234}))
+				test.Ok(t, state.AddCleanup("stop-server"))
+				test.Ok(t, state.AddCleanup("stop-server")) // Twice is a no-op
+
+				// Readable within the same phase
+				var got server
+				ok, err := state.Load("server", &got)
+				test.Ok(t, err)
+				test.True(t, ok)
+				test.Equal(t, got.PID, 1234)
+
+				return nil
+			},
+			Post: func(ctx context.Context, state *State) error {
+				calls = append(calls, "post")
+
+				test.Equal(t, state.Phase(), PhasePost)
+
+				var got server
+				ok, err := state.Load("server", &got)
+				test.Ok(t, err)
+				test.True(t, ok)
+				test.Equal(t, got, server{Addr: "localhost:8080", PID: 1234})
+
+				err = state.AddCleanup("stop-server")
+				test.Err(t, err)
+
+				return nil
+			},
+			Cleanup: map[string]PhaseFunc{
+				"remove-cache": record("remove-cache"),
+				"stop-server":  record("stop-server"),
+				"unused":       record("unused"),
+			},
+		}
+
+		test.Ok(t, run(t.Context(), action, []string{"--actions-phase=pre"}))
+		next()
+		test.Ok(t, run(t.Context(), action, nil))
+		next()
+		test.Ok(t, run(t.Context(), action, nil))
+
+		want := []string{"pre", "main", "post", "stop-server", "remove-cache"}
+		test.EqualFunc(t, calls, want, slices.Equal)
+	})
+
+	t.Run("main fails", func(t *testing.T) {
+		next := runnerState(t)
+
+		var stopped bool
+
+		action := Action{
+			Main: func(ctx context.Context, state *State) error {
+				test.Ok(t, state.AddCleanup("stop"))
+				return errors.New("bang")
+			},
+			Cleanup: map[string]PhaseFunc{
+				"stop": func(ctx context.Context, state *State) error {
+					stopped = true
+					return nil
+				},
+			},
+		}
+
+		test.Err(t, run(t.Context(), action, nil))
+		next()
+		test.Ok(t, run(t.Context(), action, nil))
+		test.True(t, stopped)
+	})
+
+	t.Run("post errors", func(t *testing.T) {
+		next := runnerState(t)
+
+		var ran []string
+
+		fail := func(name string) PhaseFunc {
+			return func(ctx context.Context, state *State) error {
+				ran = append(ran, name)
+				return errors.New(name + " failed")
+			}
+		}
+
+		action := Action{
+			Main: func(ctx context.Context, state *State) error {
+				test.Ok(t, state.AddCleanup("one"))
+				return state.AddCleanup("two")
+			},
+			Post: fail("post"),
+			Cleanup: map[string]PhaseFunc{
+				"one": fail("one"),
+				"two": fail("two"),
+			},
+		}
+
+		test.Ok(t, run(t.Context(), action, nil))
+		next()
+
+		err := run(t.Context(), action, nil)
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "post failed\ncleanup task \"two\" failed: two failed\ncleanup task \"one\" failed: one failed")
+		test.EqualFunc(t, ran, []string{"post", "two", "one"}, slices.Equal)
+	})
+
+	t.Run("no pre", func(t *testing.T) {
+		runnerState(t)
+
+		action := Action{Main: func(context.Context, *State) error { return nil }}
+
+		err := run(t.Context(), action, []string{"--actions-phase=pre"})
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "action has no pre phase")
+	})
+
+	t.Run("phase flag removed from args", func(t *testing.T) {
+		runnerState(t)
+
+		old := os.Args
+		os.Args = []string{"action", "--actions-phase=main", "--verbose"}
+
+		t.Cleanup(func() { os.Args = old })
+
+		var args []string
+
+		action := Action{
+			Main: func(context.Context, *State) error {
+				args = os.Args
+				return nil
+			},
+		}
+
+		test.Ok(t, Run(t.Context(), action))
+		test.EqualFunc(t, args, []string{"action", "--verbose"}, slices.Equal)
+	})
+
+	t.Run("no main", func(t *testing.T) {
+		runnerState(t)
+
+		err := run(t.Context(), Action{}, nil)
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "action has no main phase")
+	})
+
+	t.Run("no state file", func(t *testing.T) {
+		runnerState(t)
+		t.Setenv(stateFile, "")
+
+		called := false
+		action := Action{
+			Main: func(context.Context, *State) error {
+				called = true
+				return nil
+			},
+		}
+
+		test.Err(t, run(t.Context(), action, nil))
+		test.False(t, called)
+	})
+}
+
+func TestState(t *testing.T) {
+	t.Run("reserved", func(t *testing.T) {
+		runnerState(t)
+
+		state := &State{values: make(map[string]string)}
+
+		test.Err(t, state.Set(phaseKey, "post"))
+		test.Err(t, state.Set(" "+cleanupKey, "[]"))
+	})
+
+	t.Run("unknown cleanup", func(t *testing.T) {
+		runnerState(t)
+
+		state := &State{values: make(map[string]string), phase: PhaseMain}
+
+		err := state.AddCleanup("missing")
+		test.Err(t, err)
+		test.Equal(t, err.Error(), `no cleanup task named "missing" in Action.Cleanup`)
+	})
+
+	t.Run("load missing", func(t *testing.T) {
+		runnerState(t)
+
+		state := &State{values: make(map[string]string)}
+
+		value := 42
+		ok, err := state.Load("missing", &value)
+		test.Ok(t, err)
+		test.False(t, ok)
+		test.Equal(t, value, 42)
+	})
+
+	t.Run("load invalid", func(t *testing.T) {
+		runnerState(t)
+		t.Setenv("STATE_number", "not json")
+
+		state := &State{values: make(map[string]string)}
+
+		var value int
+		ok, err := state.Load("number", &value)
+		test.Err(t, err)
+		test.True(t, ok)
+	})
+
+	t.Run("overrides earlier phase", func(t *testing.T) {
+		runnerState(t)
+		t.Setenv("STATE_key", "old")
+
+		state := &State{values: make(map[string]string)}
+
+		test.Ok(t, state.Set("key", "  new  "))
+
+		value, ok := state.Get("key")
+		test.True(t, ok)
+		test.Equal(t, value, "new")
+	})
+}
@@ -0,0 +1,288 @@
+package actions
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TableCell is a single cell in a table added to a step summary with [SummaryBuilder.Table].
+type TableCell struct {
+	Data    string // The text content of the cell, this will be HTML escaped
+	Colspan int    // Number of columns the cell spans, omitted if < 2
+	Rowspan int    // Number of rows the cell spans, omitted if < 2
+	Header  bool   // Whether the cell is a header cell (<th>) rather than a data cell (<td>)
+}
+
+// SummaryBuilder incrementally builds up a step summary in an internal buffer before
+// writing it to $GITHUB_STEP_SUMMARY.
+//
+// It is modelled on the summary API in the actions toolkit. Each method adds an HTML
+// element to the buffer and returns the builder so calls may be chained:
+//
+//	summary := actions.NewSummary().
+//		Heading("Test Results", 2).
+//		Paragraph("All tests passed!").
+//		List([]string{"unit", "integration"}, false)
+//
+//	if err := summary.Write(); err != nil {
+//		// Handle error
+//	}
+//
+// Unlike [Summary], [SummaryBuilder.Write] appends to the step summary so a summary may be
+// built up across multiple writes, use [SummaryBuilder.Overwrite] to replace it instead.
+//
+// All text content is HTML escaped, use [SummaryBuilder.Raw] to add content verbatim.
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions#adding-a-job-summary
+type SummaryBuilder struct {
+	buf strings.Builder
+}
+
+// NewSummary returns a new, empty [SummaryBuilder].
+func NewSummary() *SummaryBuilder {
+	return &SummaryBuilder{}
+}
+
+// Raw adds text to the summary buffer verbatim, with no escaping or trailing newline.
+//
+// This may be used to add arbitrary markdown or HTML to the summary.
+func (s *SummaryBuilder) Raw(text string) *SummaryBuilder {
+	s.buf.WriteString(text)
+	return s
+}
+
+// EOL adds a newline to the summary buffer.
+func (s *SummaryBuilder) EOL() *SummaryBuilder {
+	s.buf.WriteByte('\n')
+	return s
+}
+
+// Heading adds a heading (<h1> to <h6>) to the summary buffer.
+//
+// If level is outside the range 1-6 it is clamped to the nearest valid level.
+func (s *SummaryBuilder) Heading(text string, level int) *SummaryBuilder {
+	level = min(max(level, 1), 6)
+	tag := "h" + strconv.Itoa(level)
+
+	return s.element(tag, html.EscapeString(text))
+}
+
+// Paragraph adds a paragraph of text (<p>) to the summary buffer.
+func (s *SummaryBuilder) Paragraph(text string) *SummaryBuilder {
+	return s.element("p", html.EscapeString(text))
+}
+
+// CodeBlock adds a block of code (<pre><code>) to the summary buffer.
+//
+// If lang is not empty it is used to syntax highlight the code.
+func (s *SummaryBuilder) CodeBlock(code, lang string) *SummaryBuilder {
+	code = "<code>" + html.EscapeString(code) + "</code>"
+
+	if lang == "" {
+		return s.element("pre", code)
+	}
+
+	return s.element("pre", code, "lang", lang)
+}
+
+// List adds a bulleted (<ul>) or, if ordered is true, numbered (<ol>) list to the summary buffer.
+func (s *SummaryBuilder) List(items []string, ordered bool) *SummaryBuilder {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	list := &strings.Builder{}
+	for _, item := range items {
+		list.WriteString("<li>")
+		list.WriteString(html.EscapeString(item))
+		list.WriteString("</li>")
+	}
+
+	return s.element(tag, list.String())
+}
+
+// Table adds a table (<table>) to the summary buffer.
+//
+// Each element of rows is a row in the table, mark cells with Header to make a header row.
+func (s *SummaryBuilder) Table(rows [][]TableCell) *SummaryBuilder {
+	table := &strings.Builder{}
+
+	for _, row := range rows {
+		table.WriteString("<tr>")
+
+		for _, cell := range row {
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+
+			table.WriteByte('<')
+			table.WriteString(tag)
+
+			if cell.Colspan > 1 {
+				table.WriteString(` colspan="` + strconv.Itoa(cell.Colspan) + `"`)
+			}
+
+			if cell.Rowspan > 1 {
+				table.WriteString(` rowspan="` + strconv.Itoa(cell.Rowspan) + `"`)
+			}
+
+			table.WriteByte('>')
+			table.WriteString(html.EscapeString(cell.Data))
+			table.WriteString("</" + tag + ">")
+		}
+
+		table.WriteString("</tr>")
+	}
+
+	return s.element("table", table.String())
+}
+
+// Details adds a collapsible section (<details>) to the summary buffer, with label shown
+// when collapsed and content revealed when expanded.
+func (s *SummaryBuilder) Details(label, content string) *SummaryBuilder {
+	details := "<summary>" + html.EscapeString(label) + "</summary>" + html.EscapeString(content)
+	return s.element("details", details)
+}
+
+// Image adds an image (<img>) to the summary buffer.
+//
+// If width or height are > 0 they are set on the image, otherwise they are omitted.
+func (s *SummaryBuilder) Image(src, alt string, width, height int) *SummaryBuilder {
+	attrs := []string{"src", src, "alt", alt}
+
+	if width > 0 {
+		attrs = append(attrs, "width", strconv.Itoa(width))
+	}
+
+	if height > 0 {
+		attrs = append(attrs, "height", strconv.Itoa(height))
+	}
+
+	return s.void("img", attrs...)
+}
+
+// Link adds a hyperlink (<a>) to the summary buffer.
+func (s *SummaryBuilder) Link(text, href string) *SummaryBuilder {
+	return s.element("a", html.EscapeString(text), "href", href)
+}
+
+// Quote adds a quote (<blockquote>) to the summary buffer.
+//
+// If cite is not empty, it is added as the URL of the source of the quote.
+func (s *SummaryBuilder) Quote(text, cite string) *SummaryBuilder {
+	if cite == "" {
+		return s.element("blockquote", html.EscapeString(text))
+	}
+
+	return s.element("blockquote", html.EscapeString(text), "cite", cite)
+}
+
+// Separator adds a horizontal rule (<hr>) to the summary buffer.
+func (s *SummaryBuilder) Separator() *SummaryBuilder {
+	return s.void("hr")
+}
+
+// Break adds a line break (<br>) to the summary buffer.
+func (s *SummaryBuilder) Break() *SummaryBuilder {
+	return s.void("br")
+}
+
+// Stringify returns the current contents of the summary buffer.
+func (s *SummaryBuilder) Stringify() string {
+	return s.buf.String()
+}
+
+// IsEmpty reports whether the summary buffer is empty.
+func (s *SummaryBuilder) IsEmpty() bool {
+	return s.buf.Len() == 0
+}
+
+// Empty discards the contents of the summary buffer without writing it anywhere.
+func (s *SummaryBuilder) Empty() *SummaryBuilder {
+	s.buf.Reset()
+	return s
+}
+
+// Write appends the contents of the summary buffer to $GITHUB_STEP_SUMMARY, creating
+// the file if necessary, then empties the buffer.
+func (s *SummaryBuilder) Write() error {
+	path := os.Getenv(summaryFile)
+	if path == "" {
+		return fmt.Errorf("$%s is not set or is empty", summaryFile)
+	}
+
+	//nolint:gosec // G703: path is set by the trusted Actions runner, not user input
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePermissions)
+	if err != nil {
+		return fmt.Errorf("could not open $%s file %s: %w", summaryFile, path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(s.buf.String()); err != nil {
+		return fmt.Errorf("could not write to $%s at path %s: %w", summaryFile, path, err)
+	}
+
+	s.buf.Reset()
+
+	return nil
+}
+
+// Overwrite replaces the contents of $GITHUB_STEP_SUMMARY with the contents of the summary
+// buffer, creating the file if necessary, then empties the buffer.
+func (s *SummaryBuilder) Overwrite() error {
+	if err := Summary(s.buf.String()); err != nil {
+		return err
+	}
+
+	s.buf.Reset()
+
+	return nil
+}
+
+// Clear empties both the summary buffer and $GITHUB_STEP_SUMMARY.
+func (s *SummaryBuilder) Clear() error {
+	s.buf.Reset()
+	return Summary("")
+}
+
+// element writes an HTML element with the given tag, inner content and attributes to the
+// buffer followed by a newline.
+//
+// Attributes are given as alternating key, value pairs and their values are escaped.
+func (s *SummaryBuilder) element(tag, content string, attrs ...string) *SummaryBuilder {
+	s.openTag(tag, attrs...)
+	s.buf.WriteString(content)
+	s.buf.WriteString("</" + tag + ">\n")
+
+	return s
+}
+
+// void writes a void HTML element (one that has no content or closing tag) like <hr>
+// to the buffer followed by a newline.
+func (s *SummaryBuilder) void(tag string, attrs ...string) *SummaryBuilder {
+	s.openTag(tag, attrs...)
+	s.buf.WriteByte('\n')
+
+	return s
+}
+
+// openTag writes an opening HTML tag with attributes to the buffer.
+func (s *SummaryBuilder) openTag(tag string, attrs ...string) {
+	s.buf.WriteByte('<')
+	s.buf.WriteString(tag)
+
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.buf.WriteByte(' ')
+		s.buf.WriteString(attrs[i])
+		s.buf.WriteString(`="`)
+		s.buf.WriteString(html.EscapeString(attrs[i+1]))
+		s.buf.WriteByte('"')
+	}
+
+	s.buf.WriteByte('>')
+}
@@ -0,0 +1,207 @@
+package actions //nolint: testpackage // See actions_test.go
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/test"
+)
+
+func TestSummaryBuilder(t *testing.T) {
+	tests := []struct {
+		build func(s *SummaryBuilder) // Build the summary
+		name  string                  // Name of the test case
+		want  string                  // Expected contents of the buffer
+	}{
+		{
+			name:  "empty",
+			build: func(s *SummaryBuilder) {},
+			want:  "",
+		},
+		{
+			name: "raw",
+			build: func(s *SummaryBuilder) {
+				s.Raw("# Markdown <b>as is</b>").EOL()
+			},
+			want: "# Markdown <b>as is</b>\n",
+		},
+		{
+			name: "heading",
+			build: func(s *SummaryBuilder) {
+				s.Heading("Results", 2)
+			},
+			want: "<h2>Results</h2>\n",
+		},
+		{
+			name: "heading clamped",
+			build: func(s *SummaryBuilder) {
+				s.Heading("Too small", 0).Heading("Too big", 9)
+			},
+			want: "<h1>Too small</h1>\n<h6>Too big</h6>\n",
+		},
+		{
+			name: "paragraph escaped",
+			build: func(s *SummaryBuilder) {
+				s.Paragraph("1 < 2 & 3 > 2")
+			},
+			want: "<p>1 &lt; 2 &amp; 3 &gt; 2</p>\n",
+		},
+		{
+			name: "code block",
+			build: func(s *SummaryBuilder) {
+				s.CodeBlock("if a < b {}", "go")
+			},
+			want: `<pre lang="go"><code>if a &lt; b {}</code></pre>` + "\n",
+		},
+		{
+			name: "code block no lang",
+			build: func(s *SummaryBuilder) {
+				s.CodeBlock("echo hello", "")
+			},
+			want: "<pre><code>echo hello</code></pre>\n",
+		},
+		{
+			name: "bulleted list",
+			build: func(s *SummaryBuilder) {
+				s.List([]string{"one", "<two>"}, false)
+			},
+			want: "<ul><li>one</li><li>&lt;two&gt;</li></ul>\n",
+		},
+		{
+			name: "numbered list",
+			build: func(s *SummaryBuilder) {
+				s.List([]string{"first", "second"}, true)
+			},
+			want: "<ol><li>first</li><li>second</li></ol>\n",
+		},
+		{
+			name: "table",
+			build: func(s *SummaryBuilder) {
+				s.Table([][]TableCell{
+					{{Data: "File", Header: true}, {Data: "Result", Header: true}},
+					{{Data: "a.go"}, {Data: "pass"}},
+					{{Data: "<all>", Colspan: 2}},
+					{{Data: "b.go", Rowspan: 2}, {Data: "fail"}},
+				})
+			},
+			want: "<table><tr><th>File</th><th>Result</th></tr><tr><td>a.go</td><td>pass</td></tr>" +
+				`<tr><td colspan="2">&lt;all&gt;</td></tr><tr><td rowspan="2">b.go</td><td>fail</td></tr></table>` + "\n",
+		},
+		{
+			name: "details",
+			build: func(s *SummaryBuilder) {
+				s.Details("Logs", "lots of <output>")
+			},
+			want: "<details><summary>Logs</summary>lots of &lt;output&gt;</details>\n",
+		},
+		{
+			name: "image",
+			build: func(s *SummaryBuilder) {
+				s.Image("https://example.com/a.png", `a "chart"`, 32, 0)
+			},
+			want: `<img src="https://example.com/a.png" alt="a &#34;chart&#34;" width="32">` + "\n",
+		},
+		{
+			name: "link",
+			build: func(s *SummaryBuilder) {
+				s.Link("docs & more", "https://example.com?a=1&b=2")
+			},
+			want: `<a href="https://example.com?a=1&amp;b=2">docs &amp; more</a>` + "\n",
+		},
+		{
+			name: "quote",
+			build: func(s *SummaryBuilder) {
+				s.Quote("Simplicity is complicated", "https://go.dev").Quote("No cite", "")
+			},
+			want: `<blockquote cite="https://go.dev">Simplicity is complicated</blockquote>` + "\n" +
+				"<blockquote>No cite</blockquote>\n",
+		},
+		{
+			name: "separator and break",
+			build: func(s *SummaryBuilder) {
+				s.Separator().Break()
+			},
+			want: "<hr>\n<br>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := NewSummary()
+			tt.build(summary)
+
+			test.Diff(t, summary.Stringify(), tt.want)
+			test.Equal(t, summary.IsEmpty(), tt.want == "")
+
+			summary.Empty()
+			test.True(t, summary.IsEmpty())
+		})
+	}
+}
+
+func TestSummaryBuilderWrite(t *testing.T) {
+	old := summaryFile
+	summaryFile = testSummaryName
+
+	t.Cleanup(func() { summaryFile = old })
+
+	t.Run("unset", func(t *testing.T) {
+		err := NewSummary().Heading("Nope", 1).Write()
+		test.Err(t, err) // $TEST_GITHUB_STEP_SUMMARY is not set
+	})
+
+	t.Run("append", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "summary.md")
+		t.Setenv(summaryFile, path) // Doesn't exist yet, should be created
+
+		summary := NewSummary()
+
+		err := summary.Heading("First", 1).Write()
+		test.Ok(t, err)
+		test.True(t, summary.IsEmpty()) // Buffer is emptied after a write
+
+		err = summary.Paragraph("Second").Write()
+		test.Ok(t, err)
+
+		written, err := os.ReadFile(path)
+		test.Ok(t, err)
+		test.Diff(t, string(written), "<h1>First</h1>\n<p>Second</p>\n")
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "summary.md")
+		t.Setenv(summaryFile, path)
+
+		err := os.WriteFile(path, []byte("original contents"), filePermissions)
+		test.Ok(t, err)
+
+		summary := NewSummary()
+
+		err = summary.Heading("Only", 1).Overwrite()
+		test.Ok(t, err)
+		test.True(t, summary.IsEmpty())
+
+		written, err := os.ReadFile(path)
+		test.Ok(t, err)
+		test.Diff(t, string(written), "<h1>Only</h1>\n")
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "summary.md")
+		t.Setenv(summaryFile, path)
+
+		err := os.WriteFile(path, []byte("original contents"), filePermissions)
+		test.Ok(t, err)
+
+		summary := NewSummary().Paragraph("Pending")
+
+		err = summary.Clear()
+		test.Ok(t, err)
+		test.True(t, summary.IsEmpty())
+
+		written, err := os.ReadFile(path)
+		test.Ok(t, err)
+		test.Equal(t, string(written), "")
+	})
+}
@@ -0,0 +1,349 @@
+package toolcache
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Extract extracts archive into the directory dest, choosing the format based on the
+// file extension of archive, and returns the path to dest.
+//
+// Supported formats are .tar, .tar.gz, .tgz, .tar.xz, .txz and .zip. If dest is empty,
+// a uniquely named directory in $RUNNER_TEMP is used.
+//
+// Note that archives downloaded with [Download] have no extension, in which case use
+// the format specific functions [ExtractTar], [ExtractTarXz] or [ExtractZip] directly.
+func Extract(ctx context.Context, archive, dest string) (string, error) {
+	name := strings.ToLower(archive)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".txz"):
+		return ExtractTarXz(ctx, archive, dest)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"), strings.HasSuffix(name, ".tar"):
+		return ExtractTar(archive, dest)
+	case strings.HasSuffix(name, ".zip"):
+		return ExtractZip(archive, dest)
+	default:
+		return "", fmt.Errorf("could not determine archive format of %s", archive)
+	}
+}
+
+// ExtractTar extracts the tar archive, which may optionally be gzip compressed, into the
+// directory dest and returns the path to dest.
+//
+// If dest is empty, a uniquely named directory in $RUNNER_TEMP is used. Entries that would
+// be extracted outside of dest, including through symlinks earlier in the archive, are
+// rejected with an error.
+func ExtractTar(archive, dest string) (string, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", archive, err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+
+	var r io.Reader = buffered
+
+	// Sniff the gzip magic number rather than relying on the file extension
+	if magic, err := buffered.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return "", fmt.Errorf("could not decompress %s: %w", archive, err)
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	return extractTar(r, archive, dest)
+}
+
+// ExtractTarXz extracts the xz compressed tar archive into the directory dest and returns
+// the path to dest.
+//
+// The standard library has no xz decompressor, so this requires the xz command, which is
+// available on all GitHub hosted runners, to decompress the archive. The tar archive it
+// outputs is extracted in the same way as [ExtractTar], so entries that would be extracted
+// outside of dest are rejected with an error. If dest is empty, a uniquely named directory in
+// $RUNNER_TEMP is used.
+func ExtractTarXz(ctx context.Context, archive, dest string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, "xz", "--decompress", "--stdout", archive)
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("could not decompress %s: %w", archive, err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		return "", fmt.Errorf("could not decompress %s: %w", archive, err)
+	}
+
+	dest, extractErr := extractTar(stdout, archive, dest)
+	if extractErr != nil {
+		// xz may be blocked writing the rest of the archive
+		cancel()
+	} else if _, err = io.Copy(io.Discard, stdout); err != nil {
+		extractErr = fmt.Errorf("could not read %s: %w", archive, err)
+	}
+
+	// If xz failed the archive was truncated, which xz explains better than the tar reader
+	if err = cmd.Wait(); err != nil && (extractErr == nil || stderr.Len() > 0) {
+		return "", fmt.Errorf("could not decompress %s: %w: %s", archive, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if extractErr != nil {
+		return "", extractErr
+	}
+
+	return dest, nil
+}
+
+// extractTar extracts the uncompressed tar archive read from r into the directory dest, see
+// [ExtractTar], and returns the path to dest. The archive is only used in errors.
+func extractTar(r io.Reader, archive, dest string) (string, error) {
+	dest, err := prepareDest(dest)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := os.OpenRoot(dest)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", dest, err)
+	}
+	defer root.Close()
+
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("could not read %s: %w", archive, err)
+		}
+
+		if err := extractTarEntry(tr, header, root); err != nil {
+			return "", fmt.Errorf("could not extract %s from %s: %w", header.Name, archive, err)
+		}
+	}
+
+	return dest, nil
+}
+
+// ExtractZip extracts the zip archive into the directory dest and returns the path to dest.
+//
+// If dest is empty, a uniquely named directory in $RUNNER_TEMP is used. Entries that would
+// be extracted outside of dest, including through symlinks earlier in the archive, are
+// rejected with an error.
+func ExtractZip(archive, dest string) (string, error) {
+	dest, err := prepareDest(dest)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := os.OpenRoot(dest)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", dest, err)
+	}
+	defer root.Close()
+
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %w", archive, err)
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if err := extractZipEntry(file, root); err != nil {
+			return "", fmt.Errorf("could not extract %s from %s: %w", file.Name, archive, err)
+		}
+	}
+
+	return dest, nil
+}
+
+// extractTarEntry extracts a single tar entry into root.
+func extractTarEntry(tr *tar.Reader, header *tar.Header, root *os.Root) error {
+	name, err := within(root, header.Name)
+	if err != nil {
+		return err
+	}
+
+	mode := header.FileInfo().Mode()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return root.MkdirAll(name, mode.Perm()|0o700)
+	case tar.TypeReg:
+		return writeFile(root, name, tr, mode)
+	case tar.TypeSymlink:
+		return symlink(root, name, header.Linkname)
+	case tar.TypeLink:
+		source, err := within(root, header.Linkname)
+		if err != nil {
+			return err
+		}
+
+		if err := root.MkdirAll(filepath.Dir(name), dirPermissions); err != nil {
+			return err
+		}
+
+		return root.Link(source, name)
+	default:
+		// Devices, fifos etc. have no place in a tool archive
+		return nil
+	}
+}
+
+// extractZipEntry extracts a single zip entry into root.
+func extractZipEntry(file *zip.File, root *os.Root) error {
+	name, err := within(root, file.Name)
+	if err != nil {
+		return err
+	}
+
+	mode := file.Mode()
+
+	if mode.IsDir() {
+		return root.MkdirAll(name, mode.Perm()|0o700)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&fs.ModeSymlink != 0 {
+		// The content of a symlink entry is the link target
+		link, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+
+		return symlink(root, name, string(link))
+	}
+
+	// Zips created on windows often have no permissions set at all
+	if mode.Perm() == 0 {
+		mode |= filePermissions
+	}
+
+	return writeFile(root, name, rc, mode)
+}
+
+// within returns name as a local path within root, returning an error if it would lie
+// outside of it, either lexically or by passing through a symlink already extracted.
+//
+// Everything is written through root so nothing can escape it regardless, but rejecting
+// paths through symlinks means symlink targets can be checked where they really are.
+func within(root *os.Root, name string) (string, error) {
+	local := filepath.Clean(filepath.FromSlash(name))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("illegal path %q in archive escapes destination", name)
+	}
+
+	dir := ""
+	for part := range strings.SplitSeq(filepath.Dir(local), string(filepath.Separator)) {
+		if part == "." {
+			break
+		}
+
+		dir = filepath.Join(dir, part)
+
+		info, err := root.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("illegal path %q in archive passes through symlink %q", name, dir)
+		}
+	}
+
+	return local, nil
+}
+
+// symlink creates a symlink at name in root pointing to link, returning an error if the
+// link would resolve to somewhere outside of root.
+func symlink(root *os.Root, name, link string) error {
+	if filepath.IsAbs(link) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), link)) {
+		return fmt.Errorf("illegal symlink %q in archive escapes destination", link)
+	}
+
+	if err := root.MkdirAll(filepath.Dir(name), dirPermissions); err != nil {
+		return err
+	}
+
+	return root.Symlink(link, name)
+}
+
+// writeFile writes the contents of r to a new file at name in root with mode.
+func writeFile(root *os.Root, name string, r io.Reader, mode fs.FileMode) error {
+	if err := root.MkdirAll(filepath.Dir(name), dirPermissions); err != nil {
+		return err
+	}
+
+	file, err := root.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	//nolint:gosec // G110: tool archives are trusted and verified by checksum
+	if _, err := io.Copy(file, r); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// prepareDest creates the extraction directory dest, or a unique one in $RUNNER_TEMP if
+// dest is empty, and returns its absolute path.
+func prepareDest(dest string) (string, error) {
+	if dest == "" {
+		temp, err := tempDir()
+		if err != nil {
+			return "", err
+		}
+
+		dest = filepath.Join(temp, rand.Text())
+	}
+
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s: %w", dest, err)
+	}
+
+	if err := os.MkdirAll(dest, dirPermissions); err != nil {
+		return "", fmt.Errorf("could not create %s: %w", dest, err)
+	}
+
+	return dest, nil
+}
@@ -0,0 +1,303 @@
+package toolcache_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/actions/toolcache"
+	"go.followtheprocess.codes/test"
+)
+
+// entry is a file to put in a test archive.
+type entry struct {
+	name string      // Path in the archive
+	body string      // File contents, or link target for symlinks
+	mode fs.FileMode // File mode
+}
+
+// writeTar writes a tar archive of entries to a new file in dir, gzipping it if compress is true.
+func writeTar(t *testing.T, dir, name string, entries []entry, compress bool) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm())}
+
+		switch {
+		case e.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		case e.mode&fs.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = e.body
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(e.body))
+		}
+
+		test.Ok(t, tw.WriteHeader(header))
+
+		if header.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(e.body))
+			test.Ok(t, err)
+		}
+	}
+
+	test.Ok(t, tw.Close())
+
+	data := buf.Bytes()
+
+	if compress {
+		compressed := &bytes.Buffer{}
+		gz := gzip.NewWriter(compressed)
+		_, err := gz.Write(data)
+		test.Ok(t, err)
+		test.Ok(t, gz.Close())
+
+		data = compressed.Bytes()
+	}
+
+	path := filepath.Join(dir, name)
+	test.Ok(t, os.WriteFile(path, data, 0o644))
+
+	return path
+}
+
+// writeZip writes a zip archive of entries to a new file in dir.
+func writeZip(t *testing.T, dir, name string, entries []entry) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		header.SetMode(e.mode)
+
+		w, err := zw.CreateHeader(header)
+		test.Ok(t, err)
+
+		if !e.mode.IsDir() {
+			_, err = w.Write([]byte(e.body))
+			test.Ok(t, err)
+		}
+	}
+
+	test.Ok(t, zw.Close())
+
+	path := filepath.Join(dir, name)
+	test.Ok(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	return path
+}
+
+// tool is a typical set of entries in a tool archive.
+var tool = []entry{
+	{name: "tool/", mode: fs.ModeDir | 0o755},
+	{name: "tool/bin/tool", body: "binary", mode: 0o755},
+	{name: "tool/README.md", body: "# Tool", mode: 0o644},
+	{name: "tool/bin/alias", body: "tool", mode: fs.ModeSymlink | 0o777},
+}
+
+// symlinkChain is a chain of symlinks that are each within the destination on their own,
+// but together put evil in its parent.
+var symlinkChain = []entry{
+	{name: "a", body: ".", mode: fs.ModeSymlink | 0o777},
+	{name: "a/b", body: "..", mode: fs.ModeSymlink | 0o777},
+	{name: "a/b/evil", body: "pwned", mode: 0o644},
+}
+
+// checkTool checks the tool entries were correctly extracted into dir.
+func checkTool(t *testing.T, dir string) {
+	t.Helper()
+
+	got, err := os.ReadFile(filepath.Join(dir, "tool", "bin", "tool"))
+	test.Ok(t, err)
+	test.Equal(t, string(got), "binary")
+
+	got, err = os.ReadFile(filepath.Join(dir, "tool", "README.md"))
+	test.Ok(t, err)
+	test.Equal(t, string(got), "# Tool")
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(dir, "tool", "bin", "tool"))
+		test.Ok(t, err)
+		test.Equal(t, info.Mode().Perm(), os.FileMode(0o755))
+
+		link, err := os.Readlink(filepath.Join(dir, "tool", "bin", "alias"))
+		test.Ok(t, err)
+		test.Equal(t, link, "tool")
+	}
+}
+
+func TestExtractTar(t *testing.T) {
+	t.Run("gzip", func(t *testing.T) {
+		archive := writeTar(t, t.TempDir(), "tool.tar.gz", tool, true)
+		dest := t.TempDir()
+
+		got, err := toolcache.Extract(t.Context(), archive, dest)
+		test.Ok(t, err)
+		test.Equal(t, got, dest)
+
+		checkTool(t, dest)
+	})
+
+	t.Run("uncompressed no extension", func(t *testing.T) {
+		t.Setenv("RUNNER_TEMP", t.TempDir())
+
+		archive := writeTar(t, t.TempDir(), "download", tool, false)
+
+		dest, err := toolcache.ExtractTar(archive, "")
+		test.Ok(t, err)
+		test.True(t, strings.HasPrefix(dest, os.Getenv("RUNNER_TEMP")))
+
+		checkTool(t, dest)
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		archive := writeTar(t, t.TempDir(), "evil.tar", []entry{{name: "../../evil", body: "pwned", mode: 0o644}}, false)
+
+		_, err := toolcache.ExtractTar(archive, t.TempDir())
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "escapes destination"))
+	})
+
+	t.Run("symlink traversal", func(t *testing.T) {
+		archive := writeTar(
+			t,
+			t.TempDir(),
+			"evil.tar",
+			[]entry{{name: "link", body: "../../etc/passwd", mode: fs.ModeSymlink | 0o777}},
+			false,
+		)
+
+		_, err := toolcache.ExtractTar(archive, t.TempDir())
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "escapes destination"))
+	})
+
+	t.Run("symlink chain", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("creating symlinks needs extra privileges on windows")
+		}
+
+		archive := writeTar(t, t.TempDir(), "evil.tar", symlinkChain, false)
+		parent := t.TempDir()
+
+		_, err := toolcache.ExtractTar(archive, filepath.Join(parent, "dest"))
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "passes through symlink"))
+
+		_, err = os.Lstat(filepath.Join(parent, "evil"))
+		test.True(t, os.IsNotExist(err), test.Context("evil was written outside the destination"))
+	})
+}
+
+func TestExtractZip(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		archive := writeZip(t, t.TempDir(), "tool.zip", tool)
+		dest := t.TempDir()
+
+		got, err := toolcache.Extract(t.Context(), archive, dest)
+		test.Ok(t, err)
+		test.Equal(t, got, dest)
+
+		checkTool(t, dest)
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		archive := writeZip(t, t.TempDir(), "evil.zip", []entry{{name: "../evil", body: "pwned", mode: 0o644}})
+
+		_, err := toolcache.ExtractZip(archive, t.TempDir())
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "escapes destination"))
+	})
+
+	t.Run("symlink chain", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("creating symlinks needs extra privileges on windows")
+		}
+
+		archive := writeZip(t, t.TempDir(), "evil.zip", symlinkChain)
+		parent := t.TempDir()
+
+		_, err := toolcache.ExtractZip(archive, filepath.Join(parent, "dest"))
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "passes through symlink"))
+
+		_, err = os.Lstat(filepath.Join(parent, "evil"))
+		test.True(t, os.IsNotExist(err), test.Context("evil was written outside the destination"))
+	})
+}
+
+func TestExtractTarXz(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not installed")
+	}
+
+	source := writeTar(t, t.TempDir(), "tool.tar", tool, false)
+
+	// Compress it with the real xz
+	err := exec.CommandContext(t.Context(), "xz", source).Run()
+	test.Ok(t, err)
+
+	dest := t.TempDir()
+
+	got, err := toolcache.Extract(t.Context(), source+".xz", dest)
+	test.Ok(t, err)
+	test.Equal(t, got, dest)
+
+	checkTool(t, dest)
+}
+
+func TestExtractTarXzSymlinkChain(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not installed")
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs extra privileges on windows")
+	}
+
+	source := writeTar(t, t.TempDir(), "evil.tar", symlinkChain, false)
+
+	err := exec.CommandContext(t.Context(), "xz", source).Run()
+	test.Ok(t, err)
+
+	parent := t.TempDir()
+
+	_, err = toolcache.ExtractTarXz(t.Context(), source+".xz", filepath.Join(parent, "dest"))
+	test.Err(t, err)
+	test.True(t, strings.Contains(err.Error(), "passes through symlink"), test.Context("wrong error: %v", err))
+
+	_, err = os.Lstat(filepath.Join(parent, "evil"))
+	test.True(t, os.IsNotExist(err), test.Context("evil was written outside the destination"))
+}
+
+func TestExtractTarXzCorrupt(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not installed")
+	}
+
+	archive := filepath.Join(t.TempDir(), "corrupt.tar.xz")
+	test.Ok(t, os.WriteFile(archive, []byte("not xz"), 0o644))
+
+	_, err := toolcache.ExtractTarXz(t.Context(), archive, t.TempDir())
+	test.Err(t, err)
+	test.True(t, strings.Contains(err.Error(), "could not decompress"), test.Context("wrong error: %v", err))
+}
+
+func TestExtractUnknown(t *testing.T) {
+	_, err := toolcache.Extract(t.Context(), "tool.rar", t.TempDir())
+	test.Err(t, err)
+}
@@ -0,0 +1,667 @@
+// Package toolcache provides mechanisms for downloading, extracting and caching tools
+// in the runner's tool cache, mirroring the @actions/tool-cache package of the actions toolkit.
+//
+// A typical "setup-x" action first looks in the tool cache for a version satisfying the
+// requested range, and only if one is not found does it download, extract and cache it,
+// finally adding it to $PATH:
+//
+//	dir, err := toolcache.Find("mytool", "^1.2", "")
+//	if errors.Is(err, toolcache.ErrNotFound) {
+//		archive, err := toolcache.Download(ctx, url, toolcache.Checksum(sha))
+//		// ...
+//		extracted, err := toolcache.Extract(ctx, archive, "")
+//		// ...
+//		dir, err = toolcache.CacheDir(extracted, "mytool", "1.2.3", "")
+//		// ...
+//	}
+//
+//	if err := actions.AddPath(dir); err != nil {
+//		// Handle error
+//	}
+//
+// See https://github.com/actions/toolkit/tree/main/packages/tool-cache
+package toolcache // import "go.followtheprocess.codes/actions/toolcache"
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"go.followtheprocess.codes/actions"
+	"go.followtheprocess.codes/actions/internal/semver"
+)
+
+const (
+	// tempVar is the env var containing the path to the runner's temporary directory.
+	tempVar = "RUNNER_TEMP"
+
+	// cacheVar is the env var containing the path to the runner's tool cache.
+	cacheVar = "RUNNER_TOOL_CACHE"
+
+	// dirPermissions is the permissions used when creating directories.
+	dirPermissions = 0o755
+
+	// filePermissions is the permissions used when creating files.
+	filePermissions = 0o644
+
+	// completeSuffix is appended to a cached tool directory to create the marker file
+	// that signals the tool was completely cached.
+	completeSuffix = ".complete"
+)
+
+// ErrNotFound is returned from [Find] and [Use] when no cached version of a tool
+// satisfies the requested version range.
+var ErrNotFound = errors.New("tool not found in tool cache")
+
+// HTTPError is returned from [Download] when the server responds with an unsuccessful
+// status code.
+type HTTPError struct {
+	URL        string // The URL being downloaded
+	StatusCode int    // The HTTP status code of the response
+}
+
+// Error implements the error interface for [HTTPError].
+func (h *HTTPError) Error() string {
+	return fmt.Sprintf("download of %s failed with status %d: %s", h.URL, h.StatusCode, http.StatusText(h.StatusCode))
+}
+
+// downloadConfig holds the configuration for a single call to [Download].
+type downloadConfig struct {
+	client   *http.Client  // The HTTP client to download with
+	headers  http.Header   // Additional request headers
+	checksum string        // Expected hex encoded SHA-256 of the download
+	dest     string        // Where to save the download
+	delay    time.Duration // Delay between attempts, doubled each time
+	attempts int           // Maximum number of attempts
+}
+
+// DownloadOption is a configuration option for [Download].
+type DownloadOption interface {
+	// Apply the option to the config.
+	apply(cfg *downloadConfig)
+}
+
+// downloadOption is a function that implements the DownloadOption interface.
+type downloadOption func(cfg *downloadConfig)
+
+// apply applies the option, implementing the DownloadOption interface.
+func (d downloadOption) apply(cfg *downloadConfig) {
+	d(cfg)
+}
+
+// Checksum sets the expected hex encoded SHA-256 checksum of the download.
+//
+// If the downloaded file does not match, it is deleted and [Download] returns an error.
+func Checksum(sha string) DownloadOption {
+	f := func(cfg *downloadConfig) {
+		cfg.checksum = strings.ToLower(strings.TrimSpace(sha))
+	}
+
+	return downloadOption(f)
+}
+
+// Header sets an additional header to send with the download request, e.g. "Authorization".
+func Header(key, value string) DownloadOption {
+	f := func(cfg *downloadConfig) {
+		cfg.headers.Set(key, value)
+	}
+
+	return downloadOption(f)
+}
+
+// Client sets the [*http.Client] used to download, by default [http.DefaultClient] is used.
+func Client(client *http.Client) DownloadOption {
+	f := func(cfg *downloadConfig) {
+		cfg.client = client
+	}
+
+	return downloadOption(f)
+}
+
+// Retry configures how many attempts [Download] will make, and the delay before the first
+// retry which is doubled for each subsequent attempt.
+//
+// Only network errors and server errors (5xx, 408 and 429) are retried. The default is
+// 3 attempts with an initial delay of 10 seconds.
+func Retry(attempts int, delay time.Duration) DownloadOption {
+	f := func(cfg *downloadConfig) {
+		cfg.attempts = max(attempts, 1)
+		cfg.delay = delay
+	}
+
+	return downloadOption(f)
+}
+
+// Dest sets the path the download is saved to, by default a uniquely named file in $RUNNER_TEMP.
+func Dest(path string) DownloadOption {
+	f := func(cfg *downloadConfig) {
+		cfg.dest = path
+	}
+
+	return downloadOption(f)
+}
+
+// Download downloads url to a file, returning the path to the downloaded file.
+//
+// Unless [Dest] is passed, the file is saved with a unique name in $RUNNER_TEMP. Failed
+// downloads are retried according to [Retry] and, if a [Checksum] is passed, the downloaded
+// file is verified against it.
+func Download(ctx context.Context, url string, options ...DownloadOption) (string, error) {
+	cfg := downloadConfig{
+		client:   http.DefaultClient,
+		headers:  make(http.Header),
+		attempts: 3,
+		delay:    10 * time.Second,
+	}
+
+	for _, option := range options {
+		option.apply(&cfg)
+	}
+
+	if cfg.dest == "" {
+		temp, err := tempDir()
+		if err != nil {
+			return "", err
+		}
+
+		cfg.dest = filepath.Join(temp, rand.Text())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.dest), dirPermissions); err != nil {
+		return "", fmt.Errorf("could not create download directory: %w", err)
+	}
+
+	if _, err := os.Stat(cfg.dest); err == nil {
+		return "", fmt.Errorf("download destination %s already exists", cfg.dest)
+	}
+
+	delay := cfg.delay
+
+	var err error
+
+	for attempt := range cfg.attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(delay):
+			}
+
+			delay *= 2
+		}
+
+		var retryable bool
+
+		retryable, err = download(ctx, url, cfg)
+		if err == nil || !retryable {
+			break
+		}
+	}
+
+	if err != nil {
+		os.Remove(cfg.dest)
+		return "", err
+	}
+
+	if cfg.checksum != "" {
+		if err := verify(cfg.dest, cfg.checksum); err != nil {
+			os.Remove(cfg.dest)
+			return "", err
+		}
+	}
+
+	return cfg.dest, nil
+}
+
+// download makes a single attempt at downloading url to cfg.dest, reporting whether
+// any error is worth retrying.
+func download(ctx context.Context, url string, cfg downloadConfig) (retryable bool, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("could not create request for %s: %w", url, err)
+	}
+
+	for key, values := range cfg.headers {
+		request.Header[key] = values
+	}
+
+	//nolint:gosec // G107: downloading a caller supplied URL is the whole point
+	response, err := cfg.client.Do(request)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("could not download %s: %w", url, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		code := response.StatusCode
+		retryable = code >= http.StatusInternalServerError || code == http.StatusRequestTimeout ||
+			code == http.StatusTooManyRequests
+
+		return retryable, &HTTPError{URL: url, StatusCode: code}
+	}
+
+	file, err := os.Create(cfg.dest)
+	if err != nil {
+		return false, fmt.Errorf("could not create %s: %w", cfg.dest, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, response.Body); err != nil {
+		return ctx.Err() == nil, fmt.Errorf("could not download %s: %w", url, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return false, fmt.Errorf("could not write %s: %w", cfg.dest, err)
+	}
+
+	return false, nil
+}
+
+// verify checks the SHA-256 checksum of the file at path matches want.
+func verify(path, want string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s to verify checksum: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("could not read %s to verify checksum: %w", path, err)
+	}
+
+	got := hex.EncodeToString(hash.Sum(nil))
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", path, got, want)
+	}
+
+	return nil
+}
+
+// CacheDir copies the contents of the directory source into the tool cache under
+// $RUNNER_TOOL_CACHE/<tool>/<version>/<arch>, returning the path to the cached directory.
+//
+// Any existing cached copy of the same version is replaced. If arch is empty, the
+// architecture of the current machine is used.
+func CacheDir(source, tool, version, arch string) (string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("could not cache %s: %w", source, err)
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("could not cache %s: not a directory", source)
+	}
+
+	dest, err := prepare(tool, version, arch)
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyDir(source, dest); err != nil {
+		return "", fmt.Errorf("could not copy %s into tool cache: %w", source, err)
+	}
+
+	return dest, complete(dest)
+}
+
+// CacheFile copies the single file source into the tool cache as
+// $RUNNER_TOOL_CACHE/<tool>/<version>/<arch>/<target>, returning the path to the cached
+// directory (not the file).
+//
+// This is useful for tools distributed as a single binary rather than an archive. Any existing
+// cached copy of the same version is replaced. If arch is empty, the architecture of the current
+// machine is used.
+func CacheFile(source, target, tool, version, arch string) (string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("could not cache %s: %w", source, err)
+	}
+
+	if info.IsDir() {
+		return "", fmt.Errorf("could not cache %s: is a directory", source)
+	}
+
+	if target == "" || filepath.Base(target) != target {
+		return "", fmt.Errorf("invalid target file name %q", target)
+	}
+
+	dest, err := prepare(tool, version, arch)
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyFile(source, filepath.Join(dest, target), info.Mode()); err != nil {
+		return "", fmt.Errorf("could not copy %s into tool cache: %w", source, err)
+	}
+
+	return dest, complete(dest)
+}
+
+// Find returns the path to the cached directory of tool with the highest version
+// satisfying versionSpec, which may be an exact version like "1.2.3" or a range like
+// "^1.2" or "1.x".
+//
+// If arch is empty, the architecture of the current machine is used. If no cached version
+// satisfies versionSpec, [ErrNotFound] is returned.
+func Find(tool, versionSpec, arch string) (string, error) {
+	if arch == "" {
+		arch = defaultArch()
+	}
+
+	if err := checkNames(tool, arch); err != nil {
+		return "", err
+	}
+
+	root, err := cacheRoot()
+	if err != nil {
+		return "", err
+	}
+
+	// An exact version we can just look for directly
+	if version := semver.Clean(versionSpec); version != "" {
+		if err = checkName("tool version", version); err != nil {
+			return "", err
+		}
+
+		dir := filepath.Join(root, tool, version, arch)
+		if isComplete(dir) {
+			return dir, nil
+		}
+
+		return "", fmt.Errorf("%s %s (%s): %w", tool, versionSpec, arch, ErrNotFound)
+	}
+
+	r, err := semver.ParseRange(versionSpec)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := Versions(tool, arch)
+	if err != nil {
+		return "", err
+	}
+
+	// Versions are sorted ascending so the best match is the last one that matches
+	for _, version := range slices.Backward(versions) {
+		parsed, err := semver.Parse(version)
+		if err != nil {
+			continue
+		}
+
+		if r.Contains(parsed) {
+			return filepath.Join(root, tool, version, arch), nil
+		}
+	}
+
+	return "", fmt.Errorf("%s %s (%s): %w", tool, versionSpec, arch, ErrNotFound)
+}
+
+// Versions returns every completely cached version of tool for arch, sorted in ascending
+// semantic version order.
+//
+// If arch is empty, the architecture of the current machine is used. If the tool has never
+// been cached, an empty list and no error is returned.
+func Versions(tool, arch string) ([]string, error) {
+	if arch == "" {
+		arch = defaultArch()
+	}
+
+	if err := checkNames(tool, arch); err != nil {
+		return nil, err
+	}
+
+	root, err := cacheRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, tool))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not read tool cache for %s: %w", tool, err)
+	}
+
+	type cached struct {
+		name    string
+		version semver.Version
+	}
+
+	var found []cached
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		version, err := semver.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if isComplete(filepath.Join(root, tool, entry.Name(), arch)) {
+			found = append(found, cached{name: entry.Name(), version: version})
+		}
+	}
+
+	slices.SortFunc(found, func(a, b cached) int {
+		return a.version.Compare(b.version)
+	})
+
+	versions := make([]string, 0, len(found))
+	for _, c := range found {
+		versions = append(versions, c.name)
+	}
+
+	return versions, nil
+}
+
+// Use finds the cached tool matching versionSpec (see [Find]) and adds its directory to
+// $PATH using [actions.AddPath], returning the directory added.
+//
+// If the tool's executables live in a subdirectory of the cached directory (e.g. "bin")
+// pass the path elements as sub and they will be joined onto it.
+func Use(tool, versionSpec, arch string, sub ...string) (string, error) {
+	dir, err := Find(tool, versionSpec, arch)
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(append([]string{dir}, sub...)...)
+
+	if err := actions.AddPath(dir); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// prepare creates a fresh, empty directory in the tool cache for tool, version and arch,
+// removing any existing (possibly incomplete) copy.
+func prepare(tool, version, arch string) (string, error) {
+	if arch == "" {
+		arch = defaultArch()
+	}
+
+	if cleaned := semver.Clean(version); cleaned != "" {
+		version = cleaned
+	}
+
+	if err := checkNames(tool, arch); err != nil {
+		return "", err
+	}
+
+	if err := checkName("tool version", version); err != nil {
+		return "", err
+	}
+
+	root, err := cacheRoot()
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(root, tool, version, arch)
+
+	if err := os.RemoveAll(dest); err != nil {
+		return "", fmt.Errorf("could not remove existing %s: %w", dest, err)
+	}
+
+	if err := os.RemoveAll(dest + completeSuffix); err != nil {
+		return "", fmt.Errorf("could not remove existing %s: %w", dest+completeSuffix, err)
+	}
+
+	if err := os.MkdirAll(dest, dirPermissions); err != nil {
+		return "", fmt.Errorf("could not create %s: %w", dest, err)
+	}
+
+	return dest, nil
+}
+
+// checkNames checks the tool name and architecture with [checkName].
+func checkNames(tool, arch string) error {
+	if err := checkName("tool name", tool); err != nil {
+		return err
+	}
+
+	return checkName("architecture", arch)
+}
+
+// checkName checks that value, which is described by what in errors, is a single path element
+// so that joining it onto $RUNNER_TOOL_CACHE can't point anywhere outside of it.
+func checkName(what, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s cannot be empty", what)
+	}
+
+	if value == "." || !filepath.IsLocal(value) || strings.ContainsAny(value, `/\`) {
+		return fmt.Errorf("invalid %s %q", what, value)
+	}
+
+	return nil
+}
+
+// complete writes the marker file signalling that dir was completely cached.
+func complete(dir string) error {
+	if err := os.WriteFile(dir+completeSuffix, nil, filePermissions); err != nil {
+		return fmt.Errorf("could not mark %s as complete: %w", dir, err)
+	}
+
+	return nil
+}
+
+// isComplete reports whether dir exists in the tool cache and was completely cached.
+func isComplete(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+
+	_, err = os.Stat(dir + completeSuffix)
+
+	return err == nil
+}
+
+// cacheRoot returns the root of the tool cache from $RUNNER_TOOL_CACHE.
+func cacheRoot() (string, error) {
+	root := os.Getenv(cacheVar)
+	if root == "" {
+		return "", fmt.Errorf("$%s is not set or is empty", cacheVar)
+	}
+
+	return root, nil
+}
+
+// tempDir returns the runner's temporary directory from $RUNNER_TEMP.
+func tempDir() (string, error) {
+	dir := os.Getenv(tempVar)
+	if dir == "" {
+		return "", fmt.Errorf("$%s is not set or is empty", tempVar)
+	}
+
+	return dir, nil
+}
+
+// defaultArch returns the architecture of the current machine using the same naming
+// as the actions toolkit (which in turn uses the naming from NodeJS).
+func defaultArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "x86"
+	default:
+		return runtime.GOARCH
+	}
+}
+
+// copyDir recursively copies the contents of the directory src into dst, preserving
+// file modes and symlinks.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode())
+		}
+	})
+}
+
+// copyFile copies the regular file src to dst with the given mode.
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
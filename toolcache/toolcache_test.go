@@ -0,0 +1,361 @@
+package toolcache_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/actions/toolcache"
+	"go.followtheprocess.codes/test"
+)
+
+func TestDownload(t *testing.T) {
+	contents := []byte("#!/bin/sh\necho hello\n")
+	sum := sha256.Sum256(contents)
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("success", func(t *testing.T) {
+		temp := t.TempDir()
+		t.Setenv("RUNNER_TEMP", temp)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.Equal(t, r.Header.Get("Authorization"), "token secret")
+			w.Write(contents)
+		}))
+		defer server.Close()
+
+		path, err := toolcache.Download(
+			t.Context(),
+			server.URL,
+			toolcache.Checksum(checksum),
+			toolcache.Header("Authorization", "token secret"),
+		)
+		test.Ok(t, err)
+		test.Equal(t, filepath.Dir(path), temp)
+
+		got, err := os.ReadFile(path)
+		test.Ok(t, err)
+		test.DiffBytes(t, got, contents)
+	})
+
+	t.Run("no runner temp", func(t *testing.T) {
+		t.Setenv("RUNNER_TEMP", "")
+
+		_, err := toolcache.Download(t.Context(), "http://localhost")
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "$RUNNER_TEMP is not set or is empty")
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "tool")
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("tampered"))
+		}))
+		defer server.Close()
+
+		_, err := toolcache.Download(t.Context(), server.URL, toolcache.Dest(dest), toolcache.Checksum(checksum))
+		test.Err(t, err)
+		test.True(t, strings.Contains(err.Error(), "checksum mismatch"))
+
+		// Should have been cleaned up
+		_, err = os.Stat(dest)
+		test.True(t, errors.Is(err, os.ErrNotExist))
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			w.Write(contents)
+		}))
+		defer server.Close()
+
+		path, err := toolcache.Download(
+			t.Context(),
+			server.URL,
+			toolcache.Dest(filepath.Join(t.TempDir(), "tool")),
+			toolcache.Retry(3, time.Millisecond),
+		)
+		test.Ok(t, err)
+		test.Equal(t, calls.Load(), int32(3))
+
+		got, err := os.ReadFile(path)
+		test.Ok(t, err)
+		test.DiffBytes(t, got, contents)
+	})
+
+	t.Run("gives up after attempts", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := toolcache.Download(
+			t.Context(),
+			server.URL,
+			toolcache.Dest(filepath.Join(t.TempDir(), "tool")),
+			toolcache.Retry(2, time.Millisecond),
+		)
+		test.Err(t, err)
+		test.Equal(t, calls.Load(), int32(2))
+
+		var httpErr *toolcache.HTTPError
+
+		test.True(t, errors.As(err, &httpErr))
+		test.Equal(t, httpErr.StatusCode, http.StatusServiceUnavailable)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		_, err := toolcache.Download(
+			t.Context(),
+			server.URL,
+			toolcache.Dest(filepath.Join(t.TempDir(), "tool")),
+			toolcache.Retry(3, time.Millisecond),
+		)
+		test.Err(t, err)
+		test.Equal(t, calls.Load(), int32(1))
+		test.True(t, strings.Contains(err.Error(), "404"))
+	})
+}
+
+// cacheTool caches a fake tool with a single file in it, returning the cached dir.
+func cacheTool(t *testing.T, tool, version, arch string) string {
+	t.Helper()
+
+	source := t.TempDir()
+	err := os.WriteFile(filepath.Join(source, tool), []byte(version), 0o755)
+	test.Ok(t, err)
+
+	dir, err := toolcache.CacheDir(source, tool, version, arch)
+	test.Ok(t, err)
+
+	return dir
+}
+
+func TestCacheDir(t *testing.T) {
+	t.Run("no tool cache", func(t *testing.T) {
+		t.Setenv("RUNNER_TOOL_CACHE", "")
+
+		_, err := toolcache.CacheDir(t.TempDir(), "tool", "1.0.0", "x64")
+		test.Err(t, err)
+		test.Equal(t, err.Error(), "$RUNNER_TOOL_CACHE is not set or is empty")
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		cache := t.TempDir()
+		t.Setenv("RUNNER_TOOL_CACHE", cache)
+
+		source := t.TempDir()
+		err := os.MkdirAll(filepath.Join(source, "bin"), 0o755)
+		test.Ok(t, err)
+
+		err = os.WriteFile(filepath.Join(source, "bin", "tool"), []byte("binary"), 0o755)
+		test.Ok(t, err)
+
+		dir, err := toolcache.CacheDir(source, "tool", "v1.2.3", "x64")
+		test.Ok(t, err)
+		test.Equal(t, dir, filepath.Join(cache, "tool", "1.2.3", "x64"))
+
+		got, err := os.ReadFile(filepath.Join(dir, "bin", "tool"))
+		test.Ok(t, err)
+		test.Equal(t, string(got), "binary")
+
+		if runtime.GOOS != "windows" {
+			info, statErr := os.Stat(filepath.Join(dir, "bin", "tool"))
+			test.Ok(t, statErr)
+			test.Equal(t, info.Mode().Perm(), os.FileMode(0o755))
+		}
+
+		_, err = os.Stat(dir + ".complete")
+		test.Ok(t, err)
+	})
+
+	t.Run("not a dir", func(t *testing.T) {
+		t.Setenv("RUNNER_TOOL_CACHE", t.TempDir())
+
+		file := filepath.Join(t.TempDir(), "file")
+		err := os.WriteFile(file, nil, 0o644)
+		test.Ok(t, err)
+
+		_, err = toolcache.CacheDir(file, "tool", "1.0.0", "x64")
+		test.Err(t, err)
+	})
+}
+
+func TestCacheNames(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "cache")
+	t.Setenv("RUNNER_TOOL_CACHE", cache)
+
+	source := t.TempDir()
+
+	tests := []struct {
+		tool    string // Tool name
+		version string // Tool version
+		arch    string // Architecture
+		want    string // Expected error
+	}{
+		{tool: "", version: "1.0.0", arch: "x64", want: "tool name cannot be empty"},
+		{tool: "../../x", version: "1.0.0", arch: "x64", want: `invalid tool name "../../x"`},
+		{tool: "..", version: "1.0.0", arch: "x64", want: `invalid tool name ".."`},
+		{tool: "a/b", version: "1.0.0", arch: "x64", want: `invalid tool name "a/b"`},
+		{tool: `a\b`, version: "1.0.0", arch: "x64", want: `invalid tool name "a\\b"`},
+		{tool: "tool", version: "../1.0.0", arch: "x64", want: `invalid tool version "../1.0.0"`},
+		{tool: "tool", version: ".", arch: "x64", want: `invalid tool version "."`},
+		{tool: "tool", version: "1.0.0", arch: "../..", want: `invalid architecture "../.."`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			_, err := toolcache.CacheDir(source, tt.tool, tt.version, tt.arch)
+			test.Err(t, err)
+			test.Equal(t, err.Error(), tt.want)
+
+			if tt.version == "1.0.0" {
+				// Otherwise it's an invalid version spec for Find
+				_, err = toolcache.Find(tt.tool, tt.version, tt.arch)
+				test.Err(t, err)
+				test.Equal(t, err.Error(), tt.want)
+			}
+		})
+	}
+
+	// Nothing should have been created
+	_, err := os.Stat(cache)
+	test.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestCacheFile(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("RUNNER_TOOL_CACHE", cache)
+
+	source := filepath.Join(t.TempDir(), "downloaded")
+	err := os.WriteFile(source, []byte("binary"), 0o755)
+	test.Ok(t, err)
+
+	dir, err := toolcache.CacheFile(source, "tool", "tool", "2.0.0", "arm64")
+	test.Ok(t, err)
+	test.Equal(t, dir, filepath.Join(cache, "tool", "2.0.0", "arm64"))
+
+	got, err := os.ReadFile(filepath.Join(dir, "tool"))
+	test.Ok(t, err)
+	test.Equal(t, string(got), "binary")
+
+	_, err = toolcache.CacheFile(source, "../escape", "tool", "2.0.0", "arm64")
+	test.Err(t, err)
+}
+
+func TestFind(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("RUNNER_TOOL_CACHE", cache)
+
+	for _, version := range []string{"1.2.3", "1.10.0", "1.9.1", "2.0.0", "2.1.0-rc.1"} {
+		cacheTool(t, "tool", version, "x64")
+	}
+
+	cacheTool(t, "tool", "3.0.0", "arm64")
+
+	// An incomplete cache (no marker) should be ignored
+	err := os.MkdirAll(filepath.Join(cache, "tool", "1.11.0", "x64"), 0o755)
+	test.Ok(t, err)
+
+	tests := []struct {
+		spec string // Version spec to find
+		want string // Expected version found, empty if not found
+	}{
+		{spec: "1.2.3", want: "1.2.3"},
+		{spec: "v1.9.1", want: "1.9.1"},
+		{spec: "1.2.4", want: ""},
+		{spec: "1.x", want: "1.10.0"},
+		{spec: "^1.2", want: "1.10.0"},
+		{spec: "~1.9", want: "1.9.1"},
+		{spec: "*", want: "2.0.0"},
+		{spec: ">=2.1.0-rc.0", want: "2.1.0-rc.1"},
+		{spec: "3", want: ""}, // Only cached for arm64
+		{spec: "4.x", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			dir, err := toolcache.Find("tool", tt.spec, "x64")
+			if tt.want == "" {
+				test.Err(t, err)
+				test.True(t, errors.Is(err, toolcache.ErrNotFound))
+
+				return
+			}
+
+			test.Ok(t, err)
+			test.Equal(t, dir, filepath.Join(cache, "tool", tt.want, "x64"))
+		})
+	}
+
+	t.Run("invalid spec", func(t *testing.T) {
+		_, err := toolcache.Find("tool", "not a version", "x64")
+		test.Err(t, err)
+		test.True(t, !errors.Is(err, toolcache.ErrNotFound))
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		versions, err := toolcache.Versions("tool", "x64")
+		test.Ok(t, err)
+
+		want := []string{"1.2.3", "1.9.1", "1.10.0", "2.0.0", "2.1.0-rc.1"}
+		test.EqualFunc(t, versions, want, slices.Equal)
+
+		none, err := toolcache.Versions("other", "x64")
+		test.Ok(t, err)
+		test.Equal(t, len(none), 0)
+	})
+}
+
+func TestUse(t *testing.T) {
+	t.Setenv("RUNNER_TOOL_CACHE", t.TempDir())
+	t.Setenv("PATH", os.Getenv("PATH")) // AddPath modifies $PATH, make sure it's restored
+
+	githubPath := filepath.Join(t.TempDir(), "github_path")
+	err := os.WriteFile(githubPath, nil, 0o644)
+	test.Ok(t, err)
+
+	t.Setenv("GITHUB_PATH", githubPath)
+
+	dir := cacheTool(t, "tool", "1.0.0", "")
+
+	got, err := toolcache.Use("tool", "1.x", "", "bin")
+	test.Ok(t, err)
+	test.Equal(t, got, filepath.Join(dir, "bin"))
+
+	contents, err := os.ReadFile(githubPath)
+	test.Ok(t, err)
+	test.Equal(t, string(contents), got+"\n")
+	test.True(t, strings.HasPrefix(os.Getenv("PATH"), got+string(os.PathListSeparator)))
+
+	_, err = toolcache.Use("tool", "2.x", "")
+	test.True(t, errors.Is(err, toolcache.ErrNotFound))
+}